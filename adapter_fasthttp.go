@@ -6,6 +6,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/valyala/fasthttp"
 )
@@ -24,6 +25,8 @@ type (
 
 	FastHttpHttpClientAdapter struct {
 		cli *fasthttp.Client
+		// dialer tells the timings of dialing phases, if created by FasthttpTimedClient.
+		dialer *timedDialer
 	}
 )
 
@@ -87,10 +90,25 @@ func (a *fastHttpReqAdapter) SetBodyStream(body io.ReadWriteCloser, bodySize int
 }
 
 func (a *fastHttpReqAdapter) SetBody(body []byte) {
+	a.stream = nil
 	a.req.SetBody(body)
 	a.req.Header.SetContentLength(len(body))
 }
 
+// rewind tells whether the request can be sent again, which fixed bodies can, as fasthttp keeps
+// them around.
+func (a *fastHttpReqAdapter) rewind() bool {
+	return a.stream == nil
+}
+
+// bufferedBody returns the body of the request, unless streamed.
+func (a *fastHttpReqAdapter) bufferedBody() ([]byte, bool) {
+	if a.stream != nil {
+		return nil, false
+	}
+	return a.req.Body(), true
+}
+
 func (a *fastHttpReqAdapter) Body() (bts []byte) {
 	bts, _ = io.ReadAll(a.stream)
 	return
@@ -131,9 +149,22 @@ func (a *FastHttpHttpClientAdapter) Request(_ context.Context) (Request, error)
 	return adaptReqFastHttp(req), nil
 }
 
-func (a *FastHttpHttpClientAdapter) Do(_ context.Context, req Request) (Response, error) {
+func (a *FastHttpHttpClientAdapter) Do(ctx context.Context, req Request) (Response, error) {
+	recorder := timingsRecorderFrom(ctx)
+	if recorder != nil {
+		recorder.begin()
+	}
+
 	res := &fasthttp.Response{} // TODO: Acquire/Release
 	err := a.cli.Do(req.(*fastHttpReqAdapter).req, res)
+
+	if recorder != nil {
+		if a.dialer != nil {
+			a.dialer.record(recorder, res)
+		}
+		recorder.bodyDone(time.Now())
+	}
+
 	return adaptResFastHttp(res), err
 }
 
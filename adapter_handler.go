@@ -0,0 +1,273 @@
+package withttp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/valyala/fasthttp"
+)
+
+var ErrHandlerPanicked = errors.New("handler panicked")
+
+type (
+	handlerReqAdapter struct {
+		*nativeReqAdapter
+		// pipe is the buffer streamed bodies are written into, if any.
+		pipe *pipeBuffer
+	}
+
+	fastHttpHandlerReqAdapter struct {
+		*fastHttpReqAdapter
+		pipe *pipeBuffer
+		size int
+	}
+
+	fastHttpHandlerResAdapter struct {
+		*fastHttpResAdapter
+		body io.ReadCloser
+	}
+
+	// handlerRecorder is the http.ResponseWriter handlers write into, whose responses are handed
+	// over once their header is written, their bodies being streamed through a buffer, so that
+	// handlers are not held up by callers leaving them unread.
+	handlerRecorder struct {
+		header http.Header
+		res    *http.Response
+		body   *pipeBuffer
+		// err is the reason no response was written, if any.
+		err   error
+		once  sync.Once
+		ready chan struct{}
+	}
+
+	// HandlerClientAdapter dispatches requests to an http.Handler in-process, without opening
+	// sockets, so that handlers and the clients calling them can be tested together. Handlers run
+	// concurrently with their callers, as they would behind servers: streamed request bodies are
+	// read while being written, and responses are handed over as soon as their header is written,
+	// their bodies being streamed.
+	HandlerClientAdapter struct {
+		handler http.Handler
+	}
+
+	// FastHttpHandlerClientAdapter dispatches requests to a fasthttp.RequestHandler in-process,
+	// as HandlerClientAdapter does. Response bodies set as streams, see
+	// fasthttp.RequestCtx.SetBodyStreamWriter, are streamed.
+	FastHttpHandlerClientAdapter struct {
+		handler fasthttp.RequestHandler
+	}
+)
+
+func HandlerClient(h http.Handler) *HandlerClientAdapter {
+	return &HandlerClientAdapter{handler: h}
+}
+
+func (a *HandlerClientAdapter) Request(ctx context.Context) (Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	return &handlerReqAdapter{nativeReqAdapter: &nativeReqAdapter{req: req}}, nil
+}
+
+func (a *HandlerClientAdapter) Do(ctx context.Context, req Request) (Response, error) {
+	hr := req.(*handlerReqAdapter)
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r := hr.req.Clone(ctx)
+	r.Proto, r.ProtoMajor, r.ProtoMinor = "HTTP/1.1", 1, 1
+	r.Host = r.URL.Host
+	r.RequestURI = r.URL.RequestURI()
+	r.RemoteAddr = "192.0.2.1:1234"
+
+	if stream := hr.BodyStream(); stream != nil {
+		// Bodies are closed once handlers return, as servers do, rather than by handlers, which
+		// would otherwise race with stream writers.
+		r.Body = io.NopCloser(stream)
+		if _, ok := hr.bufferedBody(); !ok && r.ContentLength == 0 {
+			r.ContentLength = -1
+		}
+	} else {
+		r.Body = http.NoBody
+	}
+
+	body := newPipeBuffer()
+	rec := &handlerRecorder{header: make(http.Header), body: body, ready: make(chan struct{})}
+	rec.res = &http.Response{
+		Proto:      r.Proto,
+		ProtoMajor: r.ProtoMajor,
+		ProtoMinor: r.ProtoMinor,
+		Body:       body,
+		Request:    r,
+	}
+
+	// Callers are gone once the context is done, which fails writes, as lost connections do.
+	stop := context.AfterFunc(ctx, func() { _ = body.CloseWithError(ctx.Err()) })
+
+	go func() {
+		defer func() {
+			stop()
+
+			if hr.pipe != nil {
+				_ = hr.pipe.Close()
+			}
+
+			if p := recover(); p != nil {
+				err := errors.Wrapf(ErrHandlerPanicked, "got: '%v'", p)
+				rec.fail(err)
+				_ = body.CloseWithError(err)
+				return
+			}
+
+			rec.WriteHeader(http.StatusOK)
+			_ = body.Close()
+		}()
+
+		a.handler.ServeHTTP(rec, r)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-rec.ready:
+	}
+
+	if rec.err != nil {
+		return nil, rec.err
+	}
+	return adaptResNative(rec.res), nil
+}
+
+func (a *handlerReqAdapter) bodyPipe() io.ReadWriteCloser {
+	a.pipe = newPipeBuffer()
+	return a.pipe
+}
+
+func (w *handlerRecorder) Header() http.Header {
+	return w.header
+}
+
+func (w *handlerRecorder) WriteHeader(status int) {
+	w.once.Do(func() {
+		w.res.StatusCode = status
+		w.res.Status = strconv.Itoa(status) + " " + http.StatusText(status)
+		w.res.Header = w.header.Clone()
+		w.res.ContentLength = -1
+		if size, err := strconv.ParseInt(w.res.Header.Get("content-length"), 10, 64); err == nil {
+			w.res.ContentLength = size
+		}
+		close(w.ready)
+	})
+}
+
+func (w *handlerRecorder) Write(p []byte) (int, error) {
+	if _, ok := w.header["Content-Type"]; !ok && len(p) > 0 {
+		w.header.Set("Content-Type", http.DetectContentType(p))
+	}
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(p)
+}
+
+// Flush hands the response over, as bodies are readable as soon as written.
+func (w *handlerRecorder) Flush() {
+	w.WriteHeader(http.StatusOK)
+}
+
+// fail hands `err` over instead of the response, unless already handed over.
+func (w *handlerRecorder) fail(err error) {
+	w.once.Do(func() {
+		w.err = err
+		close(w.ready)
+	})
+}
+
+func FasthttpHandlerClient(h fasthttp.RequestHandler) *FastHttpHandlerClientAdapter {
+	return &FastHttpHandlerClientAdapter{handler: h}
+}
+
+func (a *FastHttpHandlerClientAdapter) Request(_ context.Context) (Request, error) {
+	req := &fasthttp.Request{}
+	req.Header.SetMethod(http.MethodGet)
+	return &fastHttpHandlerReqAdapter{fastHttpReqAdapter: &fastHttpReqAdapter{req: req}}, nil
+}
+
+func (a *FastHttpHandlerClientAdapter) Do(ctx context.Context, req Request) (Response, error) {
+	fr := req.(*fastHttpHandlerReqAdapter)
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	rctx := &fasthttp.RequestCtx{}
+	rctx.Init(fr.req, nil, nil)
+
+	if fr.stream != nil {
+		// Hidden from fasthttp, which closes streams once read, racing with stream writers.
+		rctx.Request.SetBodyStream(struct{ io.Reader }{fr.stream}, fr.size)
+	}
+
+	if err := a.serve(rctx, fr); err != nil {
+		return nil, err
+	}
+
+	res := &fastHttpHandlerResAdapter{fastHttpResAdapter: &fastHttpResAdapter{res: &rctx.Response}}
+
+	if rctx.Response.IsBodyStream() {
+		body := newPipeBuffer()
+		stop := context.AfterFunc(ctx, func() { _ = body.CloseWithError(ctx.Err()) })
+		go func() {
+			defer stop()
+			_ = body.CloseWithError(rctx.Response.BodyWriteTo(body))
+		}()
+		res.body = body
+	} else {
+		res.body = io.NopCloser(bytes.NewReader(rctx.Response.Body()))
+	}
+
+	return res, nil
+}
+
+// serve runs the handler, whose panics are returned as errors, as servers recover them.
+func (a *FastHttpHandlerClientAdapter) serve(
+	rctx *fasthttp.RequestCtx,
+	fr *fastHttpHandlerReqAdapter,
+) (err error) {
+	defer func() {
+		if fr.pipe != nil {
+			_ = fr.pipe.Close()
+		}
+
+		if p := recover(); p != nil {
+			err = errors.Wrapf(ErrHandlerPanicked, "got: '%v'", p)
+		}
+	}()
+
+	a.handler(rctx)
+	return nil
+}
+
+// SetBodyStream keeps streams away from fasthttp, which closes those replaced, such as the ones
+// compressed streams write into, till requests are dispatched.
+func (a *fastHttpHandlerReqAdapter) SetBodyStream(body io.ReadWriteCloser, bodySize int) {
+	a.stream, a.size = body, bodySize
+}
+
+func (a *fastHttpHandlerReqAdapter) bodyPipe() io.ReadWriteCloser {
+	a.pipe = newPipeBuffer()
+	return a.pipe
+}
+
+func (a *fastHttpHandlerResAdapter) Body() io.ReadCloser {
+	return a.body
+}
+
+func (a *fastHttpHandlerResAdapter) SetBody(body io.ReadCloser) {
+	a.body = body
+}
@@ -0,0 +1,295 @@
+package withttp
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/valyala/fasthttp"
+)
+
+func TestHandlerClient(t *testing.T) {
+	type order struct {
+		Amount float64 `json:"amount"`
+		Pair   string  `json:"pair"`
+	}
+
+	// echo responds with the orders received, doubled, one at a time.
+	echo := func(body io.Reader, w io.Writer, flush func()) {
+		dec, enc := json.NewDecoder(body), json.NewEncoder(w)
+		for {
+			var o order
+			if err := dec.Decode(&o); err != nil {
+				return
+			}
+			o.Amount *= 2
+			_ = enc.Encode(o)
+			flush()
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/orders", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", ContentTypeJSONEachRow)
+		echo(DecodeContentEncoding(r.Body, r.Header.Get("content-encoding")), w, w.(http.Flusher).Flush)
+	})
+	mux.HandleFunc("/ignored", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	fastHandler := func(ctx *fasthttp.RequestCtx) {
+		if string(ctx.Path()) == "/ignored" {
+			ctx.SetStatusCode(http.StatusNoContent)
+			return
+		}
+
+		body := DecodeContentEncoding(
+			io.NopCloser(bytes.NewReader(ctx.PostBody())),
+			string(ctx.Request.Header.Peek("content-encoding")),
+		)
+		ctx.SetContentType(ContentTypeJSONEachRow)
+		ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+			echo(body, w, func() { _ = w.Flush() })
+		})
+	}
+
+	type (
+		args struct {
+			path     string
+			orders   []order
+			encoding string
+		}
+
+		want struct {
+			orders []order
+			status int
+		}
+
+		testCase struct {
+			name string
+			args args
+			want want
+		}
+	)
+
+	orders := []order{{Amount: 1, Pair: "BTC/USDT"}, {Amount: 2.5, Pair: "ETH/USDT"}}
+	doubled := []order{{Amount: 2, Pair: "BTC/USDT"}, {Amount: 5, Pair: "ETH/USDT"}}
+
+	tests := []testCase{
+		{
+			name: "streamed",
+			args: args{path: "/orders", orders: orders},
+			want: want{orders: doubled, status: http.StatusOK},
+		},
+		{
+			name: "compressed",
+			args: args{path: "/orders", orders: orders, encoding: ContentEncodingGzip},
+			want: want{orders: doubled, status: http.StatusOK},
+		},
+		{
+			name: "empty",
+			args: args{path: "/orders"},
+			want: want{status: http.StatusOK},
+		},
+		{
+			name: "body ignored",
+			args: args{path: "/ignored", orders: orders},
+			want: want{status: http.StatusNoContent},
+		},
+	}
+
+	for _, test := range tests {
+		for name, cli := range map[string]Client{
+			"net/http": HandlerClient(mux),
+			"fasthttp": FasthttpHandlerClient(fastHandler),
+		} {
+			t.Run(test.name+" "+name, func(t *testing.T) {
+				var received []order
+
+				call := NewCall[order](cli).
+					URL("http://example.com" + test.args.path).
+					Method(http.MethodPost).
+					ContentType(ContentTypeJSONEachRow).
+					RequestStreamBody(RequestStreamBody[order, order](Slice[order](test.args.orders))).
+					ParseJSONEachRow(func(o order) bool {
+						received = append(received, o)
+						return true
+					})
+
+				if test.args.encoding != "" {
+					call = call.CompressRequest(test.args.encoding, gzip.DefaultCompression)
+				}
+
+				if err := call.Call(context.TODO()); err != nil {
+					t.Fatalf("unexpected error, want none, have %v", err)
+				}
+
+				if have := call.Res.Status(); have != test.want.status {
+					t.Errorf("unexpected status, want %d, have %d", test.want.status, have)
+				}
+
+				if !reflect.DeepEqual(received, test.want.orders) {
+					t.Errorf("unexpected orders, want %+v, have %+v", test.want.orders, received)
+				}
+			})
+		}
+	}
+}
+
+func TestHandlerClient_Panicked(t *testing.T) {
+	clients := map[string]Client{
+		"net/http": HandlerClient(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+			panic("boom")
+		})),
+		"fasthttp": FasthttpHandlerClient(func(*fasthttp.RequestCtx) {
+			panic("boom")
+		}),
+	}
+
+	for name, cli := range clients {
+		t.Run(name, func(t *testing.T) {
+			err := NewCall[any](cli).URL("http://example.com").Call(context.TODO())
+			if !errors.Is(err, ErrHandlerPanicked) {
+				t.Errorf("unexpected error, want %v, have %v", ErrHandlerPanicked, err)
+			}
+		})
+	}
+}
+
+func TestHandlerClient_Cancelled(t *testing.T) {
+	var served atomic.Bool
+
+	clients := map[string]Client{
+		"net/http": HandlerClient(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+			served.Store(true)
+		})),
+		"fasthttp": FasthttpHandlerClient(func(*fasthttp.RequestCtx) {
+			served.Store(true)
+		}),
+	}
+
+	for name, cli := range clients {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			err := NewCall[any](cli).URL("http://example.com").Call(ctx)
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("unexpected error, want %v, have %v", context.Canceled, err)
+			}
+
+			if served.Load() {
+				t.Errorf("unexpected handler served, want none")
+			}
+		})
+	}
+}
+
+func TestHandlerClient_BodyUnread(t *testing.T) {
+	type (
+		args struct {
+			// cancel cancels the call once responded, whose handler writes till failing, instead
+			// of writing a large body.
+			cancel bool
+		}
+
+		testCase struct {
+			name string
+			args args
+		}
+	)
+
+	chunk := bytes.Repeat([]byte("x"), 16*1024)
+
+	tests := []testCase{
+		{name: "unread", args: args{}},
+		{name: "cancelled", args: args{cancel: true}},
+	}
+
+	for _, test := range tests {
+		for name, newClient := range map[string]func(done chan struct{}) Client{
+			"net/http": func(done chan struct{}) Client {
+				return HandlerClient(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					defer close(done)
+					for i := 0; test.args.cancel || i < 64; i++ {
+						if _, err := w.Write(chunk); err != nil {
+							return
+						}
+					}
+				}))
+			},
+			"fasthttp": func(done chan struct{}) Client {
+				return FasthttpHandlerClient(func(ctx *fasthttp.RequestCtx) {
+					ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+						defer close(done)
+						for i := 0; test.args.cancel || i < 64; i++ {
+							_, _ = w.Write(chunk)
+							if err := w.Flush(); err != nil {
+								return
+							}
+						}
+					})
+				})
+			},
+		} {
+			t.Run(test.name+" "+name, func(t *testing.T) {
+				done := make(chan struct{})
+
+				ctx, cancel := context.WithCancel(context.TODO())
+				defer cancel()
+
+				call := NewCall[any](newClient(done)).URL("http://example.com")
+				if err := call.Call(ctx); err != nil {
+					t.Fatalf("unexpected error, want none, have %v", err)
+				}
+
+				if test.args.cancel {
+					cancel()
+				}
+
+				select {
+				case <-done:
+				case <-time.After(5 * time.Second):
+					t.Fatalf("unexpected handler still running, want it returned")
+				}
+			})
+		}
+	}
+}
+
+// Example_handlerClient demonstrates how to call handlers without opening sockets.
+func Example_handlerClient() {
+	type Order struct {
+		Amount float64 `json:"amount"`
+		Pair   string  `json:"pair"`
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/orders/1", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("content-type", ContentTypeJSON)
+		_ = json.NewEncoder(w).Encode(Order{Amount: 100.5, Pair: "BTC/USD"})
+	})
+
+	call := NewCall[Order](HandlerClient(mux)).
+		URL("http://example.com/orders/1").
+		ParseJSON().
+		ExpectedStatusCodes(http.StatusOK)
+
+	if err := call.Call(context.Background()); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Order amount: %.1f, Pair: %s\n", call.BodyParsed.Amount, call.BodyParsed.Pair)
+	// Output: Order amount: 100.5, Pair: BTC/USD
+}
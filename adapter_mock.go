@@ -22,7 +22,7 @@ func (a *MockHttpClientAdapter) Request(_ context.Context) (Request, error) {
 }
 
 func (a *MockHttpClientAdapter) Do(_ context.Context, _ Request) (Response, error) {
-	return adaptResMock(&http.Response{}), nil
+	return adaptResMock(&http.Response{Header: make(http.Header)}), nil
 }
 
 func adaptResMock(res *http.Response) Response {
@@ -5,6 +5,7 @@ import (
 	"context"
 	"io"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"strings"
 )
@@ -12,6 +13,8 @@ import (
 type (
 	nativeReqAdapter struct {
 		body io.ReadWriteCloser
+		// payload holds fixed bodies, so that they can be sent again. Nil for streamed ones.
+		payload []byte
 
 		req *http.Request
 	}
@@ -52,16 +55,44 @@ func (a *nativeReqAdapter) SetURL(u *url.URL) {
 
 func (a *nativeReqAdapter) SetBodyStream(body io.ReadWriteCloser, _ int) {
 	a.body = body
+	a.payload = nil
 	a.req.Body = body
 }
 
 func (a *nativeReqAdapter) SetBody(payload []byte) {
 	// TODO: pool these readers
 	a.body = closableReaderWriter{ReadWriter: bytes.NewBuffer(payload)}
+	a.payload = payload
 	a.req.Body = a.body
 	a.req.ContentLength = int64(len(payload))
 }
 
+func (a *nativeReqAdapter) rewind() bool {
+	switch {
+	case a.body == nil:
+		return true
+	case a.payload == nil:
+		return false
+	default:
+		a.body = closableReaderWriter{ReadWriter: bytes.NewBuffer(a.payload)}
+		a.req.Body = a.body
+		return true
+	}
+}
+
+// bufferedBody returns the body of the request, unless streamed.
+func (a *nativeReqAdapter) bufferedBody() ([]byte, bool) {
+	if a.body == nil {
+		return nil, true
+	}
+	return a.payload, a.payload != nil
+}
+
+// setContentLength announces the size of streamed bodies, which are otherwise sent chunked.
+func (a *nativeReqAdapter) setContentLength(size int64) {
+	a.req.ContentLength = size
+}
+
 func (a *nativeReqAdapter) Body() []byte {
 	bts, _ := io.ReadAll(a.req.Body)
 	return bts
@@ -94,7 +125,20 @@ func (a *NativeHttpClientAdapter) Request(ctx context.Context) (Request, error)
 }
 
 func (a *NativeHttpClientAdapter) Do(ctx context.Context, req Request) (Response, error) {
-	res, err := a.cli.Do(req.(*nativeReqAdapter).req)
+	r := req.(*nativeReqAdapter).req
+
+	recorder := timingsRecorderFrom(ctx)
+	if recorder != nil {
+		recorder.begin()
+		r = r.WithContext(httptrace.WithClientTrace(r.Context(), recorder.clientTrace()))
+	}
+
+	res, err := a.cli.Do(r)
+
+	if recorder != nil && res != nil {
+		res.Body = &timedBody{ReadCloser: res.Body, recorder: recorder}
+	}
+
 	return adaptResNative(res), err
 }
 
@@ -0,0 +1,289 @@
+package withttp
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	APIKeyInHeader APIKeyLocation = "header"
+	APIKeyInQuery  APIKeyLocation = "query"
+)
+
+// DefaultTokenExpiryLeeway is how long before their expiry cached tokens are refreshed, so that
+// they do not expire on their way to the server.
+const DefaultTokenExpiryLeeway = 10 * time.Second
+
+var (
+	ErrUnknownAuthKind       = errors.New("unknown auth kind")
+	ErrUnknownAPIKeyLocation = errors.New("unknown api key location")
+)
+
+type (
+	// APIKeyLocation tells where API keys are sent, either a header or a query string parameter.
+	APIKeyLocation string
+
+	// Token is an access token, sent in the authorization header as "<Type> <Value>".
+	Token struct {
+		Value string
+		// Type is the authorization scheme of the token. Defaults to Bearer.
+		Type string
+		// Expiry is when the token expires. Tokens without expiry never do.
+		Expiry time.Time
+	}
+
+	// TokenSource supplies access tokens.
+	TokenSource interface {
+		Token(ctx context.Context) (Token, error)
+	}
+
+	TokenSourceFunc func(ctx context.Context) (Token, error)
+
+	// tokenInvalidator is implemented by token sources able to discard their cached token, such as
+	// CachedTokenSource.
+	tokenInvalidator interface {
+		Invalidate()
+	}
+
+	// rewindableRequest is implemented by requests able to tell whether they can be sent again,
+	// which those with streamed bodies cannot.
+	rewindableRequest interface {
+		rewind() bool
+	}
+
+	// CachedTokenSource fetches tokens from its inner source lazily, on first use, and caches them
+	// until they are about to expire. It is safe for concurrent use, and concurrent callers
+	// waiting for a refresh share its outcome rather than fetching tokens of their own. Refreshes
+	// outlive the callers giving up on them, whose contexts are done, so that others still share
+	// them; inner sources are therefore expected to time out on their own.
+	CachedTokenSource struct {
+		inner  TokenSource
+		leeway time.Duration
+		now    func() time.Time
+
+		mu      sync.Mutex
+		token   Token
+		fetched bool
+		// refresh is the refresh in flight, if any.
+		refresh *tokenRefresh
+	}
+
+	// tokenRefresh is the outcome of a refresh, ready once done is closed.
+	tokenRefresh struct {
+		done  chan struct{}
+		token Token
+		err   error
+	}
+
+	// tokenSourceOption authorizes requests with the tokens of a source, fetched with the context
+	// of the call when configured by one.
+	tokenSourceOption struct {
+		ts TokenSource
+	}
+)
+
+func (f TokenSourceFunc) Token(ctx context.Context) (Token, error) {
+	return f(ctx)
+}
+
+// Header returns the value of the authorization header carrying the token.
+func (t Token) Header() string {
+	kind := t.Type
+	if !StrIsset(kind) {
+		kind = string(authHeaderKindBearer)
+	}
+	return kind + " " + t.Value
+}
+
+// Expired tells whether the token expires within `leeway` from `now`.
+func (t Token) Expired(now time.Time, leeway time.Duration) bool {
+	return !t.Expiry.IsZero() && !now.Add(leeway).Before(t.Expiry)
+}
+
+// StaticTokenSource always supplies the same bearer `token`.
+func StaticTokenSource(token string) TokenSource {
+	return TokenSourceFunc(func(_ context.Context) (Token, error) {
+		return Token{Value: token}, nil
+	})
+}
+
+// NewCachedTokenSource caches the tokens of `inner`, refreshing them `leeway` before they expire.
+func NewCachedTokenSource(inner TokenSource, leeway time.Duration) *CachedTokenSource {
+	return &CachedTokenSource{inner: inner, leeway: leeway, now: time.Now}
+}
+
+func (s *CachedTokenSource) Token(ctx context.Context) (Token, error) {
+	s.mu.Lock()
+
+	if s.fetched && !s.token.Expired(s.now(), s.leeway) {
+		token := s.token
+		s.mu.Unlock()
+		return token, nil
+	}
+
+	refresh := s.refresh
+	if refresh == nil {
+		refresh = &tokenRefresh{done: make(chan struct{})}
+		s.refresh = refresh
+		go s.fetch(context.WithoutCancel(ctx), refresh)
+	}
+
+	s.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return Token{}, ctx.Err()
+	case <-refresh.done:
+	}
+
+	if refresh.err != nil {
+		return Token{}, refresh.err
+	}
+	return refresh.token, nil
+}
+
+// fetch fetches a token from the inner source, caching it and handing it over to `refresh`.
+func (s *CachedTokenSource) fetch(ctx context.Context, refresh *tokenRefresh) {
+	token, err := s.inner.Token(ctx)
+
+	s.mu.Lock()
+	if err == nil {
+		s.token, s.fetched = token, true
+	}
+	s.refresh = nil
+	s.mu.Unlock()
+
+	refresh.token, refresh.err = token, err
+	close(refresh.done)
+}
+
+// Invalidate discards the cached token, so that the next one is fetched anew, as needed when the
+// server rejects it before its expiry.
+func (s *CachedTokenSource) Invalidate() {
+	s.mu.Lock()
+	s.fetched = false
+	s.mu.Unlock()
+}
+
+func BearerAuth[T any](token string) CallReqOptionFunc[T] {
+	return func(_ *Call[T], req Request) error {
+		header, err := CreateAuthorizationHeader(authHeaderKindBearer, token)
+		if err != nil {
+			return err
+		}
+		return ConfigureHeader(req, "authorization", header, true)
+	}
+}
+
+// APIKey sends the API key `value` as the header, or the query string parameter, called `name`.
+func APIKey[T any](in APIKeyLocation, name, value string) CallReqOptionFunc[T] {
+	return func(_ *Call[T], req Request) error {
+		return configureAPIKey(req, in, name, value)
+	}
+}
+
+// TokenAuth authorizes the call with the token supplied by `ts`, fetched with the context of the
+// call as it is sent. Sources able to discard their cached token, such as CachedTokenSource, are
+// forced to refresh it when the server replies with 401 Unauthorized, in which case the request
+// is sent once again, unless its body is streamed.
+func TokenAuth[T any](ts TokenSource) CallReqOptionFunc[T] {
+	return func(c *Call[T], _ Request) error {
+		c.reqMiddlewares = append(c.reqMiddlewares, TokenSourceMiddleware(ts))
+		return nil
+	}
+}
+
+// WithTokenSource authorizes every request of an endpoint with the token supplied by `ts`, fetched
+// with the context of the call. Configured outside of calls, tokens are fetched with a background
+// context.
+func WithTokenSource(ts TokenSource) ReqOption {
+	return tokenSourceOption{ts: ts}
+}
+
+func (o tokenSourceOption) Configure(req Request) error {
+	return configureToken(context.Background(), req, o.ts)
+}
+
+func (o tokenSourceOption) configureContext(ctx context.Context, req Request) error {
+	return configureToken(ctx, req, o.ts)
+}
+
+// TokenSourceMiddleware authorizes every request sent by the client with the token supplied by `ts`.
+// Unauthorized requests are retried as TokenAuth does.
+func TokenSourceMiddleware(ts TokenSource) Middleware {
+	retry := retryUnauthorized(ts)
+
+	return func(next Client) Client {
+		next = retry(next)
+
+		return doClient{
+			Client: next,
+			do: func(ctx context.Context, req Request) (Response, error) {
+				if err := configureToken(ctx, req, ts); err != nil {
+					return nil, err
+				}
+				return next.Do(ctx, req)
+			},
+		}
+	}
+}
+
+// retryUnauthorized sends requests rejected with 401 Unauthorized once again with a fresh token,
+// provided `ts` can discard the rejected one and the request can be sent again.
+func retryUnauthorized(ts TokenSource) Middleware {
+	return MiddlewareFunc(func(ctx context.Context, req Request, next DoFunc) (Response, error) {
+		res, err := next(ctx, req)
+		if err != nil || res == nil || res.Status() != http.StatusUnauthorized {
+			return res, err
+		}
+
+		invalidator, ok := ts.(tokenInvalidator)
+		if !ok {
+			return res, err
+		}
+
+		if rw, ok := req.(rewindableRequest); !ok || !rw.rewind() {
+			return res, err
+		}
+
+		invalidator.Invalidate()
+
+		if err = configureToken(ctx, req, ts); err != nil {
+			return res, err
+		}
+
+		if body := res.Body(); body != nil {
+			_ = body.Close()
+		}
+
+		return next(ctx, req)
+	})
+}
+
+func configureToken(ctx context.Context, req Request, ts TokenSource) error {
+	token, err := ts.Token(ctx)
+	if err != nil {
+		return errors.Wrap(err, "token source")
+	}
+	return ConfigureHeader(req, "authorization", token.Header(), true)
+}
+
+func configureAPIKey(req Request, in APIKeyLocation, name, value string) error {
+	switch in {
+	case APIKeyInHeader:
+		return ConfigureHeader(req, name, value, true)
+	case APIKeyInQuery:
+		u := req.URL()
+		qs := u.Query()
+		qs.Set(name, value)
+		u.RawQuery = qs.Encode()
+		req.SetURL(u)
+		return nil
+	default:
+		return errors.Wrapf(ErrUnknownAPIKeyLocation, "got: '%s'", in)
+	}
+}
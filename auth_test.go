@@ -0,0 +1,326 @@
+package withttp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestCall_Auth(t *testing.T) {
+	type (
+		args struct {
+			configure func(c *Call[any]) *Call[any]
+		}
+
+		want struct {
+			header string
+			value  string
+			query  string
+			err    error
+		}
+
+		testCase struct {
+			name string
+			args args
+			want want
+		}
+	)
+
+	tests := []testCase{
+		{
+			name: "bearer",
+			args: args{
+				configure: func(c *Call[any]) *Call[any] { return c.BearerAuth("s3cr3t") },
+			},
+			want: want{header: "authorization", value: "Bearer s3cr3t"},
+		},
+		{
+			name: "empty bearer",
+			args: args{
+				configure: func(c *Call[any]) *Call[any] { return c.BearerAuth("") },
+			},
+			want: want{err: ErrAssertion},
+		},
+		{
+			name: "api key in header",
+			args: args{
+				configure: func(c *Call[any]) *Call[any] {
+					return c.APIKey(APIKeyInHeader, "x-api-key", "s3cr3t")
+				},
+			},
+			want: want{header: "x-api-key", value: "s3cr3t"},
+		},
+		{
+			name: "api key in query",
+			args: args{
+				configure: func(c *Call[any]) *Call[any] {
+					return c.APIKey(APIKeyInQuery, "api_key", "s3cr3t")
+				},
+			},
+			want: want{query: "api_key=s3cr3t&page=2"},
+		},
+		{
+			name: "api key elsewhere",
+			args: args{
+				configure: func(c *Call[any]) *Call[any] {
+					return c.APIKey("cookie", "api_key", "s3cr3t")
+				},
+			},
+			want: want{err: ErrUnknownAPIKeyLocation},
+		},
+		{
+			name: "token source",
+			args: args{
+				configure: func(c *Call[any]) *Call[any] {
+					return c.TokenAuth(TokenSourceFunc(func(context.Context) (Token, error) {
+						return Token{Value: "s3cr3t", Type: "MAC"}, nil
+					}))
+				},
+			},
+			want: want{header: "authorization", value: "MAC s3cr3t"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake := NewFakeClient()
+			fake.On("", "").Respond(http.StatusOK, nil)
+
+			call := test.args.configure(NewCall[any](fake).URL("https://example.com/?page=2"))
+
+			err := call.Call(context.TODO())
+			if !errors.Is(err, test.want.err) {
+				t.Fatalf("unexpected error, want %v, have %v", test.want.err, err)
+			}
+
+			if test.want.err != nil {
+				return
+			}
+
+			if test.want.header != "" {
+				if value, _ := call.Req.Header(test.want.header); value != test.want.value {
+					t.Errorf("unexpected %s header, want '%s', have '%s'",
+						test.want.header, test.want.value, value)
+				}
+			}
+
+			if test.want.query != "" && call.Req.URL().RawQuery != test.want.query {
+				t.Errorf("unexpected query, want '%s', have '%s'",
+					test.want.query, call.Req.URL().RawQuery)
+			}
+		})
+	}
+}
+
+func TestCreateAuthorizationHeader_UnknownKind(t *testing.T) {
+	if _, err := CreateAuthorizationHeader("Negotiate", "ticket"); !errors.Is(err, ErrUnknownAuthKind) {
+		t.Errorf("unexpected error, want %v, have %v", ErrUnknownAuthKind, err)
+	}
+}
+
+func TestCachedTokenSource(t *testing.T) {
+	var fetches int32
+
+	now := time.Date(2022, 8, 1, 10, 0, 0, 0, time.UTC)
+
+	ts := NewCachedTokenSource(TokenSourceFunc(func(context.Context) (Token, error) {
+		n := atomic.AddInt32(&fetches, 1)
+		time.Sleep(10 * time.Millisecond)
+		return Token{Value: string(rune('a' + n - 1)), Expiry: now.Add(time.Minute)}, nil
+	}), DefaultTokenExpiryLeeway)
+	ts.now = func() time.Time { return now }
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if token, err := ts.Token(context.TODO()); err != nil || token.Value != "a" {
+				t.Errorf("unexpected token, want 'a', have '%s' (err: %v)", token.Value, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if fetches != 1 {
+		t.Fatalf("unexpected fetches, want 1, have %d", fetches)
+	}
+
+	// Within the leeway, tokens are deemed expired.
+	now = now.Add(time.Minute - DefaultTokenExpiryLeeway)
+
+	if token, _ := ts.Token(context.TODO()); token.Value != "b" {
+		t.Errorf("unexpected token, want 'b', have '%s'", token.Value)
+	}
+
+	ts.Invalidate()
+
+	if token, _ := ts.Token(context.TODO()); token.Value != "c" {
+		t.Errorf("unexpected token, want 'c', have '%s'", token.Value)
+	}
+}
+
+func TestCachedTokenSource_Cancelled(t *testing.T) {
+	var fetches int32
+
+	release := make(chan struct{})
+
+	ts := NewCachedTokenSource(TokenSourceFunc(func(ctx context.Context) (Token, error) {
+		atomic.AddInt32(&fetches, 1)
+		<-release
+		return Token{Value: "a"}, ctx.Err()
+	}), DefaultTokenExpiryLeeway)
+
+	waiting := make(chan Token)
+	go func() {
+		token, _ := ts.Token(context.TODO())
+		waiting <- token
+	}()
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+	defer cancel()
+
+	// Callers give up on refreshes once their context is done, while others keep waiting.
+	gaveUp := make(chan error, 1)
+	go func() {
+		_, err := ts.Token(ctx)
+		gaveUp <- err
+	}()
+
+	select {
+	case err := <-gaveUp:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("unexpected error, want %v, have %v", context.DeadlineExceeded, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("unexpected caller still waiting, want it gave up")
+	}
+
+	close(release)
+
+	if token := <-waiting; token.Value != "a" {
+		t.Errorf("unexpected token, want 'a', have '%s'", token.Value)
+	}
+
+	if fetches != 1 {
+		t.Errorf("unexpected fetches, want 1, have %d", fetches)
+	}
+}
+
+func TestTokenSource_Context(t *testing.T) {
+	type ctxKey struct{}
+
+	type (
+		args struct {
+			call func(ctx context.Context, ts TokenSource) error
+		}
+
+		testCase struct {
+			name string
+			args args
+		}
+	)
+
+	newFake := func() *FakeClient {
+		fake := NewFakeClient()
+		fake.On("", "").WithHeader("authorization", "Bearer s3cr3t").Respond(http.StatusOK, nil)
+		return fake
+	}
+
+	tests := []testCase{
+		{
+			name: "call",
+			args: args{
+				call: func(ctx context.Context, ts TokenSource) error {
+					return NewCall[any](newFake()).URL("https://example.com").TokenAuth(ts).Call(ctx)
+				},
+			},
+		},
+		{
+			name: "endpoint",
+			args: args{
+				call: func(ctx context.Context, ts TokenSource) error {
+					endpoint := NewEndpoint("mock").
+						Request(BaseURL("https://example.com"), WithTokenSource(ts))
+					return NewCall[any](newFake()).CallEndpoint(ctx, endpoint)
+				},
+			},
+		},
+		{
+			name: "client",
+			args: args{
+				call: func(ctx context.Context, ts TokenSource) error {
+					cli := WithMiddlewares(newFake(), TokenSourceMiddleware(ts))
+					return NewCall[any](cli).URL("https://example.com").Call(ctx)
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var have any
+
+			ts := TokenSourceFunc(func(ctx context.Context) (Token, error) {
+				have = ctx.Value(ctxKey{})
+				return Token{Value: "s3cr3t"}, nil
+			})
+
+			ctx := context.WithValue(context.TODO(), ctxKey{}, "call")
+
+			if err := test.args.call(ctx, ts); err != nil {
+				t.Fatalf("unexpected error, want none, have %v", err)
+			}
+
+			if have != "call" {
+				t.Errorf("unexpected context, want the one of the call, have value '%v'", have)
+			}
+		})
+	}
+}
+
+func TestTokenSourceMiddleware(t *testing.T) {
+	failing := TokenSourceFunc(func(context.Context) (Token, error) {
+		return Token{}, io.ErrUnexpectedEOF
+	})
+
+	for name, test := range map[string]struct {
+		ts     TokenSource
+		header string
+		err    error
+	}{
+		"token is set":           {ts: StaticTokenSource("s3cr3t"), header: "Bearer s3cr3t"},
+		"token source error":     {ts: failing, err: io.ErrUnexpectedEOF},
+		"endpoint token is kept": {ts: nil, header: "Bearer endpoint"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			fake := NewFakeClient()
+			fake.On("", "").Respond(http.StatusOK, nil)
+
+			cli := Client(fake)
+			if test.ts != nil {
+				cli = WithMiddlewares(cli, TokenSourceMiddleware(test.ts))
+			}
+
+			endpoint := NewEndpoint("mock").
+				Request(WithTokenSource(StaticTokenSource("endpoint")))
+
+			call := NewCall[any](cli)
+
+			err := call.CallEndpoint(context.TODO(), endpoint)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("unexpected error, want %v, have %v", test.err, err)
+			}
+
+			if value, _ := call.Req.Header("authorization"); test.err == nil && value != test.header {
+				t.Errorf("unexpected authorization header, want '%s', have '%s'", test.header, value)
+			}
+		})
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"log/slog"
+	"strconv"
 	"sync"
+	"time"
 )
 
 type (
@@ -21,15 +24,32 @@ type (
 	CallResOptionFunc[T any] func(c *Call[T], res Response) error
 
 	Call[T any] struct {
-		logger logger
+		logger     logger
+		slogger    *slog.Logger
+		slogConfig SlogConfig
+		redaction  *RedactionPolicy
+		stats      *callStats
+		tracer     CallTracer
+		trace      CallTrace
 
 		client Client
 
+		decompressionDisabled bool
+
+		// reqMiddlewares decorate the client for a single execution of the call. Options needing to
+		// act on how requests are sent, such as retrying them, add theirs while configuring them.
+		reqMiddlewares []Middleware
+		// reqSigners sign requests right before they are sent, once every other option and
+		// middleware is done with them.
+		reqSigners []Signer
+
 		reqOptions []ReqOption // TODO: Linked Lists
 		resOptions []ResOption
 
 		Req Request
 		Res Response
+		// Timings of the last attempt at sending the request, once the call is done with.
+		Timings Timings
 
 		BodyRaw    []byte
 		BodyParsed T
@@ -38,9 +58,22 @@ type (
 		ReqBodyRaw     []byte
 		ReqIsStream    bool
 
+		ReqContentEncoding  string
+		ReqCompressionLevel int
+
 		ReqStreamWriter  func(ctx context.Context, c *Call[T], res Request, wg *sync.WaitGroup) error
 		ReqStreamSniffer func([]byte, error)
 		ReqShouldSniff   bool
+
+		ResStreamSniffer func([]byte, error)
+		ResShouldSniff   bool
+	}
+
+	// sniffedReadCloser hands every chunk read from the inner reader over to the sniffer.
+	sniffedReadCloser struct {
+		io.ReadCloser
+
+		sniffer func([]byte, error)
 	}
 )
 
@@ -58,10 +91,44 @@ func NewCall[T any](client Client) *Call[T] {
 
 func (c *Call[T]) bodyReader(res Response) (rc io.ReadCloser) {
 	if c.BodyRaw != nil {
-		rc = io.NopCloser(bytes.NewReader(c.BodyRaw))
-	} else {
-		rc = res.Body()
+		return io.NopCloser(bytes.NewReader(c.BodyRaw))
+	}
+
+	rc = res.Body()
+
+	if rc != nil {
+		stats := c.stats
+		rc = progressReadCloser{ReadCloser: rc, progress: func(n int) { stats.bytesIn.Add(int64(n)) }}
+	}
+
+	if c.trace != nil && rc != nil {
+		rc = progressReadCloser{ReadCloser: rc, progress: c.trace.Received}
+	}
+
+	if !c.decompressionDisabled {
+		if encoding, ok := res.Header("content-encoding"); ok {
+			rc = DecodeContentEncoding(rc, encoding)
+		}
+	}
+
+	if c.ResShouldSniff {
+		rc = sniffedReadCloser{ReadCloser: rc, sniffer: c.ResStreamSniffer}
+	}
+
+	return
+}
+
+func (r sniffedReadCloser) Read(p []byte) (n int, err error) {
+	n, err = r.ReadCloser.Read(p)
+
+	if n > 0 {
+		r.sniffer(p[:n], nil)
+	}
+
+	if err != nil && err != io.EOF {
+		r.sniffer(nil, err)
 	}
+
 	return
 }
 
@@ -112,6 +179,23 @@ func (c *Call[T]) CallEndpoint(ctx context.Context, e *Endpoint) (err error) {
 }
 
 func (c *Call[T]) callEndpoint(ctx context.Context, e *Endpoint) (err error) {
+	c.stats = &callStats{start: time.Now()}
+	c.Res = nil
+
+	if e != nil {
+		c.stats.endpoint = e.name
+	}
+
+	ctx = context.WithValue(ctx, callStatsKey{}, c.stats)
+	ctx = c.startTrace(ctx, e)
+
+	defer func() {
+		c.Timings = c.stats.timings.snapshot()
+		c.stats.flush()
+		c.endTrace(err)
+		c.logCall(ctx, e, err)
+	}()
+
 	req, err := c.client.Request(ctx)
 	defer func() { c.Req = req }()
 
@@ -121,16 +205,27 @@ func (c *Call[T]) callEndpoint(ctx context.Context, e *Endpoint) (err error) {
 
 	if e != nil {
 		for _, opt := range e.requestOpts {
-			if err = opt.Configure(req); err != nil {
+			if co, ok := opt.(contextReqOption); ok {
+				err = co.configureContext(ctx, req)
+			} else {
+				err = opt.Configure(req)
+			}
+			if err != nil {
 				return err
 			}
 		}
 	}
 
+	c.reqMiddlewares, c.reqSigners = nil, nil
+
 	if err = c.configureReq(req); err != nil {
 		return
 	}
 
+	if err = c.compressReq(req); err != nil {
+		return
+	}
+
 	var wg *sync.WaitGroup
 
 	if c.ReqIsStream {
@@ -142,9 +237,9 @@ func (c *Call[T]) callEndpoint(ctx context.Context, e *Endpoint) (err error) {
 		}()
 	}
 
-	c.log("[withttp] %s %s", req.Method(), req.URL().String())
+	c.log("[withttp] %s %s", req.Method(), c.redactionPolicy().URL(req.URL()))
 
-	res, err := c.client.Do(ctx, req)
+	res, err := WithMiddlewares(c.client, c.middlewares(e)...).Do(ctx, req)
 
 	if c.ReqIsStream {
 		wg.Wait()
@@ -175,6 +270,75 @@ func (c *Call[T]) callEndpoint(ctx context.Context, e *Endpoint) (err error) {
 	return
 }
 
+// middlewares returns the middlewares of the current execution, tracing and signing being the
+// innermost ones, so that requests sent again, such as after a 401 Unauthorized, are traced and
+// signed again, the trace context being signed too, followed by the one logging attempts, if any,
+// so that they are logged as sent.
+func (c *Call[T]) middlewares(e *Endpoint) []Middleware {
+	if len(c.reqSigners) == 0 && c.slogger == nil && c.trace == nil {
+		return c.reqMiddlewares
+	}
+
+	mws := make([]Middleware, 0, len(c.reqMiddlewares)+3)
+	mws = append(mws, c.reqMiddlewares...)
+
+	if c.trace != nil {
+		mws = append(mws, c.traceMiddleware())
+	}
+
+	if len(c.reqSigners) > 0 {
+		mws = append(mws, SignMiddleware(c.reqSigners...))
+	}
+
+	if c.slogger != nil {
+		mws = append(mws, c.attemptsMiddleware(e))
+	}
+
+	return mws
+}
+
+// compressReq compresses the request body, whichever way it was set, if compression was requested.
+// Fixed bodies are compressed in memory, so that they can still be sent again and signed.
+func (c *Call[T]) compressReq(req Request) error {
+	if !StrIsset(c.ReqContentEncoding) {
+		return nil
+	}
+
+	encoding, level := c.ReqContentEncoding, c.ReqCompressionLevel
+	payload, buffered := RequestPayload(req)
+
+	switch {
+	case c.ReqIsStream:
+		// Stream writer has not started yet, so whatever it writes from now on gets compressed.
+		stream, err := newCompressedWriteStream(req.BodyStream(), encoding, level)
+		if err != nil {
+			return err
+		}
+		req.SetBodyStream(stream, -1)
+	case buffered && len(payload) > 0:
+		compressed, err := EncodeContentEncoding(payload, encoding, level)
+		if err != nil {
+			return err
+		}
+		req.SetBody(compressed)
+		if _, ok := req.Header("content-length"); ok {
+			req.SetHeader("content-length", strconv.Itoa(len(compressed)))
+		}
+	case !buffered && req.BodyStream() != nil:
+		stream, err := newCompressedReadStream(req.BodyStream(), encoding, level)
+		if err != nil {
+			return err
+		}
+		req.SetBodyStream(stream, -1)
+	default:
+		return nil
+	}
+
+	req.SetHeader("content-encoding", encoding)
+
+	return nil
+}
+
 func (c *Call[T]) log(tpl string, args ...any) {
 	if c.logger == nil {
 		return
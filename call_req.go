@@ -64,6 +64,10 @@ func (c *Call[T]) RawBody(payload []byte) *Call[T] {
 	return c.withReq(RawBody[T](payload))
 }
 
+func (c *Call[T]) CompressRequest(encoding string, level int) *Call[T] {
+	return c.withReq(CompressRequest[T](encoding, level))
+}
+
 func (c *Call[T]) ContentLength(length int) *Call[T] {
 	return c.Header("content-length", strconv.FormatInt(int64(length), 10), true)
 }
@@ -80,6 +84,26 @@ func (c *Call[T]) BasicAuth(user, pass string) *Call[T] {
 	return c.withReq(BasicAuth[T](user, pass))
 }
 
+func (c *Call[T]) BearerAuth(token string) *Call[T] {
+	return c.withReq(BearerAuth[T](token))
+}
+
+func (c *Call[T]) APIKey(in APIKeyLocation, name, value string) *Call[T] {
+	return c.withReq(APIKey[T](in, name, value))
+}
+
+func (c *Call[T]) DigestAuth(user, pass string) *Call[T] {
+	return c.withReq(DigestAuth[T](user, pass))
+}
+
+func (c *Call[T]) Sign(signers ...Signer) *Call[T] {
+	return c.withReq(Sign[T](signers...))
+}
+
+func (c *Call[T]) TokenAuth(ts TokenSource) *Call[T] {
+	return c.withReq(TokenAuth[T](ts))
+}
+
 func (c *Call[T]) ContentType(ct string) *Call[T] {
 	return c.withReq(ContentType[T](ct))
 }
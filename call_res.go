@@ -7,6 +7,10 @@ func (c *Call[T]) Response(opts ...ResOption) *Call[T] {
 	return c
 }
 
+func (c *Call[T]) ResponseSniffed(fn func([]byte, error)) *Call[T] {
+	return c.withReq(ResponseSniffer[T](fn))
+}
+
 func (c *Call[T]) ReadBody() *Call[T] {
 	return c.withRes(ParseBodyRaw[T]())
 }
@@ -19,20 +23,21 @@ func (c *Call[T]) ParseStream(factory StreamFactory[T], fn func(T) bool) *Call[T
 	return c.withRes(ParseStream[T](factory, fn))
 }
 
-func (c *Call[T]) ParseJSONEachRowChan(out chan<- T) *Call[T] {
-	return c.ParseStreamChan(NewJSONEachRowStreamFactory[T](), out)
+func (c *Call[T]) ParseJSONEachRowChan(out chan<- T, opts ...StreamOption) *Call[T] {
+	return c.ParseStreamChan(NewJSONEachRowStreamFactory[T](opts...), out)
 }
 
-func (c *Call[T]) ParseJSONEachRow(fn func(T) bool) *Call[T] {
-	return c.ParseStream(NewJSONEachRowStreamFactory[T](), fn)
+func (c *Call[T]) ParseJSONEachRow(fn func(T) bool, opts ...StreamOption) *Call[T] {
+	return c.ParseStream(NewJSONEachRowStreamFactory[T](opts...), fn)
 }
 
 func (c *Call[T]) ParseCSV(
 	ignoreLines int,
 	parser csvparser.Parser[T],
 	fn func(T) bool,
+	opts ...StreamOption,
 ) *Call[T] {
-	return c.ParseStream(NewCSVStreamFactory[T](ignoreLines, parser), fn)
+	return c.ParseStream(NewCSVStreamFactory[T](ignoreLines, parser, opts...), fn)
 }
 
 func (c *Call[T]) IgnoreResponseBody() *Call[T] {
@@ -4,6 +4,8 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"log/slog"
+	"net/http"
 )
 
 func (c *Call[T]) WithLogger(l logger) *Call[T] {
@@ -11,44 +13,76 @@ func (c *Call[T]) WithLogger(l logger) *Call[T] {
 	return c
 }
 
+// WithSlog logs the call through `l`, once done with it, along with every attempt at sending it.
+// Records hold the endpoint name, method, URL, status, duration, attempt and bytes sent and
+// received, the URL being redacted as per the redaction policy of the call, see Redact.
+func (c *Call[T]) WithSlog(l *slog.Logger, cfg SlogConfig) *Call[T] {
+	c.slogger = l
+	c.slogConfig = cfg
+	return c
+}
+
+// Redact sets the policy logs and dumps of the call are redacted with, instead of
+// DefaultRedactionPolicy. An empty policy redacts nothing.
+func (c *Call[T]) Redact(policy RedactionPolicy) *Call[T] {
+	c.redaction = &policy
+	return c
+}
+
+func (c *Call[T]) redactionPolicy() *RedactionPolicy {
+	if c.redaction != nil {
+		return c.redaction
+	}
+	return DefaultRedactionPolicy()
+}
+
+// DisableDecompression makes response parsers read the body as sent by the server, instead of
+// transparently undoing its Content-Encoding.
+func (c *Call[T]) DisableDecompression() *Call[T] {
+	c.decompressionDisabled = true
+	return c
+}
+
+// Log dumps the request and response of the call into `w`, redacted as per the redaction policy of
+// the call, see Redact.
 func (c *Call[T]) Log(w io.Writer) {
 	buf := bufio.NewWriter(w)
+	policy := c.redactionPolicy()
 
 	_, _ = buf.WriteString(c.Req.Method())
 	_, _ = buf.WriteString(" ")
-	_, _ = buf.WriteString(c.Req.URL().String())
+	_, _ = buf.WriteString(policy.URL(c.Req.URL()))
 	_, _ = buf.WriteString("\n")
 
 	c.Req.RangeHeaders(func(key string, value string) {
 		_, _ = buf.WriteString(key)
 		_ = buf.WriteByte(':')
 		_ = buf.WriteByte(' ')
-		_, _ = buf.WriteString(value)
+		_, _ = buf.WriteString(policy.Header(key, value))
 		_ = buf.WriteByte('\n')
 	})
 
 	if !c.ReqIsStream && len(c.ReqBodyRaw) > 0 {
 		_ = buf.WriteByte('\n')
-		_, _ = buf.Write(c.ReqBodyRaw)
+		_, _ = buf.Write(policy.Body(c.ReqBodyRaw))
 		_ = buf.WriteByte('\n')
 	}
 
 	_ = buf.WriteByte('\n')
 
-	// TODO: print text repr of status code
-	_, _ = buf.WriteString(fmt.Sprintf("%d %s", c.Res.Status(), ""))
+	_, _ = buf.WriteString(fmt.Sprintf("%d %s", c.Res.Status(), http.StatusText(c.Res.Status())))
 	_ = buf.WriteByte('\n')
 
 	c.Res.RangeHeaders(func(key string, value string) {
 		_, _ = buf.WriteString(key)
 		_ = buf.WriteByte(':')
 		_ = buf.WriteByte(' ')
-		_, _ = buf.WriteString(value)
+		_, _ = buf.WriteString(policy.Header(key, value))
 		_ = buf.WriteByte('\n')
 	})
 
 	if len(c.BodyRaw) > 0 {
-		_, _ = buf.Write(c.BodyRaw)
+		_, _ = buf.Write(policy.Body(c.BodyRaw))
 	}
 
 	_ = buf.WriteByte('\n')
@@ -0,0 +1,345 @@
+package withttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// CassetteReplay serves responses out of cassettes, failing on requests not recorded.
+	CassetteReplay CassetteMode = iota
+	// CassetteRecord sends requests through the inner client, recording them along with their
+	// responses, to be saved into cassettes.
+	CassetteRecord
+)
+
+var ErrUnmatchedInteraction = errors.New("no interaction recorded matches the request")
+
+type (
+	CassetteMode int
+
+	// CassetteConfig configures cassette clients.
+	CassetteConfig struct {
+		Mode CassetteMode
+		// Client sends requests while recording.
+		Client Client
+		// Matchers tell which interactions recorded match requests being replayed, which are to be
+		// matched by them all. Defaults to MatchMethod, MatchURL and MatchBody.
+		Matchers []CassetteMatcher
+		// Redaction is the policy interactions are scrubbed with before being recorded, requests
+		// being replayed scrubbed alike before being matched. Defaults to DefaultRedactionPolicy. An
+		// empty policy scrubs nothing.
+		Redaction *RedactionPolicy
+	}
+
+	// CassetteMatcher tells whether the request being replayed, `req`, matches the `recorded` one.
+	CassetteMatcher func(req, recorded CassetteRequest) bool
+
+	// Cassette holds the interactions recorded, as saved into files.
+	Cassette struct {
+		Interactions []Interaction `json:"interactions" yaml:"interactions"`
+	}
+
+	Interaction struct {
+		Request  CassetteRequest  `json:"request" yaml:"request"`
+		Response CassetteResponse `json:"response" yaml:"response"`
+	}
+
+	CassetteRequest struct {
+		Method       string              `json:"method" yaml:"method"`
+		URL          string              `json:"url" yaml:"url"`
+		Headers      map[string][]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+		CassetteBody `yaml:",inline"`
+	}
+
+	CassetteResponse struct {
+		Status       int                 `json:"status" yaml:"status"`
+		Headers      map[string][]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+		CassetteBody `yaml:",inline"`
+	}
+
+	// CassetteBody holds bodies as text, base64 encoded unless UTF-8. Streamed request bodies are
+	// not recorded.
+	CassetteBody struct {
+		Body     string `json:"body,omitempty" yaml:"body,omitempty"`
+		Encoding string `json:"encoding,omitempty" yaml:"encoding,omitempty"`
+	}
+
+	// CassetteClient records interactions with servers into cassette files, in record mode, and
+	// replays them, in replay mode, so that tests do not depend on servers being reachable. Files
+	// are YAML if named so, with the .yaml or .yml extension, JSON otherwise. Interactions are
+	// replayed once each, in the order they were recorded. It is safe for concurrent use.
+	CassetteClient struct {
+		path string
+		cfg  CassetteConfig
+
+		mu       sync.Mutex
+		cassette Cassette
+		replayed []bool
+	}
+)
+
+// MatchMethod matches requests by method.
+func MatchMethod(req, recorded CassetteRequest) bool {
+	return req.Method == recorded.Method
+}
+
+// MatchURL matches requests by URL, query string included.
+func MatchURL(req, recorded CassetteRequest) bool {
+	return req.URL == recorded.URL
+}
+
+// MatchBody matches requests by body.
+func MatchBody(req, recorded CassetteRequest) bool {
+	return req.CassetteBody == recorded.CassetteBody
+}
+
+// MatchHeaders matches requests by the values of the headers `keys`.
+func MatchHeaders(keys ...string) CassetteMatcher {
+	return func(req, recorded CassetteRequest) bool {
+		for _, key := range keys {
+			key = http.CanonicalHeaderKey(key)
+			if strings.Join(req.Headers[key], ",") != strings.Join(recorded.Headers[key], ",") {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// NewCassetteClient creates a client recording into, or replaying out of, the cassette at `path`,
+// which is loaded in replay mode.
+func NewCassetteClient(path string, cfg CassetteConfig) (*CassetteClient, error) {
+	if len(cfg.Matchers) == 0 {
+		cfg.Matchers = []CassetteMatcher{MatchMethod, MatchURL, MatchBody}
+	}
+	if cfg.Redaction == nil {
+		cfg.Redaction = DefaultRedactionPolicy()
+	}
+
+	c := &CassetteClient{path: path, cfg: cfg}
+
+	switch cfg.Mode {
+	case CassetteRecord:
+		if cfg.Client == nil {
+			return nil, errors.Wrapf(ErrInsufficientParams, "got: no client to record with")
+		}
+	case CassetteReplay:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if c.isYAML() {
+			err = yaml.Unmarshal(data, &c.cassette)
+		} else {
+			err = json.Unmarshal(data, &c.cassette)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot load cassette '%s'", path)
+		}
+
+		c.replayed = make([]bool, len(c.cassette.Interactions))
+	}
+
+	return c, nil
+}
+
+func (c *CassetteClient) Request(ctx context.Context) (Request, error) {
+	if c.cfg.Mode == CassetteRecord {
+		return c.cfg.Client.Request(ctx)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	return adaptReqMock(req), nil
+}
+
+func (c *CassetteClient) Do(ctx context.Context, req Request) (Response, error) {
+	if c.cfg.Mode == CassetteRecord {
+		return c.record(ctx, req)
+	}
+	return c.replay(req)
+}
+
+// Save saves the interactions recorded so far into the cassette file, creating its directory if
+// need be.
+func (c *CassetteClient) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var (
+		data []byte
+		err  error
+	)
+
+	if c.isYAML() {
+		data, err = yaml.Marshal(c.cassette)
+	} else {
+		data, err = json.MarshalIndent(c.cassette, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(c.path, data, 0o644)
+}
+
+func (c *CassetteClient) record(ctx context.Context, req Request) (Response, error) {
+	recorded := c.request(req)
+
+	res, err := c.cfg.Client.Do(ctx, req)
+	if err != nil {
+		return res, err
+	}
+
+	var body []byte
+	if rc := res.Body(); rc != nil {
+		body, err = io.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil {
+			return res, err
+		}
+	}
+	res.SetBody(io.NopCloser(bytes.NewReader(body)))
+
+	policy := c.cfg.Redaction
+	scrubbed := policy.Body(body)
+
+	headers := cassetteHeaders(policy, res)
+	if _, ok := headers["Content-Length"]; ok {
+		// Bodies recorded may be shorter or longer than those received, once redacted.
+		headers["Content-Length"] = []string{strconv.Itoa(len(scrubbed))}
+	}
+
+	interaction := Interaction{
+		Request: recorded,
+		Response: CassetteResponse{
+			Status:       res.Status(),
+			Headers:      headers,
+			CassetteBody: cassetteBody(scrubbed),
+		},
+	}
+
+	c.mu.Lock()
+	c.cassette.Interactions = append(c.cassette.Interactions, interaction)
+	c.mu.Unlock()
+
+	return res, nil
+}
+
+func (c *CassetteClient) replay(req Request) (Response, error) {
+	wanted := c.request(req)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, interaction := range c.cassette.Interactions {
+		if c.replayed[i] || !c.matches(wanted, interaction.Request) {
+			continue
+		}
+
+		c.replayed[i] = true
+
+		body, err := interaction.Response.bytes()
+		if err != nil {
+			return nil, err
+		}
+
+		header := make(http.Header, len(interaction.Response.Headers))
+		for key, values := range interaction.Response.Headers {
+			for _, value := range values {
+				header.Add(key, value)
+			}
+		}
+
+		status := interaction.Response.Status
+
+		return adaptResMock(&http.Response{
+			Status:        strconv.Itoa(status) + " " + http.StatusText(status),
+			StatusCode:    status,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+		}), nil
+	}
+
+	return nil, errors.Wrapf(ErrUnmatchedInteraction, "got: '%s %s'", wanted.Method, wanted.URL)
+}
+
+func (c *CassetteClient) matches(req, recorded CassetteRequest) bool {
+	for _, match := range c.cfg.Matchers {
+		if !match(req, recorded) {
+			return false
+		}
+	}
+	return true
+}
+
+// request returns `req` as recorded, scrubbed.
+func (c *CassetteClient) request(req Request) CassetteRequest {
+	policy := c.cfg.Redaction
+
+	recorded := CassetteRequest{
+		Method:  req.Method(),
+		URL:     policy.URL(req.URL()),
+		Headers: cassetteHeaders(policy, req),
+	}
+
+	if body, ok := RequestPayload(req); ok {
+		recorded.CassetteBody = cassetteBody(policy.Body(body))
+	}
+
+	return recorded
+}
+
+func (c *CassetteClient) isYAML() bool {
+	ext := strings.ToLower(filepath.Ext(c.path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+func (b CassetteBody) bytes() ([]byte, error) {
+	if b.Encoding == "base64" {
+		return base64.StdEncoding.DecodeString(b.Body)
+	}
+	return []byte(b.Body), nil
+}
+
+func cassetteBody(data []byte) CassetteBody {
+	if utf8.Valid(data) {
+		return CassetteBody{Body: string(data)}
+	}
+	return CassetteBody{Body: base64.StdEncoding.EncodeToString(data), Encoding: "base64"}
+}
+
+func cassetteHeaders(policy *RedactionPolicy, h header) map[string][]string {
+	headers := make(map[string][]string)
+	h.RangeHeaders(func(key, value string) {
+		key = http.CanonicalHeaderKey(key)
+		headers[key] = append(headers[key], policy.Header(key, value))
+	})
+
+	if len(headers) == 0 {
+		return nil
+	}
+	return headers
+}
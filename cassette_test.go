@@ -0,0 +1,188 @@
+package withttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestCassetteClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=abc")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(r.Method + " " + r.URL.Path + " " + r.Header.Get("x-tenant")))
+	}))
+	defer srv.Close()
+
+	type (
+		args struct {
+			file     string
+			matchers []CassetteMatcher
+			replay   func(cli Client) *Call[any]
+		}
+
+		want struct {
+			body string
+			err  error
+		}
+
+		testCase struct {
+			name string
+			args args
+			want want
+		}
+	)
+
+	call := func(cli Client, path, tenant string) *Call[any] {
+		return NewCall[any](cli).
+			URL(srv.URL+path+"?token=abc").
+			Method(http.MethodPost).
+			Header("x-tenant", tenant, true).
+			BearerAuth("s3cr3t").
+			RawBody([]byte(`{"password":"hunter2"}`)).
+			ReadBody()
+	}
+
+	tests := []testCase{
+		{
+			name: "json",
+			args: args{
+				file:   "cassette.json",
+				replay: func(cli Client) *Call[any] { return call(cli, "/b", "acme") },
+			},
+			want: want{body: "POST /b acme"},
+		},
+		{
+			name: "yaml",
+			args: args{
+				file:   "cassette.yaml",
+				replay: func(cli Client) *Call[any] { return call(cli, "/a", "acme") },
+			},
+			want: want{body: "POST /a acme"},
+		},
+		{
+			name: "unmatched",
+			args: args{
+				file:   "cassette.yaml",
+				replay: func(cli Client) *Call[any] { return call(cli, "/c", "acme") },
+			},
+			want: want{err: ErrUnmatchedInteraction},
+		},
+		{
+			name: "unmatched header",
+			args: args{
+				file:     "cassette.yaml",
+				matchers: []CassetteMatcher{MatchMethod, MatchURL, MatchHeaders("x-tenant")},
+				replay:   func(cli Client) *Call[any] { return call(cli, "/a", "other") },
+			},
+			want: want{err: ErrUnmatchedInteraction},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "cassettes", test.args.file)
+
+			recorder, err := NewCassetteClient(path, CassetteConfig{
+				Mode:   CassetteRecord,
+				Client: NetHttpClient(&http.Client{}),
+			})
+			if err != nil {
+				t.Fatalf("unexpected error, want none, have %v", err)
+			}
+
+			for _, p := range []string{"/a", "/b"} {
+				if err = call(recorder, p, "acme").Call(context.TODO()); err != nil {
+					t.Fatalf("unexpected error, want none, have %v", err)
+				}
+			}
+
+			if err = recorder.Save(); err != nil {
+				t.Fatalf("unexpected error, want none, have %v", err)
+			}
+
+			data, _ := os.ReadFile(path)
+			for _, secret := range []string{"s3cr3t", "hunter2", "token=abc", "session=abc"} {
+				if strings.Contains(string(data), secret) {
+					t.Errorf("unexpected secret '%s' in cassette:\n%s", secret, data)
+				}
+			}
+
+			player, err := NewCassetteClient(path, CassetteConfig{Matchers: test.args.matchers})
+			if err != nil {
+				t.Fatalf("unexpected error, want none, have %v", err)
+			}
+
+			replayed := test.args.replay(player)
+			if err = replayed.Call(context.TODO()); !errors.Is(err, test.want.err) {
+				t.Fatalf("unexpected error, want %v, have %v", test.want.err, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if have := string(replayed.BodyRaw); have != test.want.body {
+				t.Errorf("unexpected body, want '%s', have '%s'", test.want.body, have)
+			}
+
+			if have := replayed.Res.Status(); have != http.StatusCreated {
+				t.Errorf("unexpected status, want %d, have %d", http.StatusCreated, have)
+			}
+
+			// Interactions are replayed once.
+			err = test.args.replay(player).Call(context.TODO())
+			if !errors.Is(err, ErrUnmatchedInteraction) {
+				t.Errorf("unexpected error, want %v, have %v", ErrUnmatchedInteraction, err)
+			}
+		})
+	}
+}
+
+func TestCassetteClient_ContentLength(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", ContentTypeJSON)
+		_, _ = w.Write([]byte(`{ "id": 1, "token": "abc" }`))
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	recorder, err := NewCassetteClient(path, CassetteConfig{
+		Mode:   CassetteRecord,
+		Client: NetHttpClient(&http.Client{}),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error, want none, have %v", err)
+	}
+
+	if err = NewCall[any](recorder).URL(srv.URL).Call(context.TODO()); err != nil {
+		t.Fatalf("unexpected error, want none, have %v", err)
+	}
+
+	if err = recorder.Save(); err != nil {
+		t.Fatalf("unexpected error, want none, have %v", err)
+	}
+
+	player, err := NewCassetteClient(path, CassetteConfig{})
+	if err != nil {
+		t.Fatalf("unexpected error, want none, have %v", err)
+	}
+
+	call := NewCall[any](player).URL(srv.URL).ReadBody()
+	if err = call.Call(context.TODO()); err != nil {
+		t.Fatalf("unexpected error, want none, have %v", err)
+	}
+
+	want := strconv.Itoa(len(call.BodyRaw))
+	if have, _ := call.Res.Header("content-length"); have != want {
+		t.Errorf("unexpected content length, want %s, have %s", want, have)
+	}
+}
@@ -1,11 +1,16 @@
 package codec
 
-import "github.com/pkg/errors"
+import (
+	"github.com/pkg/errors"
+	"github.com/sonirico/withttp/csvparser"
+)
 
 var (
 	NativeJSONCodec        = NewNativeJsonCodec()
 	NativeJSONEachRowCodec = NewNativeJsonEachRowCodec(NativeJSONCodec)
 	ProxyBytesEncoder      = ProxyBytesCodec{}
+	NativeCSVCodec         = NewCSVCodec(csvparser.SeparatorComma, false)
+	NativeCSVHeaderCodec   = NewCSVCodec(csvparser.SeparatorComma, true)
 )
 
 var (
@@ -0,0 +1,177 @@
+package codec
+
+import (
+	"bufio"
+	"bytes"
+	"reflect"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/sonirico/withttp/csvparser"
+)
+
+type (
+	// CSVCodec encodes and decodes structs, and slices of them, as RFC 4180 CSV records whose
+	// columns are configured by the `csv` tag of the fields, see csvparser.FromStruct. Parsers are
+	// built once per type.
+	CSVCodec struct {
+		sep     byte
+		header  bool
+		parsers *sync.Map
+	}
+)
+
+// NewCSVCodec creates a CSV codec whose records are separated by `sep`. Codecs with `header`
+// encode slices preceded by a header record and bind columns by the header of decoded payloads.
+func NewCSVCodec(sep byte, header bool) CSVCodec {
+	return CSVCodec{sep: sep, header: header, parsers: new(sync.Map)}
+}
+
+// Encode encodes structs, or pointers to them, as a single record and slices of them as a record
+// each.
+func (c CSVCodec) Encode(x any) ([]byte, error) {
+	v := reflect.ValueOf(x)
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	buf := bytes.NewBuffer(nil)
+
+	switch v.Kind() {
+	case reflect.Struct:
+		parser, err := c.parser(v.Type())
+		if err != nil {
+			return nil, err
+		}
+
+		if err = parser.Encode(v.Interface(), buf); err != nil {
+			return nil, err
+		}
+	case reflect.Slice, reflect.Array:
+		parser, err := c.parser(indirect(v.Type().Elem()))
+		if err != nil {
+			return nil, err
+		}
+
+		if c.header {
+			if err = parser.EncodeHeader(buf); err != nil {
+				return nil, err
+			}
+		}
+
+		for i := 0; i < v.Len(); i++ {
+			if err = parser.Encode(v.Index(i).Interface(), buf); err != nil {
+				return nil, errors.Wrapf(err, "record %d", i)
+			}
+		}
+	default:
+		return nil, errors.Wrapf(ErrTypeAssertion, "want struct or slice of structs, have %T", x)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// EncodeHeader encodes the header record of `typ`, provided the codec has a header. Otherwise, it
+// returns nothing.
+func (c CSVCodec) EncodeHeader(typ reflect.Type) ([]byte, error) {
+	if !c.header {
+		return nil, nil
+	}
+
+	parser, err := c.parser(indirect(typ))
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err = parser.EncodeHeader(buf); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Decode decodes the first record of `data` into `item` when pointing to a struct, or every record
+// when pointing to a slice of structs, or of pointers to them.
+func (c CSVCodec) Decode(data []byte, item any) error {
+	target := reflect.ValueOf(item)
+	if target.Kind() != reflect.Pointer || target.IsNil() {
+		return errors.Wrapf(ErrTypeAssertion, "want non-nil pointer, have %T", item)
+	}
+
+	target = target.Elem()
+
+	typ := target.Type()
+	if target.Kind() == reflect.Slice {
+		typ = typ.Elem()
+	}
+
+	parser, err := c.parser(indirect(typ))
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 4*1024), len(data)+1)
+	scanner.Split(csvparser.ScanRecords)
+
+	header := c.header
+
+	for scanner.Scan() {
+		record := scanner.Bytes()
+		if len(record) == 0 {
+			continue
+		}
+
+		if header {
+			if parser, err = parser.BindHeader(record); err != nil {
+				return err
+			}
+			header = false
+			continue
+		}
+
+		if target.Kind() != reflect.Slice {
+			return parser.Parse(record, &item)
+		}
+
+		elem := reflect.New(indirect(typ))
+		var x any = elem.Interface()
+
+		if err = parser.Parse(record, &x); err != nil {
+			return errors.Wrapf(err, "record %d", target.Len())
+		}
+
+		if typ.Kind() == reflect.Pointer {
+			target.Set(reflect.Append(target, elem))
+		} else {
+			target.Set(reflect.Append(target, elem.Elem()))
+		}
+	}
+
+	return scanner.Err()
+}
+
+func (c CSVCodec) parser(typ reflect.Type) (csvparser.Parser[any], error) {
+	if parser, ok := c.parsers.Load(typ); ok {
+		return parser.(csvparser.Parser[any]), nil
+	}
+
+	parser, err := csvparser.FromType(typ, c.sep, csvparser.StructOptions{
+		RFC4180: true,
+		Header:  c.header,
+	})
+	if err != nil {
+		return parser, err
+	}
+
+	c.parsers.Store(typ, parser)
+	return parser, nil
+}
+
+func indirect(typ reflect.Type) reflect.Type {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	return typ
+}
@@ -0,0 +1,180 @@
+package codec
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/sonirico/withttp/csvparser"
+)
+
+type order struct {
+	Pair   string  `csv:"pair"`
+	Amount float64 `csv:"amount"`
+	Note   *string `csv:"note,optional"`
+}
+
+func TestCSVCodec_Encode(t *testing.T) {
+	type (
+		args struct {
+			codec CSVCodec
+			x     any
+		}
+
+		want struct {
+			payload string
+			err     error
+		}
+
+		testCase struct {
+			name string
+			args args
+			want want
+		}
+	)
+
+	note := "late"
+	orders := []order{
+		{Pair: "BTC/USDT", Amount: 234.5, Note: &note},
+		{Pair: `ETH, "USDT"`, Amount: 2},
+	}
+
+	tests := []testCase{
+		{
+			name: "records",
+			args: args{codec: NativeCSVCodec, x: orders},
+			want: want{payload: "BTC/USDT,234.5,late\n\"ETH, \"\"USDT\"\"\",2,\n"},
+		},
+		{
+			name: "records with header",
+			args: args{codec: NativeCSVHeaderCodec, x: orders},
+			want: want{
+				payload: "pair,amount,note\nBTC/USDT,234.5,late\n\"ETH, \"\"USDT\"\"\",2,\n",
+			},
+		},
+		{
+			name: "single record",
+			args: args{codec: NativeCSVHeaderCodec, x: &orders[0]},
+			want: want{payload: "BTC/USDT,234.5,late\n"},
+		},
+		{
+			name: "not a struct",
+			args: args{codec: NativeCSVCodec, x: "BTC/USDT"},
+			want: want{err: ErrTypeAssertion},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			payload, err := test.args.codec.Encode(test.args.x)
+			if !errors.Is(err, test.want.err) {
+				t.Fatalf("unexpected error, want %v, have %v", test.want.err, err)
+			}
+
+			if string(payload) != test.want.payload {
+				t.Errorf("unexpected payload\nwant '%s'\nhave '%s'", test.want.payload, payload)
+			}
+		})
+	}
+}
+
+func TestCSVCodec_Decode(t *testing.T) {
+	type (
+		args struct {
+			codec   CSVCodec
+			payload string
+		}
+
+		want struct {
+			orders []order
+			err    error
+		}
+
+		testCase struct {
+			name string
+			args args
+			want want
+		}
+	)
+
+	note := "late"
+
+	tests := []testCase{
+		{
+			name: "records",
+			args: args{
+				codec:   NativeCSVCodec,
+				payload: "BTC/USDT,234.5,late\r\n\"ETH, \"\"USDT\"\"\",2,\r\n",
+			},
+			want: want{
+				orders: []order{
+					{Pair: "BTC/USDT", Amount: 234.5, Note: &note},
+					{Pair: `ETH, "USDT"`, Amount: 2},
+				},
+			},
+		},
+		{
+			name: "records with header",
+			args: args{
+				codec:   NativeCSVHeaderCodec,
+				payload: "amount,pair\n234.5,BTC/USDT\n2,\"ETH,\nUSDT\"\n",
+			},
+			want: want{
+				orders: []order{{Pair: "BTC/USDT", Amount: 234.5}, {Pair: "ETH,\nUSDT", Amount: 2}},
+			},
+		},
+		{
+			name: "header only",
+			args: args{codec: NativeCSVHeaderCodec, payload: "pair,amount\n"},
+			want: want{},
+		},
+		{
+			name: "missing column",
+			args: args{codec: NativeCSVHeaderCodec, payload: "pair\nBTC/USDT\n"},
+			want: want{err: csvparser.ErrMissingColumn},
+		},
+		{
+			name: "unterminated quote",
+			args: args{codec: NativeCSVCodec, payload: "BTC/USDT,234.5,\n\"ETH/USDT,2,\n"},
+			want: want{
+				orders: []order{{Pair: "BTC/USDT", Amount: 234.5}},
+				err:    csvparser.ErrUnterminatedQuote,
+			},
+		},
+		{
+			name: "bare quote",
+			args: args{codec: NativeCSVCodec, payload: "ETH \"USDT\",2,\n"},
+			want: want{err: csvparser.ErrBareQuote},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var orders []order
+
+			err := test.args.codec.Decode([]byte(test.args.payload), &orders)
+			if !errors.Is(err, test.want.err) {
+				t.Fatalf("unexpected error, want %v, have %v", test.want.err, err)
+			}
+
+			if !reflect.DeepEqual(orders, test.want.orders) {
+				t.Errorf("unexpected orders\nwant %+v\nhave %+v", test.want.orders, orders)
+			}
+		})
+	}
+}
+
+func TestCSVCodec_DecodeSingle(t *testing.T) {
+	var o order
+
+	// Records following the first one are left alone.
+	payload := []byte("pair,amount\nBTC/USDT,234.5\n2,ETH/USDT\n")
+
+	if err := NativeCSVHeaderCodec.Decode(payload, &o); err != nil {
+		t.Fatalf("unexpected error, want none, have %v", err)
+	}
+
+	if want := (order{Pair: "BTC/USDT", Amount: 234.5}); !reflect.DeepEqual(o, want) {
+		t.Errorf("unexpected order, want %+v, have %+v", want, o)
+	}
+}
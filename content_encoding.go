@@ -0,0 +1,285 @@
+package withttp
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+)
+
+const (
+	ContentEncodingGzip     = "gzip"
+	ContentEncodingDeflate  = "deflate"
+	ContentEncodingBrotli   = "br"
+	ContentEncodingZstd     = "zstd"
+	ContentEncodingIdentity = "identity"
+)
+
+// CompressionLevelDefault selects the default compression level of each algorithm. Otherwise, levels
+// range from 1 to 9 for gzip and deflate, from 0 to 11 for brotli and from 1 to 22 for zstd.
+const CompressionLevelDefault = -1
+
+var (
+	ErrUnknownContentEncoding = errors.New("unknown content encoding")
+)
+
+type (
+	// contentDecoder undoes the content encodings of a body. Decoders are set up on the first
+	// read, so that any error surfaces to whoever consumes the body.
+	contentDecoder struct {
+		body      io.ReadCloser
+		encodings []string
+
+		reader  io.Reader
+		closers []func() error
+		err     error
+	}
+
+	// compressedWriteStream compresses whatever is written to it into the underlying stream, which
+	// is left untouched for readers.
+	compressedWriteStream struct {
+		io.ReadWriteCloser
+
+		encoder io.WriteCloser
+	}
+
+	// compressedReadStream compresses whatever is read from the underlying stream.
+	compressedReadStream struct {
+		reader *io.PipeReader
+
+		inner io.ReadWriteCloser
+	}
+)
+
+// NewContentEncoder returns a writer which compresses into `w` with the given content encoding
+// algorithm and level. Closing it flushes pending data, but does not close `w`.
+func NewContentEncoder(w io.Writer, encoding string, level int) (io.WriteCloser, error) {
+	switch encoding {
+	case ContentEncodingGzip:
+		if level == CompressionLevelDefault {
+			level = gzip.DefaultCompression
+		}
+		return gzip.NewWriterLevel(w, level)
+	case ContentEncodingDeflate:
+		if level == CompressionLevelDefault {
+			level = zlib.DefaultCompression
+		}
+		return zlib.NewWriterLevel(w, level)
+	case ContentEncodingBrotli:
+		if level == CompressionLevelDefault {
+			level = brotli.DefaultCompression
+		}
+		return brotli.NewWriterLevel(w, level), nil
+	case ContentEncodingZstd:
+		if level == CompressionLevelDefault {
+			return zstd.NewWriter(w)
+		}
+		return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+	default:
+		return nil, errors.Wrapf(ErrUnknownContentEncoding, "got: '%s'", encoding)
+	}
+}
+
+// EncodeContentEncoding compresses `payload` with the given content encoding algorithm and level.
+func EncodeContentEncoding(payload []byte, encoding string, level int) ([]byte, error) {
+	buf := bytes.NewBuffer(make([]byte, 0, len(payload)/2))
+
+	w, err := NewContentEncoder(buf, encoding, level)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = w.Write(payload); err != nil {
+		return nil, err
+	}
+
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func checkContentEncoding(encoding string) error {
+	switch encoding {
+	case ContentEncodingGzip, ContentEncodingDeflate, ContentEncodingBrotli, ContentEncodingZstd:
+		return nil
+	default:
+		return errors.Wrapf(ErrUnknownContentEncoding, "got: '%s'", encoding)
+	}
+}
+
+func newCompressedWriteStream(
+	rw io.ReadWriteCloser,
+	encoding string,
+	level int,
+) (io.ReadWriteCloser, error) {
+	encoder, err := NewContentEncoder(rw, encoding, level)
+	if err != nil {
+		return nil, err
+	}
+
+	return compressedWriteStream{ReadWriteCloser: rw, encoder: encoder}, nil
+}
+
+func (s compressedWriteStream) Write(p []byte) (int, error) {
+	return s.encoder.Write(p)
+}
+
+func (s compressedWriteStream) Close() error {
+	err := s.encoder.Close()
+	if cerr := s.ReadWriteCloser.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func newCompressedReadStream(
+	rw io.ReadWriteCloser,
+	encoding string,
+	level int,
+) (io.ReadWriteCloser, error) {
+	pr, pw := io.Pipe()
+
+	encoder, err := NewContentEncoder(pw, encoding, level)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		_, err := io.Copy(encoder, rw)
+		if cerr := encoder.Close(); err == nil {
+			err = cerr
+		}
+		_ = pw.CloseWithError(err)
+	}()
+
+	return compressedReadStream{reader: pr, inner: rw}, nil
+}
+
+func (s compressedReadStream) Read(p []byte) (int, error) {
+	return s.reader.Read(p)
+}
+
+func (s compressedReadStream) Write(p []byte) (int, error) {
+	return s.inner.Write(p)
+}
+
+func (s compressedReadStream) Close() error {
+	_ = s.reader.Close()
+	return s.inner.Close()
+}
+
+// DecodeContentEncoding wraps `rc` so that reading from it yields the payload with the content
+// encodings listed in `contentEncoding`, as sent in the Content-Encoding header, undone. Payload is
+// decoded incrementally as it is read.
+func DecodeContentEncoding(rc io.ReadCloser, contentEncoding string) io.ReadCloser {
+	encodings := parseContentEncoding(contentEncoding)
+	if len(encodings) == 0 {
+		return rc
+	}
+
+	return &contentDecoder{body: rc, encodings: encodings}
+}
+
+func (d *contentDecoder) Read(p []byte) (int, error) {
+	if d.reader == nil && d.err == nil {
+		d.err = d.setup()
+	}
+
+	if d.err != nil {
+		return 0, d.err
+	}
+
+	return d.reader.Read(p)
+}
+
+func (d *contentDecoder) Close() error {
+	for i := len(d.closers) - 1; i >= 0; i-- {
+		_ = d.closers[i]()
+	}
+
+	return d.body.Close()
+}
+
+func (d *contentDecoder) setup() error {
+	var r io.Reader = d.body
+
+	// Encodings are listed in the order they were applied, hence undo them backwards.
+	for i := len(d.encodings) - 1; i >= 0; i-- {
+		switch d.encodings[i] {
+		case ContentEncodingGzip:
+			gr, err := gzip.NewReader(r)
+			if err != nil {
+				return errors.Wrap(err, "gzip")
+			}
+			d.closers = append(d.closers, gr.Close)
+			r = gr
+		case ContentEncodingDeflate:
+			fr, err := newDeflateReader(r)
+			if err != nil {
+				return errors.Wrap(err, "deflate")
+			}
+			d.closers = append(d.closers, fr.Close)
+			r = fr
+		case ContentEncodingBrotli:
+			r = brotli.NewReader(r)
+		case ContentEncodingZstd:
+			zr, err := zstd.NewReader(r)
+			if err != nil {
+				return errors.Wrap(err, "zstd")
+			}
+			rc := zr.IOReadCloser()
+			d.closers = append(d.closers, rc.Close)
+			r = rc
+		default:
+			return errors.Wrapf(ErrUnknownContentEncoding, "got: '%s'", d.encodings[i])
+		}
+	}
+
+	d.reader = r
+	return nil
+}
+
+// newDeflateReader reads zlib wrapped deflate streams, as mandated by RFC 9110, while tolerating
+// servers which send raw deflate data instead.
+func newDeflateReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+
+	header, err := br.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+
+	return flate.NewReader(br), nil
+}
+
+func parseContentEncoding(header string) []string {
+	var encodings []string
+
+	for _, enc := range strings.Split(header, ",") {
+		enc = strings.ToLower(strings.TrimSpace(enc))
+
+		switch enc {
+		case "", ContentEncodingIdentity:
+			continue
+		case "x-gzip":
+			enc = ContentEncodingGzip
+		}
+
+		encodings = append(encodings, enc)
+	}
+
+	return encodings
+}
@@ -0,0 +1,223 @@
+package withttp
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+)
+
+func compressTestPayload(t *testing.T, encoding string, payload []byte) []byte {
+	t.Helper()
+
+	buf := bytes.NewBuffer(nil)
+
+	var w io.WriteCloser
+	switch encoding {
+	case ContentEncodingGzip:
+		w = gzip.NewWriter(buf)
+	case ContentEncodingDeflate:
+		w = zlib.NewWriter(buf)
+	case "raw-deflate":
+		w, _ = flate.NewWriter(buf, flate.DefaultCompression)
+	case ContentEncodingBrotli:
+		w = brotli.NewWriter(buf)
+	case ContentEncodingZstd:
+		w, _ = zstd.NewWriter(buf)
+	default:
+		t.Fatalf("unknown encoding %s", encoding)
+	}
+
+	if _, err := w.Write(payload); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestDecodeContentEncoding(t *testing.T) {
+	type (
+		args struct {
+			header  string
+			payload func(t *testing.T, plain []byte) []byte
+		}
+
+		want struct {
+			err error
+		}
+
+		testCase struct {
+			name string
+			args args
+			want want
+		}
+	)
+
+	compressed := func(encodings ...string) func(t *testing.T, plain []byte) []byte {
+		return func(t *testing.T, plain []byte) []byte {
+			for _, enc := range encodings {
+				plain = compressTestPayload(t, enc, plain)
+			}
+			return plain
+		}
+	}
+
+	tests := []testCase{
+		{name: "gzip", args: args{header: "gzip", payload: compressed(ContentEncodingGzip)}},
+		{name: "x-gzip", args: args{header: "x-gzip", payload: compressed(ContentEncodingGzip)}},
+		{name: "deflate", args: args{header: "deflate", payload: compressed(ContentEncodingDeflate)}},
+		{name: "raw deflate", args: args{header: "deflate", payload: compressed("raw-deflate")}},
+		{name: "brotli", args: args{header: "br", payload: compressed(ContentEncodingBrotli)}},
+		{name: "zstd", args: args{header: "zstd", payload: compressed(ContentEncodingZstd)}},
+		{name: "identity", args: args{header: "identity", payload: compressed()}},
+		{
+			name: "several encodings are undone in reverse order",
+			args: args{
+				header:  "gzip, identity, ZSTD",
+				payload: compressed(ContentEncodingGzip, ContentEncodingZstd),
+			},
+		},
+		{
+			name: "unknown encoding",
+			args: args{header: "compress", payload: compressed()},
+			want: want{err: ErrUnknownContentEncoding},
+		},
+	}
+
+	plain := bytes.Repeat([]byte(`{"amount": 234, "pair": "BTC/USDT"}`+"\n"), 64)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rc := DecodeContentEncoding(
+				io.NopCloser(bytes.NewReader(test.args.payload(t, plain))),
+				test.args.header,
+			)
+			defer func() { _ = rc.Close() }()
+
+			actual, err := io.ReadAll(rc)
+
+			if test.want.err != nil {
+				if !errors.Is(err, test.want.err) {
+					t.Fatalf("unexpected error, want %v, have %v", test.want.err, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error, want none, have %v", err)
+			}
+
+			if !bytes.Equal(plain, actual) {
+				t.Errorf("unexpected payload\nwant %q\nhave %q", plain, actual)
+			}
+		})
+	}
+}
+
+func TestCall_ResponseDecompression(t *testing.T) {
+	type (
+		args struct {
+			client               Client
+			encoding             string
+			disableDecompression bool
+		}
+
+		testCase struct {
+			name string
+			args args
+		}
+	)
+
+	orders := []Order{
+		{Amount: 234, Pair: "BTC/USDT"},
+		{Amount: 123, Pair: "ETH/USDT"},
+	}
+	plain := []byte(`{"amount": 234, "pair": "BTC/USDT"}
+{"amount": 123, "pair": "ETH/USDT"}`)
+
+	tests := make([]testCase, 0)
+	for _, client := range []struct {
+		name string
+		cli  Client
+	}{
+		{name: "net/http", cli: NetHttpClient(&http.Client{})},
+		{name: "fasthttp", cli: Fasthttp()},
+	} {
+		for _, encoding := range []string{
+			ContentEncodingGzip,
+			ContentEncodingDeflate,
+			ContentEncodingBrotli,
+			ContentEncodingZstd,
+		} {
+			tests = append(tests, testCase{
+				name: client.name + " " + encoding,
+				args: args{client: client.cli, encoding: encoding},
+			})
+		}
+		tests = append(tests, testCase{
+			name: client.name + " decompression disabled",
+			args: args{client: client.cli, encoding: ContentEncodingGzip, disableDecompression: true},
+		})
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			body := compressTestPayload(t, test.args.encoding, plain)
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("content-encoding", test.args.encoding)
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write(body)
+			}))
+			defer srv.Close()
+
+			actual := make([]Order, 0)
+
+			call := NewCall[Order](test.args.client).
+				URL(srv.URL).
+				Header("accept-encoding", "gzip, deflate, br, zstd", true)
+
+			if test.args.disableDecompression {
+				call = call.DisableDecompression().ReadBody()
+			} else {
+				call = call.ParseJSONEachRow(func(o Order) bool {
+					actual = append(actual, o)
+					return true
+				})
+			}
+
+			err := call.
+				ExpectedStatusCodes(http.StatusOK).
+				Call(context.TODO())
+
+			if err != nil {
+				t.Fatalf("unexpected error, want none, have %v", err)
+			}
+
+			if test.args.disableDecompression {
+				if !bytes.Equal(body, call.BodyRaw) {
+					t.Errorf("unexpected raw body, want compressed payload, have %q", call.BodyRaw)
+				}
+				return
+			}
+
+			if !reflect.DeepEqual(orders, actual) {
+				t.Errorf("unexpected orders\nwant %v\nhave %v", orders, actual)
+			}
+		})
+	}
+}
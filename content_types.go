@@ -1,6 +1,10 @@
 package withttp
 
 import (
+	"mime"
+	"reflect"
+	"strings"
+
 	"github.com/pkg/errors"
 	"github.com/sonirico/withttp/codec"
 )
@@ -8,14 +12,36 @@ import (
 var (
 	ContentTypeJSON        string = "application/json"
 	ContentTypeJSONEachRow string = "application/jsoneachrow"
+	ContentTypeCSV         string = "text/csv"
+	// ContentTypeCSVHeader is CSV whose first record is a header, as per RFC 4180.
+	ContentTypeCSVHeader string = "text/csv; header=present"
 )
 
 var (
 	ErrUnknownContentType = errors.New("unknown content type")
 )
 
+type (
+	// headerEncoder is implemented by codecs whose streams start with a header, such as CSV ones.
+	headerEncoder interface {
+		EncodeHeader(typ reflect.Type) ([]byte, error)
+	}
+)
+
+// ContentTypeCodec returns the codec for the media type of `c`, whose parameters are ignored, but for
+// the header one of CSV, which tells whether CSV payloads start with a header record.
 func ContentTypeCodec(c string) (codec.Codec, error) {
-	switch c {
+	mediaType, params, err := mime.ParseMediaType(c)
+	if err != nil {
+		mediaType = c
+	}
+
+	switch mediaType {
+	case ContentTypeCSV:
+		if strings.EqualFold(params["header"], "present") {
+			return codec.NativeCSVHeaderCodec, nil
+		}
+		return codec.NativeCSVCodec, nil
 	case ContentTypeJSON:
 		return codec.NativeJSONCodec, nil
 	case ContentTypeJSONEachRow:
@@ -1,13 +1,22 @@
 package csvparser
 
+import (
+	"bytes"
+	"io"
+	"math/big"
+	"time"
+)
+
 type (
 	Col[T any] interface {
 		Parse(data []byte, item *T) (int, error)
-		//Compile(x T, writer io.Writer) error
+		Compile(x T, writer io.Writer) error
 	}
 
 	opts struct {
-		sep byte
+		sep     byte
+		rfc4180 bool
+		tsv     bool
 	}
 
 	ColFactory[T any] func(opts) Col[T]
@@ -25,10 +34,26 @@ type (
 	}
 
 	BoolColumn[T any] struct {
-		inner  StringType
-		setter func(x T, v bool)
+		inner  BoolType
+		setter func(x *T, v bool)
 		getter func(x T) bool
 	}
+
+	// column backs columns of any type V parsed by a Type[V].
+	column[T, V any] struct {
+		inner  Type[V]
+		setter func(x *T, v V)
+		getter func(x T) V
+	}
+
+	// nullableColumn parses fields through the inner column, unless they are empty.
+	nullableColumn[T any] struct {
+		Col[T]
+
+		raw     StringType
+		isNull  func(x T) bool
+		setNull func(x *T)
+	}
 )
 
 func (s StringColumn[T]) Parse(data []byte, item *T) (int, error) {
@@ -40,6 +65,13 @@ func (s StringColumn[T]) Parse(data []byte, item *T) (int, error) {
 	return n, nil
 }
 
+func (s StringColumn[T]) Compile(x T, w io.Writer) error {
+	if s.getter == nil {
+		return ErrMissingGetter
+	}
+	return s.inner.Compile([]byte(s.getter(x)), w)
+}
+
 func (c IntColumn[T]) Parse(data []byte, item *T) (int, error) {
 	val, n, err := c.inner.Parse(data)
 	if err != nil {
@@ -49,6 +81,65 @@ func (c IntColumn[T]) Parse(data []byte, item *T) (int, error) {
 	return n, nil
 }
 
+func (c IntColumn[T]) Compile(x T, w io.Writer) error {
+	if c.getter == nil {
+		return ErrMissingGetter
+	}
+	return c.inner.Compile(c.getter(x), w)
+}
+
+func (c BoolColumn[T]) Parse(data []byte, item *T) (int, error) {
+	val, n, err := c.inner.Parse(data)
+	if err != nil {
+		return n, err
+	}
+	c.setter(item, val)
+	return n, nil
+}
+
+func (c BoolColumn[T]) Compile(x T, w io.Writer) error {
+	if c.getter == nil {
+		return ErrMissingGetter
+	}
+	return c.inner.Compile(c.getter(x), w)
+}
+
+func (c column[T, V]) Parse(data []byte, item *T) (int, error) {
+	val, n, err := c.inner.Parse(data)
+	if err != nil {
+		return n, err
+	}
+	c.setter(item, val)
+	return n, nil
+}
+
+func (c column[T, V]) Compile(x T, w io.Writer) error {
+	if c.getter == nil {
+		return ErrMissingGetter
+	}
+	return c.inner.Compile(c.getter(x), w)
+}
+
+func (c nullableColumn[T]) Parse(data []byte, item *T) (int, error) {
+	_, n, err := c.raw.Parse(data)
+	if err != nil || len(bytes.TrimSpace(data[:n])) > 0 {
+		return c.Col.Parse(data, item)
+	}
+
+	if c.setNull != nil {
+		c.setNull(item)
+	}
+	return n, nil
+}
+
+// Compile writes an empty field when `x` is null.
+func (c nullableColumn[T]) Compile(x T, w io.Writer) error {
+	if c.isNull != nil && c.isNull(x) {
+		return c.raw.Compile(nil, w)
+	}
+	return c.Col.Compile(x, w)
+}
+
 func StringCol[T any](
 	quote byte,
 	getter func(T) string,
@@ -56,7 +147,7 @@ func StringCol[T any](
 ) ColFactory[T] {
 	return func(opts opts) Col[T] {
 		return StringColumn[T]{
-			inner:  StrType(quote, opts.sep),
+			inner:  newStrType(quote, opts),
 			getter: getter, setter: setter,
 		}
 	}
@@ -69,8 +160,169 @@ func IntCol[T any](
 ) ColFactory[T] {
 	return func(opts opts) Col[T] {
 		return IntColumn[T]{
-			inner:  IntType(quote, opts.sep),
+			inner:  newIntType(quote, opts),
+			getter: getter, setter: setter,
+		}
+	}
+}
+
+func FloatCol[T any](
+	quote byte,
+	getter func(T) float64,
+	setter func(*T, float64),
+) ColFactory[T] {
+	return func(opts opts) Col[T] {
+		return column[T, float64]{
+			inner:  FloatType{inner: newStrType(quote, opts)},
+			getter: getter, setter: setter,
+		}
+	}
+}
+
+func Int64Col[T any](
+	quote byte,
+	getter func(T) int64,
+	setter func(*T, int64),
+) ColFactory[T] {
+	return sizedInt64Col[T](quote, 64, getter, setter)
+}
+
+// sizedInt64Col is like Int64Col, for integers fitting in `bits`.
+func sizedInt64Col[T any](
+	quote byte,
+	bits int,
+	getter func(T) int64,
+	setter func(*T, int64),
+) ColFactory[T] {
+	return func(opts opts) Col[T] {
+		return column[T, int64]{
+			inner:  Int64Type{inner: newStrType(quote, opts), bits: bits},
+			getter: getter, setter: setter,
+		}
+	}
+}
+
+func Uint64Col[T any](
+	quote byte,
+	getter func(T) uint64,
+	setter func(*T, uint64),
+) ColFactory[T] {
+	return sizedUint64Col[T](quote, 64, getter, setter)
+}
+
+// sizedUint64Col is like Uint64Col, for unsigned integers fitting in `bits`.
+func sizedUint64Col[T any](
+	quote byte,
+	bits int,
+	getter func(T) uint64,
+	setter func(*T, uint64),
+) ColFactory[T] {
+	return func(opts opts) Col[T] {
+		return column[T, uint64]{
+			inner:  Uint64Type{inner: newStrType(quote, opts), bits: bits},
+			getter: getter, setter: setter,
+		}
+	}
+}
+
+// BoolCol parses booleans as strconv.ParseBool does or, when `truthy` values are given, tells
+// whether the field matches any of them, see BoolType.
+func BoolCol[T any](
+	quote byte,
+	getter func(T) bool,
+	setter func(*T, bool),
+	truthy ...string,
+) ColFactory[T] {
+	return func(opts opts) Col[T] {
+		return BoolColumn[T]{
+			inner:  BoolType{inner: newStrType(quote, opts), truthy: truthy},
+			getter: getter, setter: setter,
+		}
+	}
+}
+
+// TimeCol parses times formatted as per `layout`, see time.Parse.
+func TimeCol[T any](
+	quote byte,
+	layout string,
+	getter func(T) time.Time,
+	setter func(*T, time.Time),
+) ColFactory[T] {
+	return func(opts opts) Col[T] {
+		return column[T, time.Time]{
+			inner:  TimeType{inner: newStrType(quote, opts), layout: layout},
 			getter: getter, setter: setter,
 		}
 	}
 }
+
+// EpochCol parses times given as the integer amount of `unit`, such as time.Second or
+// time.Millisecond, elapsed since the Unix epoch. Units default to time.Second if not positive.
+func EpochCol[T any](
+	quote byte,
+	unit time.Duration,
+	getter func(T) time.Time,
+	setter func(*T, time.Time),
+) ColFactory[T] {
+	if unit <= 0 {
+		unit = time.Second
+	}
+
+	return func(opts opts) Col[T] {
+		return column[T, time.Time]{
+			inner:  TimeType{inner: newStrType(quote, opts), unit: unit},
+			getter: getter, setter: setter,
+		}
+	}
+}
+
+// DurationCol parses durations such as "1h30m", see time.ParseDuration.
+func DurationCol[T any](
+	quote byte,
+	getter func(T) time.Duration,
+	setter func(*T, time.Duration),
+) ColFactory[T] {
+	return func(opts opts) Col[T] {
+		return column[T, time.Duration]{
+			inner:  DurationType{inner: newStrType(quote, opts)},
+			getter: getter, setter: setter,
+		}
+	}
+}
+
+// DecimalCol parses arbitrary precision decimals, such as "0.1" or "1e-8", exactly.
+func DecimalCol[T any](
+	quote byte,
+	getter func(T) *big.Rat,
+	setter func(*T, *big.Rat),
+) ColFactory[T] {
+	return func(opts opts) Col[T] {
+		return column[T, *big.Rat]{
+			inner:  DecimalType{inner: newStrType(quote, opts)},
+			getter: getter, setter: setter,
+		}
+	}
+}
+
+// Nullable makes `col` tolerate empty or blank fields, for which `setNull` is called instead, if
+// any. Quoted fields, even empty ones such as "", are left to `col` and its quote. Likewise, items
+// for which `isNull` holds are compiled as empty fields. It is meant for pointer fields, whose
+// `col` getter and setter dereference and allocate the value:
+//
+//	Nullable[T](
+//		FloatCol[T](QuoteNone,
+//			func(x T) float64 { return *x.Price },
+//			func(x *T, v float64) { x.Price = &v }),
+//		func(x T) bool { return x.Price == nil },
+//		nil,
+//	)
+func Nullable[T any](col ColFactory[T], isNull func(T) bool, setNull func(*T)) ColFactory[T] {
+	return func(opts opts) Col[T] {
+		return nullableColumn[T]{
+			Col:     col(opts),
+			raw:     newStrType(QuoteNone, opts),
+			isNull:  isNull,
+			setNull: setNull,
+		}
+	}
+}
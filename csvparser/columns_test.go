@@ -0,0 +1,238 @@
+package csvparser
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestColumns(t *testing.T) {
+	type (
+		trade struct {
+			Price    float64
+			Volume   int64
+			ID       uint64
+			Buyer    bool
+			At       time.Time
+			Settled  time.Time
+			Window   time.Duration
+			Fee      *big.Rat
+			Discount *float64
+		}
+
+		args struct {
+			payload string
+		}
+
+		want struct {
+			expected trade
+			err      error
+		}
+
+		testCase struct {
+			name string
+			args args
+			want want
+		}
+	)
+
+	discount := 0.5
+	at := time.Date(2022, 8, 1, 10, 30, 0, 0, time.UTC)
+
+	tests := []testCase{
+		{
+			name: "all columns",
+			args: args{
+				payload: `23.5,-3,18446744073709551615,Y,2022-08-01T10:30:00Z,1659349800000,1m30s,0.1,0.5`,
+			},
+			want: want{
+				expected: trade{
+					Price:    23.5,
+					Volume:   -3,
+					ID:       18446744073709551615,
+					Buyer:    true,
+					At:       at,
+					Settled:  at,
+					Window:   90 * time.Second,
+					Fee:      big.NewRat(1, 10),
+					Discount: &discount,
+				},
+			},
+		},
+		{
+			name: "empty nullable column",
+			args: args{
+				payload: `23.5,-3,1,no,2022-08-01T10:30:00Z,1659349800000,1m30s,1e-2,`,
+			},
+			want: want{
+				expected: trade{
+					Price:   23.5,
+					Volume:  -3,
+					ID:      1,
+					At:      at,
+					Settled: at,
+					Window:  90 * time.Second,
+					Fee:     big.NewRat(1, 100),
+				},
+			},
+		},
+		{
+			name: "blank nullable column",
+			args: args{
+				payload: `23.5,-3,1,no,2022-08-01T10:30:00Z,1659349800000,1m30s,1e-2,  `,
+			},
+			want: want{
+				expected: trade{
+					Price:   23.5,
+					Volume:  -3,
+					ID:      1,
+					At:      at,
+					Settled: at,
+					Window:  90 * time.Second,
+					Fee:     big.NewRat(1, 100),
+				},
+			},
+		},
+		{
+			name: "quoted nullable column",
+			args: args{
+				payload: `23.5,-3,1,no,2022-08-01T10:30:00Z,1659349800000,1m30s,1e-2,''`,
+			},
+			want: want{
+				expected: trade{
+					Price:   23.5,
+					Volume:  -3,
+					ID:      1,
+					At:      at,
+					Settled: at,
+					Window:  90 * time.Second,
+					Fee:     big.NewRat(1, 100),
+				},
+				err: strconv.ErrSyntax,
+			},
+		},
+		{
+			name: "invalid decimal",
+			args: args{
+				payload: `23.5,-3,1,no,2022-08-01T10:30:00Z,1659349800000,1m30s,1/2/3,`,
+			},
+			want: want{
+				expected: trade{
+					Price:   23.5,
+					Volume:  -3,
+					ID:      1,
+					At:      at,
+					Settled: at,
+					Window:  90 * time.Second,
+				},
+				err: strconv.ErrSyntax,
+			},
+		},
+	}
+
+	parser := New[trade](
+		SeparatorComma,
+		FloatCol[trade](QuoteNone, nil, func(x *trade, v float64) { x.Price = v }),
+		Int64Col[trade](QuoteNone, nil, func(x *trade, v int64) { x.Volume = v }),
+		Uint64Col[trade](QuoteNone, nil, func(x *trade, v uint64) { x.ID = v }),
+		BoolCol[trade](QuoteNone, nil, func(x *trade, v bool) { x.Buyer = v }, "y", "yes"),
+		TimeCol[trade](QuoteNone, time.RFC3339, nil, func(x *trade, v time.Time) { x.At = v }),
+		EpochCol[trade](QuoteNone, time.Millisecond, nil, func(x *trade, v time.Time) { x.Settled = v }),
+		DurationCol[trade](QuoteNone, nil, func(x *trade, v time.Duration) { x.Window = v }),
+		DecimalCol[trade](QuoteNone, nil, func(x *trade, v *big.Rat) { x.Fee = v }),
+		Nullable[trade](
+			FloatCol[trade](QuoteNone, nil, func(x *trade, v float64) { x.Discount = &v }),
+			nil,
+			func(x *trade) { x.Discount = nil },
+		),
+	)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			trade := trade{}
+			err := parser.Parse([]byte(test.args.payload), &trade)
+			if !errors.Is(err, test.want.err) {
+				t.Fatalf("unexpected error, want %v, have %v", test.want.err, err)
+			}
+
+			if !reflect.DeepEqual(test.want.expected, trade) {
+				t.Errorf("unexpected trade\nwant %+v\nhave %+v", test.want.expected, trade)
+			}
+		})
+	}
+}
+
+func TestEpochCol_Unit(t *testing.T) {
+	type (
+		args struct {
+			unit    time.Duration
+			payload string
+		}
+
+		want struct {
+			at time.Time
+		}
+
+		testCase struct {
+			name string
+			args args
+			want want
+		}
+	)
+
+	tests := []testCase{
+		{
+			name: "milliseconds",
+			args: args{unit: time.Millisecond, payload: "1700000000123"},
+			want: want{at: time.UnixMilli(1700000000123).UTC()},
+		},
+		{
+			name: "zero defaults to seconds",
+			args: args{unit: 0, payload: "1700000000"},
+			want: want{at: time.Unix(1700000000, 0).UTC()},
+		},
+		{
+			name: "negative defaults to seconds",
+			args: args{unit: -time.Millisecond, payload: "1700000000"},
+			want: want{at: time.Unix(1700000000, 0).UTC()},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parser := New[time.Time](
+				SeparatorComma,
+				EpochCol[time.Time](
+					QuoteNone,
+					test.args.unit,
+					func(x time.Time) time.Time { return x },
+					func(x *time.Time, v time.Time) { *x = v },
+				),
+			)
+
+			var at time.Time
+			if err := parser.Parse([]byte(test.args.payload), &at); err != nil {
+				t.Fatalf("unexpected error, want none, have %v", err)
+			}
+
+			if !at.Equal(test.want.at) {
+				t.Errorf("unexpected time, want %v, have %v", test.want.at, at)
+			}
+
+			buf := new(bytes.Buffer)
+			if err := parser.Encode(at, buf); err != nil {
+				t.Fatalf("unexpected error, want none, have %v", err)
+			}
+
+			if have := strings.TrimSpace(buf.String()); have != test.args.payload {
+				t.Errorf("unexpected payload, want '%s', have '%s'", test.args.payload, have)
+			}
+		})
+	}
+}
@@ -1,8 +1,42 @@
 package csvparser
 
-import "github.com/pkg/errors"
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
 
 var (
-	ErrColumnMismatch = errors.New("column mismatch")
-	ErrQuoteExpected  = errors.New("quote was expected")
+	ErrColumnMismatch    = errors.New("column mismatch")
+	ErrQuoteExpected     = errors.New("quote was expected")
+	ErrBareQuote         = errors.New("bare quote in non-quoted field")
+	ErrUnterminatedQuote = errors.New("unterminated quoted field")
+	ErrExtraneousQuote   = errors.New("extraneous or missing quote in quoted field")
+	ErrMissingColumn     = errors.New("missing column")
+	ErrUnsupportedType   = errors.New("unsupported type")
+	ErrInvalidTag        = errors.New("invalid tag")
+	ErrMissingGetter     = errors.New("column has no getter")
+	ErrNotHeaderAware    = errors.New("parser is not header aware")
+)
+
+type (
+	// ParseError locates an error found while parsing a record in RFC 4180 mode.
+	ParseError struct {
+		// Line is the 1-based line where the error was found. Relative to the start of the record,
+		// unless the record was read by a stream which knows better.
+		Line int
+		// Column is the 1-based byte position of the error within Line.
+		Column int
+		// Field is the 1-based index of the field being parsed.
+		Field int
+		Err   error
+	}
 )
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d, column %d, field %d: %s", e.Line, e.Column, e.Field, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
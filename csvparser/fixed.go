@@ -0,0 +1,68 @@
+package csvparser
+
+import "bytes"
+
+type (
+	// FixedField is a column found at a fixed position of the record.
+	FixedField[T any] struct {
+		offset int
+		width  int
+		col    ColFactory[T]
+	}
+
+	fixedField[T any] struct {
+		offset int
+		width  int
+		col    Col[T]
+	}
+
+	// FixedWidthParser parses records whose fields lie at fixed positions, padded up to their width,
+	// as found in mainframe reports.
+	FixedWidthParser[T any] struct {
+		fields []fixedField[T]
+		pad    byte
+	}
+)
+
+// Fixed places `col` at the `width` bytes of the record starting at the 0-based `offset`. Columns
+// are handed the field with its padding trimmed, hence should be declared with QuoteNone.
+func Fixed[T any](offset, width int, col ColFactory[T]) FixedField[T] {
+	return FixedField[T]{offset: offset, width: width, col: col}
+}
+
+// NewFixedWidth creates a fixed width parser whose fields are padded with `pad`, usually spaces,
+// on either side. Records may be shorter than declared, in which case missing fields are empty.
+func NewFixedWidth[T any](pad byte, fields ...FixedField[T]) FixedWidthParser[T] {
+	// Fields span up to the end of the record, hence no separator is to be found in them.
+	opt := opts{sep: '\n'}
+
+	parsed := make([]fixedField[T], len(fields))
+	for i, f := range fields {
+		parsed[i] = fixedField[T]{offset: f.offset, width: f.width, col: f.col(opt)}
+	}
+
+	return FixedWidthParser[T]{fields: parsed, pad: pad}
+}
+
+// Parse parses the fields of `data` into `item`. Errors are reported as *ParseError.
+func (p FixedWidthParser[T]) Parse(data []byte, item *T) error {
+	data = bytes.TrimSuffix(data, []byte{'\r'})
+
+	for i, f := range p.fields {
+		start, end := f.offset, f.offset+f.width
+		if start > len(data) {
+			start = len(data)
+		}
+		if end > len(data) {
+			end = len(data)
+		}
+
+		field := bytes.Trim(data[start:end], string([]byte{p.pad}))
+
+		if _, err := f.col.Parse(field, item); err != nil {
+			return newParseError(data, start, i, err)
+		}
+	}
+
+	return nil
+}
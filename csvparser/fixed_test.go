@@ -0,0 +1,88 @@
+package csvparser
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestFixedWidthParser_Parse(t *testing.T) {
+	type (
+		row struct {
+			Code   string
+			Amount int
+			Rate   *float64
+		}
+
+		args struct {
+			pad     byte
+			payload string
+		}
+
+		want struct {
+			expected row
+			err      error
+			column   int
+		}
+
+		testCase struct {
+			name string
+			args args
+			want want
+		}
+	)
+
+	rate := 1.25
+
+	tests := []testCase{
+		{
+			name: "space padded",
+			args: args{pad: ' ', payload: "AB    42 1.25"},
+			want: want{expected: row{Code: "AB", Amount: 42, Rate: &rate}},
+		},
+		{
+			name: "custom padding",
+			args: args{pad: '*', payload: "**AB**42*1.25"},
+			want: want{expected: row{Code: "AB", Amount: 42, Rate: &rate}},
+		},
+		{
+			name: "short record leaves trailing fields empty",
+			args: args{pad: ' ', payload: "AB    42"},
+			want: want{expected: row{Code: "AB", Amount: 42}},
+		},
+		{
+			name: "invalid field",
+			args: args{pad: ' ', payload: "AB    4x 1.25"},
+			want: want{expected: row{Code: "AB"}, err: strconv.ErrSyntax, column: 5},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parser := NewFixedWidth[row](
+				test.args.pad,
+				Fixed[row](0, 4, StringCol[row](QuoteNone, nil, func(x *row, v string) { x.Code = v })),
+				Fixed[row](4, 4, IntCol[row](QuoteNone, nil, func(x *row, v int) { x.Amount = v })),
+				Fixed[row](8, 5, Nullable[row](
+					FloatCol[row](QuoteNone, nil, func(x *row, v float64) { x.Rate = &v }), nil, nil)),
+			)
+
+			r := row{}
+			err := parser.Parse([]byte(test.args.payload), &r)
+			if !errors.Is(err, test.want.err) {
+				t.Fatalf("unexpected error, want %v, have %v", test.want.err, err)
+			}
+
+			var parseErr *ParseError
+			if errors.As(err, &parseErr) && parseErr.Column != test.want.column {
+				t.Errorf("unexpected error column, want %d, have %d", test.want.column, parseErr.Column)
+			}
+
+			if !reflect.DeepEqual(test.want.expected, r) {
+				t.Errorf("unexpected row\nwant %+v\nhave %+v", test.want.expected, r)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package csvparser
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	utf8BOM = []byte{0xef, 0xbb, 0xbf}
+)
+
+type (
+	// namedColumn is a column bound by header name rather than by position.
+	namedColumn[T any] struct {
+		Col[T]
+
+		names    []string
+		required bool
+	}
+
+	named interface {
+		headerNames() ([]string, bool)
+	}
+)
+
+func (c namedColumn[T]) headerNames() ([]string, bool) {
+	return c.names, c.required
+}
+
+// Named binds `col` to the header column called `name`, or any of its `aliases`, compared case
+// insensitively. Header is required to hold it. Parsers made only of named columns are header
+// aware, see Parser.BindHeader.
+func Named[T any](name string, col ColFactory[T], aliases ...string) ColFactory[T] {
+	return namedCol(name, col, aliases, true)
+}
+
+// Optional is like Named, but header is not required to hold the column. Items are left untouched
+// when it is missing.
+func Optional[T any](name string, col ColFactory[T], aliases ...string) ColFactory[T] {
+	return namedCol(name, col, aliases, false)
+}
+
+func namedCol[T any](name string, col ColFactory[T], aliases []string, required bool) ColFactory[T] {
+	names := append([]string{name}, aliases...)
+
+	return func(opts opts) Col[T] {
+		return namedColumn[T]{Col: col(opts), names: names, required: required}
+	}
+}
+
+// HeaderAware tells whether the parser binds its columns by header name, which happens when all of
+// them are Named or Optional. Such parsers parse fields in the order columns were declared until
+// bound to a header.
+func (p Parser[T]) HeaderAware() bool {
+	for _, col := range p.columns {
+		if _, ok := col.(named); !ok {
+			return false
+		}
+	}
+
+	return len(p.columns) > 0
+}
+
+// BindHeader returns a copy of the parser whose columns parse the fields at the position their names
+// hold in `header`. Unknown header columns are ignored, whereas missing required ones make it fail
+// with ErrMissingColumn.
+func (p Parser[T]) BindHeader(header []byte) (Parser[T], error) {
+	names, err := p.headerFields(header)
+	if err != nil {
+		return p, err
+	}
+
+	positions := make(map[string]int, len(names))
+	for i, name := range names {
+		name = strings.ToLower(name)
+		if _, ok := positions[name]; !ok {
+			positions[name] = i
+		}
+	}
+
+	fields := make([]Col[T], 0, len(names))
+	var missing []string
+
+	for _, col := range p.columns {
+		aliases, required := col.(named).headerNames()
+
+		pos, ok := -1, false
+		for _, alias := range aliases {
+			if pos, ok = positions[strings.ToLower(alias)]; ok {
+				break
+			}
+		}
+
+		if !ok {
+			if required {
+				missing = append(missing, aliases[0])
+			}
+			continue
+		}
+
+		for len(fields) <= pos {
+			fields = append(fields, nil)
+		}
+		fields[pos] = col
+	}
+
+	if len(missing) > 0 {
+		return p, errors.Wrapf(ErrMissingColumn, "%s", strings.Join(missing, ", "))
+	}
+
+	bound := p
+	bound.fields = fields
+	return bound, nil
+}
+
+func (p Parser[T]) headerFields(header []byte) ([]string, error) {
+	header = bytes.TrimPrefix(header, utf8BOM)
+	if !p.rfc4180 && !p.tsv {
+		header = bytes.TrimSpace(header)
+	}
+
+	var names []string
+
+	for cursor := 0; cursor <= len(header); cursor++ {
+		name, read, err := p.skip.Parse(header[cursor:])
+		if err != nil {
+			return nil, newParseError(header, cursor+read, len(names), err)
+		}
+
+		if !p.rfc4180 && !p.tsv {
+			name = strings.Trim(strings.TrimSpace(name), `"'`)
+		}
+
+		names = append(names, strings.TrimSpace(name))
+		cursor += read
+	}
+
+	return names, nil
+}
@@ -0,0 +1,142 @@
+package csvparser
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestParser_BindHeader(t *testing.T) {
+	type (
+		duck struct {
+			Name     string
+			Siblings int
+			Motto    string
+		}
+
+		args struct {
+			rfc4180 bool
+			header  string
+			record  string
+		}
+
+		want struct {
+			expected duck
+			err      error
+		}
+
+		testCase struct {
+			name string
+			args args
+			want want
+		}
+	)
+
+	tests := []testCase{
+		{
+			name: "same order as declared",
+			args: args{
+				header: "name,siblings,motto",
+				record: "knight,2,quack",
+			},
+			want: want{
+				expected: duck{Name: "knight", Siblings: 2, Motto: "quack"},
+			},
+		},
+		{
+			name: "reordered columns",
+			args: args{
+				header: "motto,name,siblings",
+				record: "quack,knight,2",
+			},
+			want: want{
+				expected: duck{Name: "knight", Siblings: 2, Motto: "quack"},
+			},
+		},
+		{
+			name: "names are case insensitive and aliases are honored",
+			args: args{
+				header: "Brothers,NAME",
+				record: "2,knight",
+			},
+			want: want{
+				expected: duck{Name: "knight", Siblings: 2},
+			},
+		},
+		{
+			name: "unknown columns are ignored",
+			args: args{
+				header: "id,name,color,siblings,size",
+				record: "1,knight,yellow,2,small",
+			},
+			want: want{
+				expected: duck{Name: "knight", Siblings: 2},
+			},
+		},
+		{
+			name: "quoted header in rfc 4180 mode",
+			args: args{
+				rfc4180: true,
+				header:  "\xef\xbb\xbf\"id\",\"name, full\",\"siblings\",motto",
+				record:  "1,\"knight, \"\"sir\"\"\",2,\"quack, quack\"",
+			},
+			want: want{
+				expected: duck{Name: `knight, "sir"`, Siblings: 2, Motto: "quack, quack"},
+			},
+		},
+		{
+			name: "missing required column",
+			args: args{
+				header: "motto,size",
+				record: "quack,small",
+			},
+			want: want{
+				err: ErrMissingColumn,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cols := []ColFactory[duck]{
+				Named[duck]("name",
+					StringCol[duck](QuoteNone, nil, func(d *duck, v string) { d.Name = v }),
+					"name, full"),
+				Named[duck]("siblings",
+					IntCol[duck](QuoteNone, nil, func(d *duck, v int) { d.Siblings = v }),
+					"brothers"),
+				Optional[duck]("motto",
+					StringCol[duck](QuoteNone, nil, func(d *duck, v string) { d.Motto = v })),
+			}
+
+			parser := New[duck](SeparatorComma, cols...)
+			if test.args.rfc4180 {
+				parser = NewRFC4180[duck](SeparatorComma, cols...)
+			}
+
+			if !parser.HeaderAware() {
+				t.Fatal("unexpected parser, want header aware")
+			}
+
+			bound, err := parser.BindHeader([]byte(test.args.header))
+			if !errors.Is(err, test.want.err) {
+				t.Fatalf("unexpected error, want %v, have %v", test.want.err, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			rubberDuck := duck{}
+			if err := bound.Parse([]byte(test.args.record), &rubberDuck); err != nil {
+				t.Fatalf("unexpected error, want none, have %v", err)
+			}
+
+			if !reflect.DeepEqual(test.want.expected, rubberDuck) {
+				t.Errorf("unexpected duck\nwant %v\nhave %v",
+					test.want.expected, rubberDuck)
+			}
+		})
+	}
+}
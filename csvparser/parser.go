@@ -2,11 +2,12 @@ package csvparser
 
 import (
 	"bytes"
+	"io"
+
+	"github.com/pkg/errors"
 )
 
 var (
-	quote = []byte{byte('"')}
-
 	QuoteDouble byte = '"'
 	QuoteSimple byte = '\''
 	QuoteNone   byte = 0
@@ -20,16 +21,27 @@ type (
 	Parser[T any] struct {
 		separator byte
 		columns   []Col[T]
+		rfc4180   bool
+		tsv       bool
+
+		// fields holds the column to parse every field with, by position. Fields without a column
+		// are skipped. Same as columns unless bound to a header.
+		fields []Col[T]
+		skip   StringType
 	}
 )
 
 func (p Parser[T]) Parse(data []byte, item *T) (err error) {
+	if p.rfc4180 || p.tsv {
+		return p.parseStrict(data, item)
+	}
+
 	data = bytes.TrimSpace(data) // cleanup phase
 	sepLen := 1                  // len(p.separator)
 
-	for i, col := range p.columns {
+	for i, col := range p.fields {
 		var read int
-		read, err = col.Parse(data, item)
+		read, err = p.parseField(col, data, item)
 		if err != nil {
 			return
 		}
@@ -53,12 +65,147 @@ func (p Parser[T]) Parse(data []byte, item *T) (err error) {
 	return nil
 }
 
+// parseStrict parses a whole record, which may span several lines in RFC 4180 mode. Fields in excess
+// are ignored, whereas missing ones are reported as ErrColumnMismatch.
+func (p Parser[T]) parseStrict(data []byte, item *T) error {
+	cursor := 0
+
+	for i, col := range p.fields {
+		if cursor > len(data) {
+			return newParseError(data, len(data), i, errors.Wrapf(ErrColumnMismatch,
+				"want %d fields, have %d", len(p.fields), i))
+		}
+
+		read, err := p.parseField(col, data[cursor:], item)
+		if err != nil {
+			if isSyntaxError(err) {
+				return newParseError(data, cursor+read, i, err)
+			}
+			return newParseError(data, cursor, i, err)
+		}
+
+		cursor += read
+
+		// Step over the separator. Otherwise, the end of the record has been reached, hence move the
+		// cursor beyond it to tell there are no fields left.
+		cursor++
+	}
+
+	return nil
+}
+
+func (p Parser[T]) parseField(col Col[T], data []byte, item *T) (int, error) {
+	if col == nil {
+		_, read, err := p.skip.Parse(data)
+		return read, err
+	}
+
+	return col.Parse(data, item)
+}
+
+// Encode writes `x` as a record, out of the getters of its columns, followed by a line break. Fields
+// are quoted as per their columns, see StringType.Compile.
+func (p Parser[T]) Encode(x T, w io.Writer) error {
+	buf := bytes.NewBuffer(nil)
+
+	for i, col := range p.columns {
+		if i > 0 {
+			buf.WriteByte(p.separator)
+		}
+
+		if err := col.Compile(x, buf); err != nil {
+			return errors.Wrapf(err, "field %d", i+1)
+		}
+	}
+
+	buf.WriteByte('\n')
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// EncodeHeader writes the header record of header aware parsers, made of the names their columns
+// were declared with. Otherwise, it fails with ErrNotHeaderAware.
+func (p Parser[T]) EncodeHeader(w io.Writer) error {
+	if !p.HeaderAware() {
+		return ErrNotHeaderAware
+	}
+
+	buf := bytes.NewBuffer(nil)
+
+	for i, col := range p.columns {
+		if i > 0 {
+			buf.WriteByte(p.separator)
+		}
+
+		names, _ := col.(named).headerNames()
+		if err := p.skip.Compile([]byte(names[0]), buf); err != nil {
+			return err
+		}
+	}
+
+	buf.WriteByte('\n')
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// RFC4180 tells whether the parser complies with RFC 4180.
+func (p Parser[T]) RFC4180() bool {
+	return p.rfc4180
+}
+
 func New[T any](sep byte, cols ...ColFactory[T]) Parser[T] {
+	return newParser(opts{sep: sep}, cols...)
+}
+
+// NewRFC4180 creates a parser which complies with RFC 4180. Any field may be enclosed in double
+// quotes, no matter the quote its column was declared with, in which case it can hold separators,
+// line breaks and quotes, the latter escaped by doubling them. Errors are reported as *ParseError.
+// Records spanning several lines have to be split with ScanRecords, as CSV streams do.
+func NewRFC4180[T any](sep byte, cols ...ColFactory[T]) Parser[T] {
+	return newParser(opts{sep: sep, rfc4180: true}, cols...)
+}
+
+// NewTSV creates a parser of tab separated values whose fields escape tabs, line breaks and
+// backslashes with a backslash, as in `\t`, `\n`, `\r` and `\\`. Quotes bear no special meaning,
+// no matter the quote columns were declared with. Errors are reported as *ParseError.
+func NewTSV[T any](cols ...ColFactory[T]) Parser[T] {
+	return newParser(opts{sep: SeparatorTab, tsv: true}, cols...)
+}
+
+func newParser[T any](opt opts, cols ...ColFactory[T]) Parser[T] {
 	columns := make([]Col[T], len(cols))
-	opt := opts{sep: sep}
 
 	for i, c := range cols {
 		columns[i] = c(opt)
 	}
-	return Parser[T]{separator: sep, columns: columns}
+	return Parser[T]{
+		separator: opt.sep,
+		columns:   columns,
+		rfc4180:   opt.rfc4180,
+		tsv:       opt.tsv,
+		fields:    columns,
+		skip:      newStrType(QuoteNone, opt),
+	}
+}
+
+func isSyntaxError(err error) bool {
+	return errors.Is(err, ErrBareQuote) ||
+		errors.Is(err, ErrUnterminatedQuote) ||
+		errors.Is(err, ErrExtraneousQuote)
+}
+
+func newParseError(data []byte, offset, field int, err error) *ParseError {
+	if offset > len(data) {
+		offset = len(data)
+	}
+
+	line := 1 + bytes.Count(data[:offset], []byte{'\n'})
+	column := offset + 1
+	if i := bytes.LastIndexByte(data[:offset], '\n'); i >= 0 {
+		column = offset - i
+	}
+
+	return &ParseError{Line: line, Column: column, Field: field + 1, Err: err}
 }
@@ -1,7 +1,11 @@
 package csvparser
 
 import (
+	"bufio"
+	"bytes"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/pkg/errors"
@@ -132,3 +136,385 @@ func TestParser_RawColumn(t *testing.T) {
 		})
 	}
 }
+
+func TestParser_RFC4180(t *testing.T) {
+	type (
+		args struct {
+			payload []byte
+			sep     byte
+		}
+
+		duck struct {
+			Name     string
+			Siblings int
+			Motto    string
+		}
+
+		want struct {
+			expected duck
+			err      error
+			pos      *ParseError
+		}
+
+		testCase struct {
+			name string
+			args args
+			want want
+		}
+	)
+
+	tests := []testCase{
+		{
+			name: "unquoted fields",
+			args: args{
+				payload: []byte("a duck knight in shinny armor,2,quack"),
+				sep:     SeparatorComma,
+			},
+			want: want{
+				expected: duck{Name: "a duck knight in shinny armor", Siblings: 2, Motto: "quack"},
+			},
+		},
+		{
+			name: "quoting is optional per field",
+			args: args{
+				payload: []byte(`"a duck knight",2,"quack"`),
+				sep:     SeparatorComma,
+			},
+			want: want{
+				expected: duck{Name: "a duck knight", Siblings: 2, Motto: "quack"},
+			},
+		},
+		{
+			name: "quoted fields hold separators, doubled quotes and line breaks",
+			args: args{
+				payload: []byte("\"knight, \"\"the\"\" duck\",\"2\",\"quack\r\nquack\""),
+				sep:     SeparatorComma,
+			},
+			want: want{
+				expected: duck{Name: `knight, "the" duck`, Siblings: 2, Motto: "quack\r\nquack"},
+			},
+		},
+		{
+			name: "white space is significant",
+			args: args{
+				payload: []byte(" knight ;2; "),
+				sep:     SeparatorSemicolon,
+			},
+			want: want{
+				expected: duck{Name: " knight ", Siblings: 2, Motto: " "},
+			},
+		},
+		{
+			name: "empty fields",
+			args: args{
+				payload: []byte(`"",2,`),
+				sep:     SeparatorComma,
+			},
+			want: want{
+				expected: duck{Name: "", Siblings: 2, Motto: ""},
+			},
+		},
+		{
+			name: "fields in excess are ignored",
+			args: args{
+				payload: []byte(`knight,2,quack,extra`),
+				sep:     SeparatorComma,
+			},
+			want: want{
+				expected: duck{Name: "knight", Siblings: 2, Motto: "quack"},
+			},
+		},
+		{
+			name: "bare quote",
+			args: args{
+				payload: []byte(`kni"ght,2,quack`),
+				sep:     SeparatorComma,
+			},
+			want: want{
+				err: ErrBareQuote,
+				pos: &ParseError{Line: 1, Column: 4, Field: 1},
+			},
+		},
+		{
+			name: "extraneous quote",
+			args: args{
+				payload: []byte("knight,2,\"qu\nack\"s"),
+				sep:     SeparatorComma,
+			},
+			want: want{
+				expected: duck{Name: "knight", Siblings: 2},
+				err:      ErrExtraneousQuote,
+				pos:      &ParseError{Line: 2, Column: 5, Field: 3},
+			},
+		},
+		{
+			name: "unterminated quote",
+			args: args{
+				payload: []byte(`knight,2,"quack`),
+				sep:     SeparatorComma,
+			},
+			want: want{
+				expected: duck{Name: "knight", Siblings: 2},
+				err:      ErrUnterminatedQuote,
+				pos:      &ParseError{Line: 1, Column: 16, Field: 3},
+			},
+		},
+		{
+			name: "missing fields",
+			args: args{
+				payload: []byte(`knight,2`),
+				sep:     SeparatorComma,
+			},
+			want: want{
+				expected: duck{Name: "knight", Siblings: 2},
+				err:      ErrColumnMismatch,
+				pos:      &ParseError{Line: 1, Column: 9, Field: 3},
+			},
+		},
+		{
+			name: "invalid value",
+			args: args{
+				payload: []byte(`knight,"two",quack`),
+				sep:     SeparatorComma,
+			},
+			want: want{
+				expected: duck{Name: "knight"},
+				err:      strconv.ErrSyntax,
+				pos:      &ParseError{Line: 1, Column: 8, Field: 2},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parser := NewRFC4180[duck](
+				test.args.sep,
+				StringCol[duck](QuoteDouble, nil, func(d *duck, v string) { d.Name = v }),
+				IntCol[duck](QuoteNone, nil, func(d *duck, v int) { d.Siblings = v }),
+				StringCol[duck](QuoteNone, nil, func(d *duck, v string) { d.Motto = v }),
+			)
+
+			rubberDuck := duck{}
+			err := parser.Parse(test.args.payload, &rubberDuck)
+
+			if test.want.err == nil && err != nil {
+				t.Fatalf("unexpected error, want none, have %v", err)
+			}
+
+			if test.want.err != nil && !errors.Is(err, test.want.err) {
+				t.Fatalf("unexpected error, want %v, have %v", test.want.err, err)
+			}
+
+			if test.want.pos != nil {
+				var parseErr *ParseError
+				if !errors.As(err, &parseErr) {
+					t.Fatalf("unexpected error type, want *ParseError, have %T", err)
+				}
+
+				if parseErr.Line != test.want.pos.Line ||
+					parseErr.Column != test.want.pos.Column ||
+					parseErr.Field != test.want.pos.Field {
+					t.Errorf("unexpected error position\nwant %d:%d field %d\nhave %d:%d field %d",
+						test.want.pos.Line, test.want.pos.Column, test.want.pos.Field,
+						parseErr.Line, parseErr.Column, parseErr.Field)
+				}
+			}
+
+			if !reflect.DeepEqual(test.want.expected, rubberDuck) {
+				t.Errorf("unexpected duck\nwant %v\nhave %v",
+					test.want.expected, rubberDuck)
+			}
+		})
+	}
+}
+
+func TestScanRecords(t *testing.T) {
+	payload := "name,motto\r\n\"knight\",\"quack\nquack\"\n\"sir \"\"duck\"\"\",\"a\r\nb\"\r\nlast,\"unterminated\n"
+
+	scanner := bufio.NewScanner(strings.NewReader(payload))
+	scanner.Split(ScanRecords)
+
+	records := make([]string, 0)
+	for scanner.Scan() {
+		records = append(records, scanner.Text())
+	}
+
+	expected := []string{
+		"name,motto",
+		"\"knight\",\"quack\nquack\"",
+		"\"sir \"\"duck\"\"\",\"a\r\nb\"",
+		"last,\"unterminated\n",
+	}
+
+	if !reflect.DeepEqual(expected, records) {
+		t.Errorf("unexpected records\nwant %q\nhave %q", expected, records)
+	}
+}
+
+func TestParser_Encode(t *testing.T) {
+	type (
+		duck struct {
+			Name     string
+			Siblings int
+			Motto    *string
+		}
+
+		args struct {
+			rfc4180 bool
+			duck    duck
+		}
+
+		want struct {
+			expected string
+		}
+
+		testCase struct {
+			name string
+			args args
+			want want
+		}
+	)
+
+	motto := "quack, quack"
+
+	tests := []testCase{
+		{
+			name: "legacy quotes as declared",
+			args: args{duck: duck{Name: "knight", Siblings: 2, Motto: &motto}},
+			want: want{expected: "\"knight\",2,quack, quack\n"},
+		},
+		{
+			name: "rfc 4180 quotes when needed",
+			args: args{rfc4180: true, duck: duck{Name: `sir "knight"`, Siblings: 2, Motto: &motto}},
+			want: want{expected: "\"sir \"\"knight\"\"\",2,\"quack, quack\"\n"},
+		},
+		{
+			name: "null column",
+			args: args{rfc4180: true, duck: duck{Name: "knight", Siblings: 2}},
+			want: want{expected: "\"knight\",2,\n"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cols := []ColFactory[duck]{
+				StringCol[duck](QuoteDouble,
+					func(d duck) string { return d.Name },
+					func(d *duck, v string) { d.Name = v }),
+				IntCol[duck](QuoteNone,
+					func(d duck) int { return d.Siblings },
+					func(d *duck, v int) { d.Siblings = v }),
+				Nullable[duck](
+					StringCol[duck](QuoteNone,
+						func(d duck) string { return *d.Motto },
+						func(d *duck, v string) { d.Motto = &v }),
+					func(d duck) bool { return d.Motto == nil },
+					nil,
+				),
+			}
+
+			parser := New[duck](SeparatorComma, cols...)
+			if test.args.rfc4180 {
+				parser = NewRFC4180[duck](SeparatorComma, cols...)
+			}
+
+			buf := bytes.NewBuffer(nil)
+			if err := parser.Encode(test.args.duck, buf); err != nil {
+				t.Fatalf("unexpected error, want none, have %v", err)
+			}
+
+			if test.want.expected != buf.String() {
+				t.Errorf("unexpected record\nwant '%s'\nhave '%s'", test.want.expected, buf.String())
+			}
+
+			if !test.args.rfc4180 {
+				return
+			}
+
+			rubberDuck := duck{}
+			if err := parser.Parse(bytes.TrimSuffix(buf.Bytes(), []byte("\n")), &rubberDuck); err != nil {
+				t.Fatalf("unexpected error, want none, have %v", err)
+			}
+
+			if !reflect.DeepEqual(test.args.duck, rubberDuck) {
+				t.Errorf("unexpected duck\nwant %v\nhave %v", test.args.duck, rubberDuck)
+			}
+		})
+	}
+}
+
+func TestParser_EncodeHeader(t *testing.T) {
+	type duck struct {
+		Name     string
+		Siblings int
+	}
+
+	parser := New[duck](
+		SeparatorComma,
+		Named[duck]("name", StringCol[duck](QuoteNone, nil, nil)),
+		Optional[duck]("siblings", IntCol[duck](QuoteNone, nil, nil), "brothers"),
+	)
+
+	buf := bytes.NewBuffer(nil)
+	if err := parser.EncodeHeader(buf); err != nil {
+		t.Fatalf("unexpected error, want none, have %v", err)
+	}
+
+	if want := "name,siblings\n"; want != buf.String() {
+		t.Errorf("unexpected header\nwant '%s'\nhave '%s'", want, buf.String())
+	}
+
+	if err := parser.Encode(duck{}, buf); !errors.Is(err, ErrMissingGetter) {
+		t.Errorf("unexpected error, want %v, have %v", ErrMissingGetter, err)
+	}
+
+	positional := New[duck](SeparatorComma, StringCol[duck](QuoteNone, nil, nil))
+	if err := positional.EncodeHeader(buf); !errors.Is(err, ErrNotHeaderAware) {
+		t.Errorf("unexpected error, want %v, have %v", ErrNotHeaderAware, err)
+	}
+}
+
+func TestParser_TSV(t *testing.T) {
+	type duck struct {
+		Name     string
+		Siblings int
+		Motto    string
+	}
+
+	parser := NewTSV[duck](
+		StringCol[duck](QuoteDouble,
+			func(d duck) string { return d.Name },
+			func(d *duck, v string) { d.Name = v }),
+		IntCol[duck](QuoteNone,
+			func(d duck) int { return d.Siblings },
+			func(d *duck, v int) { d.Siblings = v }),
+		StringCol[duck](QuoteNone,
+			func(d duck) string { return d.Motto },
+			func(d *duck, v string) { d.Motto = v }),
+	)
+
+	rubberDuck := duck{Name: `"sir"	knight`, Siblings: 2, Motto: "quack\\\nquack"}
+
+	buf := bytes.NewBuffer(nil)
+	if err := parser.Encode(rubberDuck, buf); err != nil {
+		t.Fatalf("unexpected error, want none, have %v", err)
+	}
+
+	if want := "\"sir\"\\tknight\t2\tquack\\\\\\nquack\n"; want != buf.String() {
+		t.Errorf("unexpected record\nwant '%s'\nhave '%s'", want, buf.String())
+	}
+
+	decoded := duck{}
+	if err := parser.Parse(bytes.TrimSuffix(buf.Bytes(), []byte("\n")), &decoded); err != nil {
+		t.Fatalf("unexpected error, want none, have %v", err)
+	}
+
+	if !reflect.DeepEqual(rubberDuck, decoded) {
+		t.Errorf("unexpected duck\nwant %q\nhave %q", rubberDuck, decoded)
+	}
+
+	err := parser.Parse([]byte("knight\t2"), &decoded)
+	if !errors.Is(err, ErrColumnMismatch) {
+		t.Errorf("unexpected error, want %v, have %v", ErrColumnMismatch, err)
+	}
+}
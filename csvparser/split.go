@@ -0,0 +1,38 @@
+package csvparser
+
+// ScanRecords is a bufio.SplitFunc which splits CSV records, as opposed to lines, so that line breaks
+// enclosed in quotes are kept within the record. Record terminators, either "\n" or "\r\n", are
+// dropped.
+func ScanRecords(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	quoted := false
+
+	for i, b := range data {
+		switch b {
+		case QuoteDouble:
+			// Doubled quotes toggle twice, thus leaving the state untouched.
+			quoted = !quoted
+		case '\n':
+			if !quoted {
+				return i + 1, dropCR(data[:i]), nil
+			}
+		}
+	}
+
+	if atEOF {
+		return len(data), dropCR(data), nil
+	}
+
+	// Request more data.
+	return 0, nil, nil
+}
+
+func dropCR(data []byte) []byte {
+	if len(data) > 0 && data[len(data)-1] == '\r' {
+		return data[:len(data)-1]
+	}
+	return data
+}
@@ -0,0 +1,297 @@
+package csvparser
+
+import (
+	"math/big"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const tagName = "csv"
+
+type (
+	// StructOptions tunes the parsers built by FromStruct.
+	StructOptions struct {
+		// RFC4180 makes the parser comply with RFC 4180, see NewRFC4180.
+		RFC4180 bool
+		// Header binds columns by their names instead of by position, see Named.
+		Header bool
+	}
+
+	// fieldTag holds the settings of a field as per its `csv` tag, which look like
+	// `csv:"name,quote=double,format=...,alias=other|another,optional"`.
+	fieldTag struct {
+		name     string
+		quote    byte
+		format   string
+		aliases  []string
+		optional bool
+	}
+)
+
+// FromStruct builds a parser for T, which must be a struct, out of its exported fields. Columns
+// follow the order fields are declared in and are configured by their `csv` tag, which holds the
+// column name, defaulting to the field name, followed by any of these comma separated settings:
+//
+//   - quote=double|simple|none: quote the column is enclosed in. Defaults to none.
+//   - format=...: type specific format of the column. Times take either a layout, defaulting to
+//     time.RFC3339, or unix, unixmilli, unixmicro or unixnano for elapsed time since the Unix
+//     epoch. Booleans take their truthy values, such as format=yes|y, see BoolCol.
+//   - alias=name|other: alternative column names, used when binding by header.
+//   - optional: column may be missing from the header, used when binding by header.
+//
+// Supported field types are strings, integers, floats, booleans, time.Time, time.Duration and
+// *big.Rat decimals, as well as pointers to them, which are left nil for empty fields. Integers out
+// of the range of their field fail to parse. Fields tagged with `csv:"-"` are skipped. Reflection
+// makes these parsers slower than those built by hand with New, which remain the way to go for hot
+// paths.
+func FromStruct[T any](sep byte, opts StructOptions) (Parser[T], error) {
+	return fromStruct[T](reflect.TypeOf((*T)(nil)).Elem(), sep, opts)
+}
+
+// FromType is like FromStruct, for struct types only known at runtime. Items are of `any` type,
+// holding either values of `typ` or pointers to them, the latter being required to parse into.
+func FromType(typ reflect.Type, sep byte, opts StructOptions) (Parser[any], error) {
+	return fromStruct[any](typ, sep, opts)
+}
+
+func fromStruct[T any](typ reflect.Type, sep byte, opts StructOptions) (Parser[T], error) {
+	if typ.Kind() != reflect.Struct {
+		return Parser[T]{}, errors.Wrapf(ErrUnsupportedType, "want struct, have %s", typ)
+	}
+
+	cols := make([]ColFactory[T], 0, typ.NumField())
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag, ok, err := parseFieldTag(field)
+		if err != nil {
+			return Parser[T]{}, err
+		}
+
+		if !ok {
+			continue
+		}
+
+		col, err := structCol[T](field, i, tag)
+		if err != nil {
+			return Parser[T]{}, err
+		}
+
+		if opts.Header {
+			if tag.optional {
+				col = Optional[T](tag.name, col, tag.aliases...)
+			} else {
+				col = Named[T](tag.name, col, tag.aliases...)
+			}
+		}
+
+		cols = append(cols, col)
+	}
+
+	if opts.RFC4180 {
+		return NewRFC4180[T](sep, cols...), nil
+	}
+
+	return New[T](sep, cols...), nil
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	decimalType  = reflect.TypeOf((*big.Rat)(nil))
+)
+
+// structCol builds the column of a field. Pointer fields are nullable, see Nullable, and so are
+// decimals.
+func structCol[T any](field reflect.StructField, index int, tag fieldTag) (ColFactory[T], error) {
+	typ, ptr := field.Type, false
+	if typ.Kind() == reflect.Pointer && typ != decimalType {
+		typ, ptr = typ.Elem(), true
+	}
+
+	if tag.format != "" && typ != timeType && typ.Kind() != reflect.Bool {
+		return nil, errors.Wrapf(ErrInvalidTag, "field %s: format is not supported by %s",
+			field.Name, field.Type)
+	}
+
+	var col ColFactory[T]
+
+	switch {
+	case typ == timeType:
+		unit, epoch := epochUnits[tag.format]
+		switch {
+		case epoch:
+			col = reflectCol(index, ptr, func(get func(T) time.Time, set func(*T, time.Time)) ColFactory[T] {
+				return EpochCol[T](tag.quote, unit, get, set)
+			})
+		default:
+			layout := tag.format
+			if layout == "" {
+				layout = time.RFC3339
+			}
+			col = reflectCol(index, ptr, func(get func(T) time.Time, set func(*T, time.Time)) ColFactory[T] {
+				return TimeCol[T](tag.quote, layout, get, set)
+			})
+		}
+	case typ == durationType:
+		col = reflectCol(index, ptr, func(get func(T) time.Duration, set func(*T, time.Duration)) ColFactory[T] {
+			return DurationCol[T](tag.quote, get, set)
+		})
+	case typ == decimalType:
+		col = Nullable[T](reflectCol(index, false, func(get func(T) *big.Rat, set func(*T, *big.Rat)) ColFactory[T] {
+			return DecimalCol[T](tag.quote, get, set)
+		}), nil, nil)
+	default:
+		switch typ.Kind() {
+		case reflect.String:
+			col = reflectCol(index, ptr, func(get func(T) string, set func(*T, string)) ColFactory[T] {
+				return StringCol[T](tag.quote, get, set)
+			})
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			col = reflectCol(index, ptr, func(get func(T) int64, set func(*T, int64)) ColFactory[T] {
+				return sizedInt64Col[T](tag.quote, typ.Bits(), get, set)
+			})
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			col = reflectCol(index, ptr, func(get func(T) uint64, set func(*T, uint64)) ColFactory[T] {
+				return sizedUint64Col[T](tag.quote, typ.Bits(), get, set)
+			})
+		case reflect.Float32, reflect.Float64:
+			col = reflectCol(index, ptr, func(get func(T) float64, set func(*T, float64)) ColFactory[T] {
+				return FloatCol[T](tag.quote, get, set)
+			})
+		case reflect.Bool:
+			var truthy []string
+			if tag.format != "" {
+				truthy = strings.Split(tag.format, "|")
+			}
+			col = reflectCol(index, ptr, func(get func(T) bool, set func(*T, bool)) ColFactory[T] {
+				return BoolCol[T](tag.quote, get, set, truthy...)
+			})
+		default:
+			return nil, errors.Wrapf(ErrUnsupportedType, "field %s of type %s", field.Name, field.Type)
+		}
+	}
+
+	return col, nil
+}
+
+// epochUnits maps the formats of times given as elapsed time since the Unix epoch to their unit.
+var epochUnits = map[string]time.Duration{
+	"unix":      time.Second,
+	"unixmilli": time.Millisecond,
+	"unixmicro": time.Microsecond,
+	"unixnano":  time.Nanosecond,
+}
+
+// reflectCol builds a column through `col` whose getter and setter access the field at `index`,
+// converting between V and the field type. Pointer fields are left nil when empty.
+func reflectCol[T, V any](
+	index int,
+	ptr bool,
+	col func(getter func(T) V, setter func(*T, V)) ColFactory[T],
+) ColFactory[T] {
+	typ := reflect.TypeOf((*V)(nil)).Elem()
+
+	getter := func(x T) V {
+		field := structValue(reflect.ValueOf(x)).Field(index)
+		if ptr {
+			if field.IsNil() {
+				var zero V
+				return zero
+			}
+			field = field.Elem()
+		}
+		return field.Convert(typ).Interface().(V)
+	}
+
+	setter := func(x *T, v V) {
+		field := structValue(reflect.ValueOf(x).Elem()).Field(index)
+		val := reflect.ValueOf(&v).Elem()
+
+		if !ptr {
+			field.Set(val.Convert(field.Type()))
+			return
+		}
+
+		elem := reflect.New(field.Type().Elem())
+		elem.Elem().Set(val.Convert(field.Type().Elem()))
+		field.Set(elem)
+	}
+
+	if !ptr {
+		return col(getter, setter)
+	}
+
+	isNull := func(x T) bool {
+		return structValue(reflect.ValueOf(x)).Field(index).IsNil()
+	}
+
+	setNull := func(x *T) {
+		field := structValue(reflect.ValueOf(x).Elem()).Field(index)
+		field.Set(reflect.Zero(field.Type()))
+	}
+
+	return Nullable[T](col(getter, setter), isNull, setNull)
+}
+
+// structValue returns the struct held by `v`, which may be wrapped by interfaces and pointers, as
+// happens to the items of FromType parsers.
+func structValue(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	return v
+}
+
+// parseFieldTag returns the settings of `field`, unless it has to be skipped.
+func parseFieldTag(field reflect.StructField) (tag fieldTag, ok bool, err error) {
+	raw, _ := field.Tag.Lookup(tagName)
+	if raw == "-" {
+		return tag, false, nil
+	}
+
+	parts := strings.Split(raw, ",")
+
+	tag.name = strings.TrimSpace(parts[0])
+	if tag.name == "" {
+		tag.name = field.Name
+	}
+
+	for _, part := range parts[1:] {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+
+		switch key {
+		case "quote":
+			switch value {
+			case "double":
+				tag.quote = QuoteDouble
+			case "simple", "single":
+				tag.quote = QuoteSimple
+			case "none", "":
+				tag.quote = QuoteNone
+			default:
+				return tag, false, errors.Wrapf(ErrInvalidTag, "field %s: unknown quote '%s'",
+					field.Name, value)
+			}
+		case "format":
+			tag.format = value
+		case "alias":
+			tag.aliases = append(tag.aliases, strings.Split(value, "|")...)
+		case "optional":
+			tag.optional = true
+		case "":
+		default:
+			return tag, false, errors.Wrapf(ErrInvalidTag, "field %s: unknown setting '%s'",
+				field.Name, key)
+		}
+	}
+
+	return tag, true, nil
+}
@@ -0,0 +1,238 @@
+package csvparser
+
+import (
+	"math/big"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestFromStruct(t *testing.T) {
+	type (
+		duck struct {
+			Name     string `csv:"name,quote=none"`
+			Siblings int64  `csv:"siblings,alias=brothers|sisters"`
+			Motto    string `csv:",optional"`
+			Secret   string `csv:"-"`
+			internal string
+		}
+
+		args struct {
+			opts    StructOptions
+			header  string
+			payload string
+		}
+
+		want struct {
+			expected duck
+			err      error
+		}
+
+		testCase struct {
+			name string
+			args args
+			want want
+		}
+	)
+
+	tests := []testCase{
+		{
+			name: "positional",
+			args: args{
+				payload: `knight,2,quack`,
+			},
+			want: want{
+				expected: duck{Name: "knight", Siblings: 2, Motto: "quack"},
+			},
+		},
+		{
+			name: "positional rfc 4180",
+			args: args{
+				opts:    StructOptions{RFC4180: true},
+				payload: `"knight, ""sir""",2,quack`,
+			},
+			want: want{
+				expected: duck{Name: `knight, "sir"`, Siblings: 2, Motto: "quack"},
+			},
+		},
+		{
+			name: "by header with aliases and optional columns",
+			args: args{
+				opts:    StructOptions{RFC4180: true, Header: true},
+				header:  "sisters,name,color",
+				payload: `3,knight,yellow`,
+			},
+			want: want{
+				expected: duck{Name: "knight", Siblings: 3},
+			},
+		},
+		{
+			name: "by header with default names",
+			args: args{
+				opts:    StructOptions{Header: true},
+				header:  "motto,siblings,name",
+				payload: `quack,2,knight`,
+			},
+			want: want{
+				expected: duck{Name: "knight", Siblings: 2, Motto: "quack"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parser, err := FromStruct[duck](SeparatorComma, test.args.opts)
+			if err != nil {
+				t.Fatalf("unexpected error, want none, have %v", err)
+			}
+
+			if test.args.header != "" {
+				if parser, err = parser.BindHeader([]byte(test.args.header)); err != nil {
+					t.Fatalf("unexpected error, want none, have %v", err)
+				}
+			}
+
+			rubberDuck := duck{}
+			err = parser.Parse([]byte(test.args.payload), &rubberDuck)
+			if !errors.Is(err, test.want.err) {
+				t.Fatalf("unexpected error, want %v, have %v", test.want.err, err)
+			}
+
+			if !reflect.DeepEqual(test.want.expected, rubberDuck) {
+				t.Errorf("unexpected duck\nwant %v\nhave %v",
+					test.want.expected, rubberDuck)
+			}
+		})
+	}
+}
+
+func TestFromStruct_Errors(t *testing.T) {
+	type (
+		unsupported struct {
+			Ch chan int
+		}
+
+		badQuote struct {
+			Name string `csv:"name,quote=backtick"`
+		}
+
+		badSetting struct {
+			Name string `csv:"name,upper"`
+		}
+	)
+
+	if _, err := FromStruct[int](SeparatorComma, StructOptions{}); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("unexpected error, want %v, have %v", ErrUnsupportedType, err)
+	}
+
+	if _, err := FromStruct[unsupported](SeparatorComma, StructOptions{}); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("unexpected error, want %v, have %v", ErrUnsupportedType, err)
+	}
+
+	if _, err := FromStruct[badQuote](SeparatorComma, StructOptions{}); !errors.Is(err, ErrInvalidTag) {
+		t.Errorf("unexpected error, want %v, have %v", ErrInvalidTag, err)
+	}
+
+	if _, err := FromStruct[badSetting](SeparatorComma, StructOptions{}); !errors.Is(err, ErrInvalidTag) {
+		t.Errorf("unexpected error, want %v, have %v", ErrInvalidTag, err)
+	}
+}
+
+func TestFromStruct_Types(t *testing.T) {
+	type trade struct {
+		Price   float32        `csv:"price"`
+		Volume  int8           `csv:"volume"`
+		ID      uint           `csv:"id"`
+		Buyer   bool           `csv:"buyer,format=buy|b"`
+		At      time.Time      `csv:"at,format=2006-01-02"`
+		Settled *time.Time     `csv:"settled,format=unix"`
+		Window  *time.Duration `csv:"window"`
+		Fee     *big.Rat       `csv:"fee"`
+		Note    *string        `csv:"note"`
+	}
+
+	parser, err := FromStruct[trade](SeparatorComma, StructOptions{RFC4180: true})
+	if err != nil {
+		t.Fatalf("unexpected error, want none, have %v", err)
+	}
+
+	at := time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC)
+	settled := at.Add(10 * time.Hour)
+	window := time.Minute
+	note, empty := "late", ""
+
+	tests := []struct {
+		payload  string
+		expected trade
+	}{
+		{
+			payload: `1.5,-2,7,BUY,2022-08-01,1659348000,1m,0.25,late`,
+			expected: trade{
+				Price: 1.5, Volume: -2, ID: 7, Buyer: true, At: at, Settled: &settled,
+				Window: &window, Fee: big.NewRat(1, 4), Note: &note,
+			},
+		},
+		{
+			payload:  `1.5,-2,7,sell,2022-08-01,,,,`,
+			expected: trade{Price: 1.5, Volume: -2, ID: 7, At: at},
+		},
+		{
+			payload:  `1.5,-2,7,sell,2022-08-01,,,,""`,
+			expected: trade{Price: 1.5, Volume: -2, ID: 7, At: at, Note: &empty},
+		},
+	}
+
+	for _, test := range tests {
+		rubberTrade := trade{}
+		if err := parser.Parse([]byte(test.payload), &rubberTrade); err != nil {
+			t.Fatalf("unexpected error, want none, have %v", err)
+		}
+
+		if !reflect.DeepEqual(test.expected, rubberTrade) {
+			t.Errorf("unexpected trade\nwant %+v\nhave %+v", test.expected, rubberTrade)
+		}
+	}
+}
+
+func TestFromStruct_OutOfRange(t *testing.T) {
+	type trade struct {
+		Volume int8    `csv:"volume"`
+		ID     *uint16 `csv:"id"`
+	}
+
+	parser, err := FromStruct[trade](SeparatorComma, StructOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error, want none, have %v", err)
+	}
+
+	id := uint16(65535)
+
+	tests := []struct {
+		name     string
+		payload  string
+		expected trade
+		err      error
+	}{
+		{name: "in range", payload: `-128,65535`, expected: trade{Volume: -128, ID: &id}},
+		{name: "int overflow", payload: `300,1`, err: strconv.ErrRange},
+		{name: "int underflow", payload: `-129,1`, err: strconv.ErrRange},
+		{name: "uint overflow", payload: `1,65536`, err: strconv.ErrRange},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rubberTrade := trade{}
+			err := parser.Parse([]byte(test.payload), &rubberTrade)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("unexpected error, want %v, have %v", test.err, err)
+			}
+
+			if test.err == nil && !reflect.DeepEqual(test.expected, rubberTrade) {
+				t.Errorf("unexpected trade\nwant %+v\nhave %+v", test.expected, rubberTrade)
+			}
+		})
+	}
+}
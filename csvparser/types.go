@@ -1,33 +1,93 @@
 package csvparser
 
 import (
+	"bytes"
 	"io"
+	"math/big"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/sonirico/vago/slices"
 )
 
+var (
+	tsvEscaper = strings.NewReplacer("\\", `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+)
+
 type (
 	Type[T any] interface {
 		Parse(data []byte) (T, int, error)
-		//Compile(x T, writer io.Writer) error
+		Compile(x T, writer io.Writer) error
 	}
 
 	StringType struct {
-		sep   byte
-		quote byte
+		sep     byte
+		quote   byte
+		rfc4180 bool
+		tsv     bool
 	}
 
 	IntegerType struct {
 		inner StringType
 	}
+
+	FloatType struct {
+		inner StringType
+	}
+
+	// Int64Type parses integers fitting in `bits`, defaulting to 64, failing on those out of range.
+	Int64Type struct {
+		inner StringType
+		bits  int
+	}
+
+	// Uint64Type parses unsigned integers fitting in `bits`, defaulting to 64, failing on those out
+	// of range.
+	Uint64Type struct {
+		inner StringType
+		bits  int
+	}
+
+	// BoolType parses booleans as strconv.ParseBool does, unless truthy values are given, in which
+	// case any of them, compared case insensitively, stands for true and anything else for false.
+	BoolType struct {
+		inner  StringType
+		truthy []string
+	}
+
+	// TimeType parses times formatted as per layout or, if missing, as integer amounts of unit
+	// elapsed since the Unix epoch.
+	TimeType struct {
+		inner  StringType
+		layout string
+		unit   time.Duration
+	}
+
+	DurationType struct {
+		inner StringType
+	}
+
+	// DecimalType parses arbitrary precision decimals, such as amounts of money, without the
+	// rounding errors of floats.
+	DecimalType struct {
+		inner StringType
+	}
 )
 
 // Parse parses `data`, which is ensured to be non-nil and its length greater than zero
 func (s StringType) Parse(data []byte) (string, int, error) {
+	if s.rfc4180 {
+		return s.parseRFC4180(data)
+	}
+
+	if s.tsv {
+		return s.parseTSV(data)
+	}
+
 	if s.quote != QuoteNone {
-		if data[0] != s.quote {
+		if len(data) == 0 || data[0] != s.quote {
 			return "", 0, errors.Wrapf(ErrQuoteExpected, "<%s>", string(s.quote))
 		}
 
@@ -58,28 +118,132 @@ func (s StringType) Parse(data []byte) (string, int, error) {
 	return string(payload), len(payload), nil
 }
 
-func (s StringType) Compile(data []byte, w io.Writer) error {
-	if s.quote != QuoteNone {
+// parseRFC4180 parses the next field of `data` as per RFC 4180. Fields may optionally be enclosed in
+// double quotes, in which case they can hold separators, line breaks and doubled quotes, which
+// are unescaped. Returned int is the amount of bytes consumed or, upon error, the offset where the
+// error was found.
+func (s StringType) parseRFC4180(data []byte) (string, int, error) {
+	if len(data) == 0 || data[0] != QuoteDouble {
+		end := bytes.IndexByte(data, s.sep)
+		if end < 0 {
+			end = len(data)
+		}
+
+		if i := bytes.IndexByte(data[:end], QuoteDouble); i >= 0 {
+			return "", i, ErrBareQuote
+		}
+
+		return string(data[:end]), end, nil
+	}
+
+	var (
+		payload []byte
+		i       = 1
+	)
+
+	for {
+		j := bytes.IndexByte(data[i:], QuoteDouble)
+		if j < 0 {
+			return "", len(data), ErrUnterminatedQuote
+		}
+
+		j += i
 
-		n, err := w.Write(quote)
-		if err != nil || n < 1 {
-			// todo: handle
+		if j+1 < len(data) && data[j+1] == QuoteDouble {
+			// Doubled quote, which stands for a literal one.
+			payload = append(payload, data[i:j+1]...)
+			i = j + 2
+			continue
 		}
+
+		payload = append(payload, data[i:j]...)
+
+		end := j + 1
+		if end < len(data) && data[end] != s.sep {
+			return "", end, ErrExtraneousQuote
+		}
+
+		return string(payload), end, nil
 	}
+}
 
-	n, err := w.Write(data)
+// parseTSV parses the next field of `data`, which ends at the next separator, unescaping backslash
+// escape sequences. Unknown sequences are kept as they are.
+func (s StringType) parseTSV(data []byte) (string, int, error) {
+	end := bytes.IndexByte(data, s.sep)
+	if end < 0 {
+		end = len(data)
+	}
 
-	if err != nil || n < 1 {
-		// todo: handle
+	field := data[:end]
+	if bytes.IndexByte(field, '\\') < 0 {
+		return string(field), end, nil
 	}
 
-	if s.quote != QuoteNone {
-		n, err := w.Write(quote)
-		if err != nil || n < 1 {
-			// todo: handle
+	payload := make([]byte, 0, len(field))
+
+	for i := 0; i < len(field); i++ {
+		if field[i] != '\\' || i+1 == len(field) {
+			payload = append(payload, field[i])
+			continue
+		}
+
+		i++
+
+		switch field[i] {
+		case 't':
+			payload = append(payload, '\t')
+		case 'n':
+			payload = append(payload, '\n')
+		case 'r':
+			payload = append(payload, '\r')
+		case '\\':
+			payload = append(payload, '\\')
+		default:
+			payload = append(payload, '\\', field[i])
+		}
+	}
+
+	return string(payload), end, nil
+}
+
+// Compile writes `data` as a field, enclosed in the quote of the type, if any. In RFC 4180 mode,
+// fields are only enclosed in double quotes when declared with a quote or when holding separators,
+// quotes or line breaks, quotes being escaped by doubling them. In TSV mode, fields are never quoted
+// but escaped, see NewTSV.
+func (s StringType) Compile(data []byte, w io.Writer) error {
+	if s.tsv {
+		_, err := io.WriteString(w, tsvEscaper.Replace(string(data)))
+		return err
+	}
+
+	quote := s.quote
+
+	if s.rfc4180 {
+		quote = QuoteNone
+		if s.quote != QuoteNone ||
+			bytes.IndexByte(data, s.sep) >= 0 ||
+			bytes.ContainsAny(data, "\"\r\n") {
+			quote = QuoteDouble
+			data = bytes.ReplaceAll(data, []byte{QuoteDouble}, []byte{QuoteDouble, QuoteDouble})
 		}
 	}
-	return nil
+
+	if quote == QuoteNone {
+		_, err := w.Write(data)
+		return err
+	}
+
+	if _, err := w.Write([]byte{quote}); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+
+	_, err := w.Write([]byte{quote})
+	return err
 }
 
 func (i IntegerType) Parse(data []byte) (int, int, error) {
@@ -93,6 +257,156 @@ func (i IntegerType) Parse(data []byte) (int, int, error) {
 	return int(res), n, err
 }
 
+func (i IntegerType) Compile(x int, w io.Writer) error {
+	return i.inner.Compile(strconv.AppendInt(nil, int64(x), 10), w)
+}
+
+func (f FloatType) Parse(data []byte) (float64, int, error) {
+	val, n, err := f.inner.Parse(data)
+	if err != nil {
+		return 0, n, err
+	}
+
+	res, err := strconv.ParseFloat(val, 64)
+	return res, n, err
+}
+
+func (f FloatType) Compile(x float64, w io.Writer) error {
+	return f.inner.Compile(strconv.AppendFloat(nil, x, 'f', -1, 64), w)
+}
+
+func (i Int64Type) Parse(data []byte) (int64, int, error) {
+	val, n, err := i.inner.Parse(data)
+	if err != nil {
+		return 0, n, err
+	}
+
+	res, err := strconv.ParseInt(val, 10, bitSize(i.bits))
+	return res, n, err
+}
+
+func (i Int64Type) Compile(x int64, w io.Writer) error {
+	return i.inner.Compile(strconv.AppendInt(nil, x, 10), w)
+}
+
+func (u Uint64Type) Parse(data []byte) (uint64, int, error) {
+	val, n, err := u.inner.Parse(data)
+	if err != nil {
+		return 0, n, err
+	}
+
+	res, err := strconv.ParseUint(val, 10, bitSize(u.bits))
+	return res, n, err
+}
+
+// bitSize returns `bits`, unless unset, in which case integers take 64 bits.
+func bitSize(bits int) int {
+	if bits == 0 {
+		return 64
+	}
+	return bits
+}
+
+func (u Uint64Type) Compile(x uint64, w io.Writer) error {
+	return u.inner.Compile(strconv.AppendUint(nil, x, 10), w)
+}
+
+func (b BoolType) Parse(data []byte) (bool, int, error) {
+	val, n, err := b.inner.Parse(data)
+	if err != nil {
+		return false, n, err
+	}
+
+	if len(b.truthy) == 0 {
+		res, err := strconv.ParseBool(val)
+		return res, n, err
+	}
+
+	for _, truthy := range b.truthy {
+		if strings.EqualFold(val, truthy) {
+			return true, n, nil
+		}
+	}
+
+	return false, n, nil
+}
+
+// Compile writes true as the first truthy value, if any, and false as "false".
+func (b BoolType) Compile(x bool, w io.Writer) error {
+	if x && len(b.truthy) > 0 {
+		return b.inner.Compile([]byte(b.truthy[0]), w)
+	}
+	return b.inner.Compile(strconv.AppendBool(nil, x), w)
+}
+
+func (t TimeType) Parse(data []byte) (time.Time, int, error) {
+	val, n, err := t.inner.Parse(data)
+	if err != nil {
+		return time.Time{}, n, err
+	}
+
+	if t.layout != "" {
+		res, err := time.Parse(t.layout, val)
+		return res, n, err
+	}
+
+	elapsed, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return time.Time{}, n, err
+	}
+
+	return time.Unix(0, 0).Add(time.Duration(elapsed) * t.unit).UTC(), n, nil
+}
+
+func (t TimeType) Compile(x time.Time, w io.Writer) error {
+	if t.layout != "" {
+		return t.inner.Compile(x.AppendFormat(nil, t.layout), w)
+	}
+	return t.inner.Compile(strconv.AppendInt(nil, x.UnixNano()/int64(t.unit), 10), w)
+}
+
+func (d DurationType) Parse(data []byte) (time.Duration, int, error) {
+	val, n, err := d.inner.Parse(data)
+	if err != nil {
+		return 0, n, err
+	}
+
+	res, err := time.ParseDuration(val)
+	return res, n, err
+}
+
+func (d DurationType) Compile(x time.Duration, w io.Writer) error {
+	return d.inner.Compile([]byte(x.String()), w)
+}
+
+func (d DecimalType) Parse(data []byte) (*big.Rat, int, error) {
+	val, n, err := d.inner.Parse(data)
+	if err != nil {
+		return nil, n, err
+	}
+
+	res, ok := new(big.Rat).SetString(val)
+	if !ok {
+		return nil, n, errors.Wrapf(strconv.ErrSyntax, "decimal: parsing '%s'", val)
+	}
+
+	return res, n, nil
+}
+
+// Compile writes `x` with as many decimals as needed to represent it exactly or, when not possible,
+// such as for 1/3, rounded to 18 decimals. Nil decimals are written as empty fields.
+func (d DecimalType) Compile(x *big.Rat, w io.Writer) error {
+	if x == nil {
+		return d.inner.Compile(nil, w)
+	}
+
+	prec, exact := x.FloatPrec()
+	if !exact {
+		prec = 18
+	}
+	return d.inner.Compile([]byte(x.FloatString(prec)), w)
+}
+
 func StrType(quote, sep byte) StringType {
 	return StringType{quote: quote, sep: sep}
 }
@@ -100,3 +414,11 @@ func StrType(quote, sep byte) StringType {
 func IntType(quote, sep byte) IntegerType {
 	return IntegerType{inner: StrType(quote, sep)}
 }
+
+func newStrType(quote byte, opts opts) StringType {
+	return StringType{quote: quote, sep: opts.sep, rfc4180: opts.rfc4180, tsv: opts.tsv}
+}
+
+func newIntType(quote byte, opts opts) IntegerType {
+	return IntegerType{inner: newStrType(quote, opts)}
+}
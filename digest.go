@@ -0,0 +1,335 @@
+package withttp
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrUnsupportedDigest = errors.New("unsupported digest challenge")
+)
+
+type (
+	// authChallenge is a challenge of a WWW-Authenticate header, see RFC 7235, whose parameter
+	// names are lower cased.
+	authChallenge struct {
+		scheme string
+		params map[string]string
+	}
+
+	// digestChallenge holds the parameters of a Digest WWW-Authenticate challenge, see RFC 7616.
+	digestChallenge struct {
+		realm     string
+		nonce     string
+		opaque    string
+		algorithm string
+		qop       bool
+	}
+
+	// digestAuth answers Digest challenges on behalf of a user. Once challenged, requests are
+	// authorized upfront with the same nonce, whose count it keeps track of.
+	digestAuth struct {
+		user string
+		pass string
+
+		cnonce func() string
+
+		mu        sync.Mutex
+		challenge *digestChallenge
+		nc        int
+	}
+)
+
+// DigestAuth authorizes the call with HTTP Digest authentication, as per RFC 7616, with MD5 or
+// SHA-256, either of them in their session variant, and qop=auth. Request is sent again after the
+// server challenges it with 401 Unauthorized, hence its body must not be streamed.
+func DigestAuth[T any](user, pass string) CallReqOptionFunc[T] {
+	return func(c *Call[T], _ Request) error {
+		c.reqMiddlewares = append(c.reqMiddlewares, DigestAuthMiddleware(user, pass))
+		return nil
+	}
+}
+
+// DigestAuthMiddleware authorizes every request sent by the client with HTTP Digest authentication,
+// as DigestAuth does. Challenges are remembered, so that following requests are authorized upfront
+// until the server challenges them again, such as when the nonce goes stale. Out of several Digest
+// challenges, the strongest supported one is answered, SHA-256 before MD5.
+func DigestAuthMiddleware(user, pass string) Middleware {
+	d := &digestAuth{user: user, pass: pass, cnonce: newNonce}
+
+	return MiddlewareFunc(func(ctx context.Context, req Request, next DoFunc) (Response, error) {
+		if err := d.authorize(req); err != nil {
+			return nil, err
+		}
+
+		res, err := next(ctx, req)
+		if err != nil || res == nil || res.Status() != http.StatusUnauthorized {
+			return res, err
+		}
+
+		var headers []string
+		res.RangeHeaders(func(key, value string) {
+			if strings.EqualFold(key, "www-authenticate") {
+				headers = append(headers, value)
+			}
+		})
+
+		challenge, err := parseDigestChallenge(strings.Join(headers, ", "))
+		if err != nil {
+			// Not challenged with Digest, which is up to the caller to handle.
+			return res, nil
+		}
+
+		if rw, ok := req.(rewindableRequest); !ok || !rw.rewind() {
+			return res, nil
+		}
+
+		d.challenged(challenge)
+
+		if err = d.authorize(req); err != nil {
+			return res, err
+		}
+
+		if body := res.Body(); body != nil {
+			_ = body.Close()
+		}
+
+		return next(ctx, req)
+	})
+}
+
+func (d *digestAuth) challenged(challenge *digestChallenge) {
+	d.mu.Lock()
+	d.challenge, d.nc = challenge, 0
+	d.mu.Unlock()
+}
+
+// authorize sets the authorization header of `req`, provided the server already challenged us.
+func (d *digestAuth) authorize(req Request) error {
+	d.mu.Lock()
+	challenge := d.challenge
+	d.nc++
+	nc := d.nc
+	d.mu.Unlock()
+
+	if challenge == nil {
+		return nil
+	}
+
+	header, err := challenge.authorization(d.user, d.pass, req.Method(), req.URL().RequestURI(), nc,
+		d.cnonce())
+	if err != nil {
+		return err
+	}
+
+	return ConfigureHeader(req, "authorization", header, true)
+}
+
+func (c *digestChallenge) authorization(user, pass, method, uri string, nc int, cnonce string) (string, error) {
+	var newHash func() hash.Hash
+
+	algorithm := strings.ToUpper(c.algorithm)
+
+	switch strings.TrimSuffix(algorithm, "-SESS") {
+	case "", "MD5":
+		newHash = md5.New
+	case "SHA-256":
+		newHash = sha256.New
+	default:
+		return "", errors.Wrapf(ErrUnsupportedDigest, "algorithm: '%s'", c.algorithm)
+	}
+
+	h := func(parts ...string) string {
+		digest := newHash()
+		digest.Write([]byte(strings.Join(parts, ":")))
+		return hex.EncodeToString(digest.Sum(nil))
+	}
+
+	count := fmt.Sprintf("%08x", nc)
+
+	ha1 := h(user, c.realm, pass)
+	if strings.HasSuffix(algorithm, "-SESS") {
+		ha1 = h(ha1, c.nonce, cnonce)
+	}
+
+	ha2 := h(method, uri)
+
+	var response string
+	if c.qop {
+		response = h(ha1, c.nonce, count, cnonce, "auth", ha2)
+	} else {
+		// RFC 2069 compatibility.
+		response = h(ha1, c.nonce, ha2)
+	}
+
+	b := new(strings.Builder)
+	fmt.Fprintf(b, `Digest username="%s", realm="%s", nonce="%s", uri="%s", response="%s"`,
+		quoteDigest(user), quoteDigest(c.realm), quoteDigest(c.nonce), quoteDigest(uri), response)
+
+	if c.algorithm != "" {
+		fmt.Fprintf(b, ", algorithm=%s", c.algorithm)
+	}
+
+	if c.qop {
+		fmt.Fprintf(b, `, qop=auth, nc=%s, cnonce="%s"`, count, quoteDigest(cnonce))
+	}
+
+	if c.opaque != "" {
+		fmt.Fprintf(b, `, opaque="%s"`, quoteDigest(c.opaque))
+	}
+
+	return b.String(), nil
+}
+
+// parseDigestChallenge returns the strongest supported Digest challenge of a WWW-Authenticate
+// header, which may hold several challenges, comma separated, as RFC 7616, section 3.7, allows.
+// Equally strong challenges are answered in the order the server prefers, that is, the first one.
+func parseDigestChallenge(header string) (*digestChallenge, error) {
+	var best *digestChallenge
+
+	err := errors.Wrap(ErrUnsupportedDigest, "missing challenge")
+
+	for _, c := range parseAuthChallenges(header) {
+		challenge, cerr := newDigestChallenge(c)
+		if cerr != nil {
+			err = cerr
+			continue
+		}
+
+		if best == nil || challenge.strength() > best.strength() {
+			best = challenge
+		}
+	}
+
+	if best == nil {
+		return nil, err
+	}
+	return best, nil
+}
+
+func newDigestChallenge(c authChallenge) (*digestChallenge, error) {
+	if !strings.EqualFold(c.scheme, "digest") {
+		return nil, errors.Wrapf(ErrUnsupportedDigest, "scheme: '%s'", c.scheme)
+	}
+
+	challenge := &digestChallenge{
+		realm:     c.params["realm"],
+		nonce:     c.params["nonce"],
+		opaque:    c.params["opaque"],
+		algorithm: c.params["algorithm"],
+	}
+
+	if challenge.strength() == 0 {
+		return nil, errors.Wrapf(ErrUnsupportedDigest, "algorithm: '%s'", challenge.algorithm)
+	}
+
+	if qop := c.params["qop"]; qop != "" {
+		for _, option := range strings.Split(qop, ",") {
+			if strings.TrimSpace(option) == "auth" {
+				challenge.qop = true
+			}
+		}
+
+		if !challenge.qop {
+			return nil, errors.Wrapf(ErrUnsupportedDigest, "qop: '%s'", qop)
+		}
+	}
+
+	if challenge.nonce == "" {
+		return nil, errors.Wrap(ErrUnsupportedDigest, "missing nonce")
+	}
+
+	return challenge, nil
+}
+
+// strength ranks the algorithm of the challenge, unsupported ones ranking 0.
+func (c *digestChallenge) strength() int {
+	switch strings.TrimSuffix(strings.ToUpper(c.algorithm), "-SESS") {
+	case "", "MD5":
+		return 1
+	case "SHA-256":
+		return 2
+	default:
+		return 0
+	}
+}
+
+// parseAuthChallenges parses the challenges of a WWW-Authenticate header, each of them a scheme
+// followed by comma separated key=value parameters, values being optionally quoted. Tokens not
+// followed by "=" start new challenges.
+func parseAuthChallenges(header string) []authChallenge {
+	var challenges []authChallenge
+
+	for rest := strings.TrimSpace(header); ; {
+		rest = strings.TrimLeft(rest, ", ")
+		if rest == "" {
+			return challenges
+		}
+
+		end := strings.IndexAny(rest, " =,")
+		if end < 0 {
+			end = len(rest)
+		}
+
+		token := rest[:end]
+		rest = strings.TrimLeft(rest[end:], " ")
+
+		if len(challenges) == 0 || !strings.HasPrefix(rest, "=") {
+			challenges = append(challenges, authChallenge{scheme: token, params: map[string]string{}})
+			continue
+		}
+
+		var value string
+
+		rest = strings.TrimLeft(rest[1:], " ")
+		if strings.HasPrefix(rest, `"`) {
+			value, rest = unquoteDigest(rest[1:])
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+			value = strings.TrimSpace(value)
+		}
+
+		challenges[len(challenges)-1].params[strings.ToLower(token)] = value
+	}
+}
+
+// unquoteDigest returns the quoted string `s` starts with, after its opening quote, and the rest.
+func unquoteDigest(s string) (string, string) {
+	b := new(strings.Builder)
+
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case '"':
+			return b.String(), s[i+1:]
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+
+	return b.String(), ""
+}
+
+func quoteDigest(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
+
+func newNonce() string {
+	bts := make([]byte, 16)
+	_, _ = rand.Read(bts)
+	return hex.EncodeToString(bts)
+}
@@ -0,0 +1,202 @@
+package withttp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestDigestChallenge_Authorization(t *testing.T) {
+	type (
+		args struct {
+			header string
+		}
+
+		want struct {
+			authorization string
+			err           error
+		}
+
+		testCase struct {
+			name string
+			args args
+			want want
+		}
+	)
+
+	// Example of RFC 7616, section 3.9.1.
+	const (
+		realm  = `realm="http-auth@example.org"`
+		nonce  = `nonce="7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v"`
+		opaque = `opaque="FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS"`
+		cnonce = "f2/wE4q74E6zIJEtWaHKaf5wv/H5QzzpXusqGemxURZJ"
+	)
+
+	tests := []testCase{
+		{
+			name: "md5",
+			args: args{
+				header: `Digest ` + realm + `, qop="auth, auth-int", algorithm=MD5, ` + nonce + `, ` + opaque,
+			},
+			want: want{
+				authorization: `Digest username="Mufasa", ` + realm + `, ` + nonce + `, uri="/dir/index.html", ` +
+					`response="8ca523f5e9506fed4657c9700eebdbec", algorithm=MD5, qop=auth, nc=00000001, ` +
+					`cnonce="` + cnonce + `", ` + opaque,
+			},
+		},
+		{
+			name: "sha-256",
+			args: args{
+				header: `Digest ` + realm + `, qop="auth, auth-int", algorithm=SHA-256, ` + nonce + `, ` + opaque,
+			},
+			want: want{
+				authorization: `Digest username="Mufasa", ` + realm + `, ` + nonce + `, uri="/dir/index.html", ` +
+					`response="753927fa0e85d155564e2e272a28d1802ca10daf4496794697cf8db5856cb6c1", ` +
+					`algorithm=SHA-256, qop=auth, nc=00000001, cnonce="` + cnonce + `", ` + opaque,
+			},
+		},
+		{
+			name: "strongest challenge",
+			args: args{
+				header: `Basic realm="basic", Digest ` + realm + `, qop="auth", algorithm=MD5, ` + nonce + `, ` +
+					opaque + `, Digest ` + realm + `, qop="auth, auth-int", algorithm=SHA-256, ` + nonce +
+					`, ` + opaque + `, Digest ` + realm + `, qop="auth", algorithm=SHA-512-256, ` + nonce,
+			},
+			want: want{
+				authorization: `Digest username="Mufasa", ` + realm + `, ` + nonce + `, uri="/dir/index.html", ` +
+					`response="753927fa0e85d155564e2e272a28d1802ca10daf4496794697cf8db5856cb6c1", ` +
+					`algorithm=SHA-256, qop=auth, nc=00000001, cnonce="` + cnonce + `", ` + opaque,
+			},
+		},
+		{
+			name: "supported challenge",
+			args: args{
+				header: `Digest ` + realm + `, qop="auth", algorithm=SHA-512-256, ` + nonce + `, Digest ` +
+					realm + `, qop="auth, auth-int", algorithm=MD5, ` + nonce + `, ` + opaque,
+			},
+			want: want{
+				authorization: `Digest username="Mufasa", ` + realm + `, ` + nonce + `, uri="/dir/index.html", ` +
+					`response="8ca523f5e9506fed4657c9700eebdbec", algorithm=MD5, qop=auth, nc=00000001, ` +
+					`cnonce="` + cnonce + `", ` + opaque,
+			},
+		},
+		{
+			name: "unsupported algorithm",
+			args: args{header: `Digest ` + realm + `, qop="auth", algorithm=SHA-512-256, ` + nonce},
+			want: want{err: ErrUnsupportedDigest},
+		},
+		{
+			name: "unsupported qop",
+			args: args{header: `Digest ` + realm + `, qop="auth-int", ` + nonce},
+			want: want{err: ErrUnsupportedDigest},
+		},
+		{
+			name: "not a digest challenge",
+			args: args{header: `Basic ` + realm},
+			want: want{err: ErrUnsupportedDigest},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			challenge, err := parseDigestChallenge(test.args.header)
+
+			var authorization string
+			if err == nil {
+				authorization, err = challenge.authorization("Mufasa", "Circle of Life", http.MethodGet,
+					"/dir/index.html", 1, cnonce)
+			}
+
+			if !errors.Is(err, test.want.err) {
+				t.Fatalf("unexpected error, want %v, have %v", test.want.err, err)
+			}
+
+			if authorization != test.want.authorization {
+				t.Errorf("unexpected authorization,\nwant '%s'\nhave '%s'", test.want.authorization,
+					authorization)
+			}
+		})
+	}
+}
+
+func TestCall_DigestAuth(t *testing.T) {
+	const (
+		user, pass, realm, nonce = "Mufasa", "Circle of Life", "test", "dcd98b7102dd2f0e8b11d0f600bfb0c093"
+		payload                  = `{"name":"I am a payload"}`
+	)
+
+	h := func(parts ...string) string {
+		sum := sha256.Sum256([]byte(strings.Join(parts, ":")))
+		return hex.EncodeToString(sum[:])
+	}
+
+	var attempts int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+
+		authorization := r.Header.Get("authorization")
+		if authorization == "" {
+			// Weaker challenges offered first are to be passed over.
+			w.Header().Add("www-authenticate", `Digest realm="`+realm+`", qop="auth", `+
+				`algorithm=MD5, nonce="`+nonce+`", opaque="xyz"`)
+			w.Header().Add("www-authenticate", `Digest realm="`+realm+`", qop="auth,auth-int", `+
+				`algorithm=SHA-256, nonce="`+nonce+`", opaque="xyz"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		params := make(map[string]string)
+		for _, param := range strings.Split(strings.TrimPrefix(authorization, "Digest "), ", ") {
+			key, value, _ := strings.Cut(param, "=")
+			params[key] = strings.Trim(value, `"`)
+		}
+
+		response := h(h(user, realm, pass), nonce, params["nc"], params["cnonce"], "auth",
+			h(r.Method, r.URL.RequestURI()))
+
+		if params["response"] != response || params["nc"] != "00000001" || params["opaque"] != "xyz" {
+			t.Errorf("unexpected authorization, have '%s'", authorization)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if body, _ := io.ReadAll(r.Body); string(body) != payload {
+			t.Errorf("unexpected body, want '%s', have '%s'", payload, body)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	for name, cli := range map[string]Client{
+		"net/http": NetHttpClient(&http.Client{}),
+		"fasthttp": Fasthttp(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			atomic.StoreInt32(&attempts, 0)
+
+			call := NewCall[any](cli).
+				URL(srv.URL+"/dir/index.html?a=1").
+				Method(http.MethodPost).
+				RawBody([]byte(payload)).
+				DigestAuth(user, pass).
+				ExpectedStatusCodes(http.StatusNoContent)
+
+			if err := call.Call(context.TODO()); err != nil {
+				t.Fatalf("unexpected error, want none, have %v", err)
+			}
+
+			if attempts != 2 {
+				t.Errorf("unexpected attempts, want 2, have %d", attempts)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package withttp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// CurlBodyFile names the file curl commands read bodies from when they cannot be inlined, such
+	// as streamed or binary ones, which are to be saved there in order to run them.
+	CurlBodyFile = "body"
+
+	shellSafe = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_.,/:=%+"
+)
+
+var ErrNoResponse = errors.New("no response was received")
+
+// Curl renders the request of the call as a curl command, redacted as per the redaction policy
+// of the call, see Redact. Once called, it is the request last sent, otherwise the request is
+// configured as it would be, endpoint options aside.
+func (c *Call[T]) Curl() (string, error) {
+	req, err := c.dumpedRequest()
+	if err != nil {
+		return "", err
+	}
+
+	policy := c.redactionPolicy()
+
+	var data []string
+	if body, ok := RequestPayload(req); !ok || !isText(body) {
+		data = []string{"--data-binary @" + CurlBodyFile}
+	} else if len(body) > 0 {
+		// Unlike --data-binary, --data-raw does not read bodies starting with @ out of files.
+		data = []string{"--data-raw " + shellQuote(string(policy.Body(body)))}
+	}
+
+	args := []string{"curl"}
+
+	switch method := req.Method(); {
+	case method == http.MethodHead:
+		args = append(args, "--head")
+	case method != http.MethodGet || len(data) > 0:
+		// curl sends bodies along with POST requests unless told otherwise.
+		args = append(args, "-X "+shellQuote(method))
+	}
+
+	args = append(args, shellQuote(policy.URL(req.URL())))
+
+	for _, h := range sortedHeaders(req) {
+		switch strings.ToLower(h[0]) {
+		case "host", "content-length", "transfer-encoding":
+			// Told by curl itself.
+			continue
+		}
+		args = append(args, "-H "+shellQuote(h[0]+": "+policy.Header(h[0], h[1])))
+	}
+
+	return strings.Join(append(args, data...), " \\\n  "), nil
+}
+
+// DumpRequest dumps the request of the call in HTTP/1.1 wire format, redacted as per the
+// redaction policy of the call, see Redact. Requests are the ones told by Curl, whose streamed
+// bodies are left out.
+func (c *Call[T]) DumpRequest() ([]byte, error) {
+	req, err := c.dumpedRequest()
+	if err != nil {
+		return nil, err
+	}
+
+	policy := c.redactionPolicy()
+	buf := new(bytes.Buffer)
+
+	u := req.URL()
+	_, _ = fmt.Fprintf(buf, "%s %s HTTP/1.1\r\n", req.Method(), requestTarget(policy, u))
+	_, _ = fmt.Fprintf(buf, "Host: %s\r\n", u.Host)
+
+	body, buffered := RequestPayload(req)
+	body = policy.Body(body)
+	hasLength := false
+
+	for _, h := range sortedHeaders(req) {
+		value := policy.Header(h[0], h[1])
+
+		switch strings.ToLower(h[0]) {
+		case "host":
+			continue
+		case "content-length":
+			// Bodies dumped may be shorter or longer than those sent, once redacted.
+			if buffered {
+				hasLength, value = true, strconv.Itoa(len(body))
+			}
+		}
+		_, _ = fmt.Fprintf(buf, "%s: %s\r\n", h[0], value)
+	}
+
+	if buffered && len(body) > 0 && !hasLength {
+		_, _ = fmt.Fprintf(buf, "Content-Length: %d\r\n", len(body))
+	}
+
+	_, _ = buf.WriteString("\r\n")
+
+	if buffered {
+		_, _ = buf.Write(body)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// DumpResponse dumps the response last received by the call in HTTP/1.1 wire format, redacted as
+// per the redaction policy of the call, see Redact. Bodies are only dumped if read, see ReadBody,
+// as decoded, hence without their Content-Encoding, unless decompression is disabled.
+func (c *Call[T]) DumpResponse() ([]byte, error) {
+	if c.Res == nil {
+		return nil, ErrNoResponse
+	}
+
+	policy := c.redactionPolicy()
+	buf := new(bytes.Buffer)
+
+	status := c.Res.Status()
+	_, _ = fmt.Fprintf(buf, "HTTP/1.1 %d %s\r\n", status, http.StatusText(status))
+
+	body := policy.Body(c.BodyRaw)
+
+	decoded := false
+	if encoding, ok := c.Res.Header("content-encoding"); ok && c.BodyRaw != nil {
+		decoded = !c.decompressionDisabled && len(parseContentEncoding(encoding)) > 0
+	}
+
+	for _, h := range sortedHeaders(c.Res) {
+		value := policy.Header(h[0], h[1])
+		switch {
+		case decoded && strings.EqualFold(h[0], "content-encoding"):
+			continue
+		case c.BodyRaw != nil && strings.EqualFold(h[0], "content-length"):
+			value = strconv.Itoa(len(body))
+		}
+		_, _ = fmt.Fprintf(buf, "%s: %s\r\n", h[0], value)
+	}
+
+	_, _ = buf.WriteString("\r\n")
+	_, _ = buf.Write(body)
+
+	return buf.Bytes(), nil
+}
+
+// dumpedRequest returns the request last sent by the call or, if not called yet, a request
+// configured as it would be, endpoint options aside.
+func (c *Call[T]) dumpedRequest() (Request, error) {
+	if c.Req != nil {
+		return c.Req, nil
+	}
+
+	req, err := c.client.Request(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	if err = c.configureReq(req); err != nil {
+		return nil, err
+	}
+
+	if err = c.compressReq(req); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// requestTarget returns the path and query string of `u`, redacted as per `policy`.
+func requestTarget(policy *RedactionPolicy, u *url.URL) string {
+	target := url.URL{Path: u.Path, RawPath: u.RawPath, RawQuery: u.RawQuery}
+	if target.Path == "" {
+		target.Path = "/"
+	}
+	return policy.URL(&target)
+}
+
+// sortedHeaders returns the headers of `h` as key and value pairs sorted by key, which some
+// adapters range over in random order.
+func sortedHeaders(h header) [][2]string {
+	var headers [][2]string
+	h.RangeHeaders(func(key, value string) {
+		headers = append(headers, [2]string{key, value})
+	})
+
+	sort.SliceStable(headers, func(i, j int) bool {
+		return strings.ToLower(headers[i][0]) < strings.ToLower(headers[j][0])
+	})
+
+	return headers
+}
+
+// isText tells whether `data` can be passed as an argument to shell commands.
+func isText(data []byte) bool {
+	return utf8.Valid(data) && bytes.IndexByte(data, 0) < 0
+}
+
+// shellQuote quotes `s` as a single argument of POSIX shells, unless it is safe as it is.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, shellSafe) == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,330 @@
+package withttp
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestCurl(t *testing.T) {
+	type (
+		args struct {
+			call func(cli Client) *Call[any]
+		}
+
+		want struct {
+			curl string
+		}
+
+		testCase struct {
+			name string
+			args args
+			want want
+		}
+	)
+
+	tests := []testCase{
+		{
+			name: "get",
+			args: args{
+				call: func(cli Client) *Call[any] {
+					return NewCall[any](cli).
+						URL("https://example.com/items?page=2&token=abc").
+						Header("X-Api-Key", "s3cr3t", true).
+						Header("X-Name", "it's me", true)
+				},
+			},
+			want: want{
+				curl: "curl \\\n" +
+					"  'https://example.com/items?page=2&token=[REDACTED]' \\\n" +
+					"  -H 'X-Api-Key: [REDACTED]' \\\n" +
+					"  -H 'X-Name: it'\\''s me'",
+			},
+		},
+		{
+			name: "post",
+			args: args{
+				call: func(cli Client) *Call[any] {
+					return NewCall[any](cli).
+						URL("https://example.com/login").
+						Method(http.MethodPost).
+						ContentType(ContentTypeJSON).
+						RawBody([]byte(`{"user":"me","password":"hunter2"}`))
+				},
+			},
+			want: want{
+				curl: "curl \\\n" +
+					"  -X POST \\\n" +
+					"  https://example.com/login \\\n" +
+					"  -H 'Content-Type: application/json' \\\n" +
+					"  --data-raw '{\"user\":\"me\",\"password\":\"[REDACTED]\"}'",
+			},
+		},
+		{
+			name: "get with body",
+			args: args{
+				call: func(cli Client) *Call[any] {
+					return NewCall[any](cli).
+						URL("https://example.com/search").
+						RawBody([]byte("q"))
+				},
+			},
+			want: want{
+				curl: "curl \\\n" +
+					"  -X GET \\\n" +
+					"  https://example.com/search \\\n" +
+					"  --data-raw q",
+			},
+		},
+		{
+			name: "body starting with @",
+			args: args{
+				call: func(cli Client) *Call[any] {
+					return NewCall[any](cli).
+						URL("https://example.com/files").
+						Method(http.MethodPost).
+						RawBody([]byte("@etc/passwd"))
+				},
+			},
+			want: want{
+				curl: "curl \\\n" +
+					"  -X POST \\\n" +
+					"  https://example.com/files \\\n" +
+					"  --data-raw '@etc/passwd'",
+			},
+		},
+		{
+			name: "streamed",
+			args: args{
+				call: func(cli Client) *Call[any] {
+					return NewCall[any](cli).
+						URL("https://example.com/items").
+						Method(http.MethodPut).
+						RequestStreamBody(RequestStreamBody[any, int](Slice[int]{1, 2}))
+				},
+			},
+			want: want{
+				curl: "curl \\\n" +
+					"  -X PUT \\\n" +
+					"  https://example.com/items \\\n" +
+					"  --data-binary @body",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		for name, cli := range map[string]Client{
+			"net/http": NetHttpClient(&http.Client{}),
+			"fasthttp": Fasthttp(),
+		} {
+			t.Run(test.name+" "+name, func(t *testing.T) {
+				curl, err := test.args.call(cli).Curl()
+				if err != nil {
+					t.Fatalf("unexpected error, want none, have %v", err)
+				}
+
+				if curl != test.want.curl {
+					t.Errorf("unexpected curl, want:\n%s\nhave:\n%s", test.want.curl, curl)
+				}
+			})
+		}
+	}
+}
+
+func TestDump(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Set-Cookie", "session=abc")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":1,"token":"abc"}`))
+	}))
+	defer srv.Close()
+
+	call := NewCall[any](NetHttpClient(&http.Client{})).
+		URL(srv.URL + "/items?token=abc").
+		Method(http.MethodPost).
+		RawBody([]byte(`{"password":"hunter2"}`)).
+		ReadBody()
+
+	if _, err := call.DumpResponse(); err == nil {
+		t.Errorf("unexpected error, want some, have none")
+	}
+
+	if err := call.Call(context.TODO()); err != nil {
+		t.Fatalf("unexpected error, want none, have %v", err)
+	}
+
+	req, err := call.DumpRequest()
+	if err != nil {
+		t.Fatalf("unexpected error, want none, have %v", err)
+	}
+
+	want := "POST /items?token=[REDACTED] HTTP/1.1\r\n" +
+		"Host: " + srv.Listener.Addr().String() + "\r\n" +
+		"Content-Length: 25\r\n" +
+		"\r\n" +
+		`{"password":"[REDACTED]"}`
+	if !bytes.Equal(req, []byte(want)) {
+		t.Errorf("unexpected request, want:\n%s\nhave:\n%s", want, req)
+	}
+
+	res, err := call.DumpResponse()
+	if err != nil {
+		t.Fatalf("unexpected error, want none, have %v", err)
+	}
+
+	want = "HTTP/1.1 201 Created\r\n" +
+		"Content-Length: 29\r\n" +
+		"Content-Type: application/json\r\n" +
+		"Date: " + call.Res.(*nativeResAdapter).res.Header.Get("Date") + "\r\n" +
+		"Set-Cookie: [REDACTED]\r\n" +
+		"\r\n" +
+		`{"id":1,"token":"[REDACTED]"}`
+	if !bytes.Equal(res, []byte(want)) {
+		t.Errorf("unexpected response, want:\n%s\nhave:\n%s", want, res)
+	}
+}
+
+func TestDumpResponse_ContentEncoding(t *testing.T) {
+	type (
+		args struct {
+			decompressionDisabled bool
+		}
+
+		want struct {
+			res string
+		}
+
+		testCase struct {
+			name string
+			args args
+			want want
+		}
+	)
+
+	compressed, err := EncodeContentEncoding([]byte(`{"id":1}`), ContentEncodingGzip, -1)
+	if err != nil {
+		t.Fatalf("unexpected error, want none, have %v", err)
+	}
+
+	tests := []testCase{
+		{
+			name: "decoded",
+			args: args{},
+			want: want{
+				res: "HTTP/1.1 200 OK\r\n" +
+					"Content-Length: 8\r\n" +
+					"Content-Type: application/json\r\n" +
+					"\r\n" +
+					`{"id":1}`,
+			},
+		},
+		{
+			name: "decompression disabled",
+			args: args{decompressionDisabled: true},
+			want: want{
+				res: "HTTP/1.1 200 OK\r\n" +
+					"Content-Encoding: gzip\r\n" +
+					"Content-Length: " + strconv.Itoa(len(compressed)) + "\r\n" +
+					"Content-Type: application/json\r\n" +
+					"\r\n" +
+					string(compressed),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cli := HandlerClient(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Content-Encoding", ContentEncodingGzip)
+				w.Header().Set("Content-Length", strconv.Itoa(len(compressed)))
+				_, _ = w.Write(compressed)
+			}))
+
+			call := NewCall[any](cli).URL("http://example.com/items").ReadBody()
+			if test.args.decompressionDisabled {
+				call.DisableDecompression()
+			}
+
+			if err := call.Call(context.TODO()); err != nil {
+				t.Fatalf("unexpected error, want none, have %v", err)
+			}
+
+			res, err := call.DumpResponse()
+			if err != nil {
+				t.Fatalf("unexpected error, want none, have %v", err)
+			}
+
+			if !bytes.Equal(res, []byte(test.want.res)) {
+				t.Errorf("unexpected response, want:\n%q\nhave:\n%q", test.want.res, res)
+			}
+		})
+	}
+}
+
+func TestDumpRequest_ContentLength(t *testing.T) {
+	type (
+		args struct {
+			cli Client
+		}
+
+		want struct {
+			req string
+		}
+
+		testCase struct {
+			name string
+			args args
+			want want
+		}
+	)
+
+	tests := []testCase{
+		{
+			name: "net/http",
+			args: args{cli: NetHttpClient(&http.Client{})},
+			want: want{
+				req: "POST /login HTTP/1.1\r\n" +
+					"Host: example.com\r\n" +
+					"Content-Type: application/json\r\n" +
+					"Content-Length: 25\r\n" +
+					"\r\n" +
+					`{"password":"[REDACTED]"}`,
+			},
+		},
+		{
+			name: "fasthttp",
+			args: args{cli: Fasthttp()},
+			want: want{
+				req: "POST /login HTTP/1.1\r\n" +
+					"Host: example.com\r\n" +
+					"Content-Length: 25\r\n" +
+					"Content-Type: application/json\r\n" +
+					"\r\n" +
+					`{"password":"[REDACTED]"}`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := NewCall[any](test.args.cli).
+				URL("http://example.com/login").
+				Method(http.MethodPost).
+				ContentType(ContentTypeJSON).
+				RawBody([]byte(`{ "password" : "hunter2" }`)).
+				DumpRequest()
+			if err != nil {
+				t.Fatalf("unexpected error, want none, have %v", err)
+			}
+
+			if !bytes.Equal(req, []byte(test.want.req)) {
+				t.Errorf("unexpected request, want:\n%s\nhave:\n%s", test.want.req, req)
+			}
+		})
+	}
+}
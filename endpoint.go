@@ -64,6 +64,12 @@ type (
 
 	ReqOptionFunc func(req Request) error
 
+	// contextReqOption is implemented by request options needing the context of the call, such as
+	// those fetching tokens, which calls configure them with.
+	contextReqOption interface {
+		configureContext(ctx context.Context, req Request) error
+	}
+
 	ResOption interface {
 		Parse(r Response) error
 	}
@@ -6,4 +6,5 @@ var (
 	ErrAssertion            = errors.New("assertion was unmet")
 	ErrUnexpectedStatusCode = errors.Wrap(ErrAssertion, "unexpected status code")
 	ErrInsufficientParams   = errors.New("insufficient params")
+	ErrTooManyRecordErrors  = errors.New("too many record errors")
 )
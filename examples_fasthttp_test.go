@@ -3,9 +3,7 @@ package withttp
 import (
 	"context"
 	"fmt"
-	"io"
 	"net/http"
-	"strings"
 	"testing"
 	"time"
 )
@@ -96,21 +94,18 @@ func TestFastHTTP_GetRepoInfo(t *testing.T) {
 
 func TestFastHTTP_MockedExample(t *testing.T) {
 	mockEndpoint := NewEndpoint("MockGithubAPI").
-		Request(BaseURL("http://example.com")).
-		Response(
-			MockedRes(func(res Response) {
-				res.SetBody(
-					io.NopCloser(
-						strings.NewReader(
-							`{"id": 67890, "html_url": "https://github.com/sonirico/withttp"}`,
-						),
-					),
-				)
-				res.SetStatus(http.StatusOK)
-			}),
-		)
-
-	call := NewCall[githubRepoInfoFast](Fasthttp()).
+		Request(BaseURL("http://example.com"))
+
+	fake := NewFakeClient()
+	fake.On(http.MethodGet, "/repos/sonirico/withttp").
+		WithHeader("User-Agent", "withttp/0.5.1 See https://github.com/sonirico/withttp").
+		Respond(
+			http.StatusOK,
+			[]byte(`{"id": 67890, "html_url": "https://github.com/sonirico/withttp"}`),
+		).
+		Times(1)
+
+	call := NewCall[githubRepoInfoFast](fake).
 		URI("repos/sonirico/withttp").
 		Method(http.MethodGet).
 		Header("User-Agent", "withttp/0.5.1 See https://github.com/sonirico/withttp", false).
@@ -135,6 +130,8 @@ func TestFastHTTP_MockedExample(t *testing.T) {
 	if call.BodyParsed.URL != "https://github.com/sonirico/withttp" {
 		t.Errorf("Expected URL 'https://github.com/sonirico/withttp', got %s", call.BodyParsed.URL)
 	}
+
+	fake.AssertExpectations(t)
 }
 
 func TestFastHTTP_CreateIssue(t *testing.T) {
@@ -154,29 +151,29 @@ func TestFastHTTP_CreateIssue(t *testing.T) {
 
 func TestFastHTTP_MockedCreateIssue(t *testing.T) {
 	mockEndpoint := NewEndpoint("MockGithubAPI").
-		Request(BaseURL("http://example.com")).
-		Response(
-			MockedRes(func(res Response) {
-				res.SetBody(
-					io.NopCloser(
-						strings.NewReader(
-							`{"id": 999, "url": "https://api.github.com/repos/sonirico/withttp/issues/999"}`,
-						),
-					),
-				)
-				res.SetStatus(http.StatusCreated)
-			}),
-		)
+		Request(BaseURL("http://example.com"))
 
-	call := NewCall[githubCreateIssueResponse](Fasthttp()).
+	issue := map[string]string{
+		"title":    "test issue",
+		"body":     "test body",
+		"assignee": "sonirico",
+	}
+
+	fake := NewFakeClient()
+	fake.On(http.MethodPost, "/repos/sonirico/withttp/issues").
+		WithHeader("Authorization", "Bearer S3cret").
+		WithJSONBody(issue).
+		Respond(
+			http.StatusCreated,
+			[]byte(`{"id": 999, "url": "https://api.github.com/repos/sonirico/withttp/issues/999"}`),
+		).
+		Times(1)
+
+	call := NewCall[githubCreateIssueResponse](fake).
 		URI("repos/sonirico/withttp/issues").
 		Method(http.MethodPost).
 		ContentType(ContentTypeJSON).
-		Body(map[string]string{
-			"title":    "test issue",
-			"body":     "test body",
-			"assignee": "sonirico",
-		}).
+		Body(issue).
 		HeaderFunc(func() (key, value string, override bool) {
 			key = "Authorization"
 			value = "Bearer S3cret"
@@ -199,6 +196,8 @@ func TestFastHTTP_MockedCreateIssue(t *testing.T) {
 	if call.BodyParsed.URL != expectedURL {
 		t.Errorf("Expected URL '%s', got %s", expectedURL, call.BodyParsed.URL)
 	}
+
+	fake.AssertExpectations(t)
 }
 
 // Example_fasthttp_basicCall demonstrates how to make a simple HTTP call using the FastHTTP adapter.
@@ -208,17 +207,15 @@ func Example_fasthttp_basicCall() {
 		Name string `json:"name"`
 	}
 
-	// Create a mocked endpoint for the example
 	endpoint := NewEndpoint("GithubAPI").
-		Request(BaseURL("https://api.github.com")).
-		Response(
-			MockedRes(func(res Response) {
-				res.SetBody(io.NopCloser(strings.NewReader(`{"id": 12345, "name": "withttp"}`)))
-				res.SetStatus(http.StatusOK)
-			}),
-		)
-
-	call := NewCall[RepoInfo](NewMockHttpClientAdapter()).
+		Request(BaseURL("https://api.github.com"))
+
+	// Stub the responses of the endpoint for the example
+	fake := NewFakeClient()
+	fake.On(http.MethodGet, "/repos/user/repo").
+		Respond(http.StatusOK, []byte(`{"id": 12345, "name": "withttp"}`))
+
+	call := NewCall[RepoInfo](fake).
 		URI("/repos/user/repo").
 		Method(http.MethodGet).
 		Header("User-Agent", "withttp-example", false).
@@ -247,26 +244,20 @@ func Example_fasthttp_postRequest() {
 		URL string `json:"url"`
 	}
 
-	// Create a mocked endpoint for the example
 	endpoint := NewEndpoint("API").
-		Request(BaseURL("https://api.example.com")).
-		Response(
-			MockedRes(func(res Response) {
-				res.SetBody(
-					io.NopCloser(
-						strings.NewReader(`{"id": 42, "url": "https://api.example.com/items/42"}`),
-					),
-				)
-				res.SetStatus(http.StatusCreated)
-			}),
-		)
+		Request(BaseURL("https://api.example.com"))
+
+	// Stub the responses of the endpoint for the example
+	fake := NewFakeClient()
+	fake.On(http.MethodPost, "/items").
+		Respond(http.StatusCreated, []byte(`{"id": 42, "url": "https://api.example.com/items/42"}`))
 
 	payload := CreateRequest{
 		Title: "Example Item",
 		Body:  "This is an example",
 	}
 
-	call := NewCall[CreateResponse](NewMockHttpClientAdapter()).
+	call := NewCall[CreateResponse](fake).
 		URI("/items").
 		Method(http.MethodPost).
 		ContentType(ContentTypeJSON).
@@ -292,15 +283,14 @@ func Example_fasthttp_withHeaderFunc() {
 	}
 
 	endpoint := NewEndpoint("API").
-		Request(BaseURL("https://api.example.com")).
-		Response(
-			MockedRes(func(res Response) {
-				res.SetBody(io.NopCloser(strings.NewReader(`{"message": "Hello"}`)))
-				res.SetStatus(http.StatusOK)
-			}),
-		)
-
-	call := NewCall[APIResponse](NewMockHttpClientAdapter()).
+		Request(BaseURL("https://api.example.com"))
+
+	fake := NewFakeClient()
+	fake.On(http.MethodGet, "/hello").
+		WithHeader("X-Request-ID", "req-12345").
+		Respond(http.StatusOK, []byte(`{"message": "Hello"}`))
+
+	call := NewCall[APIResponse](fake).
 		URI("/hello").
 		Method(http.MethodGet).
 		HeaderFunc(func() (key, value string, override bool) {
@@ -1,7 +1,6 @@
 package withttp
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -33,15 +32,13 @@ func TestRequestStream_FromSlice(t *testing.T) {
 	// Mock endpoint that captures the request body
 	var receivedData []byte
 	testEndpoint := NewEndpoint("webhook-site-request-stream-example").
-		Request(BaseURL("https://webhook.site/24e84e8f-75cf-4239-828e-8bed244c0afb")).
-		Response(
-			MockedRes(func(res Response) {
-				res.SetStatus(http.StatusOK)
-				res.SetBody(io.NopCloser(strings.NewReader(`{"status": "ok"}`)))
-			}),
-		)
-
-	call := NewCall[any](Fasthttp()).
+		Request(BaseURL("https://webhook.site/24e84e8f-75cf-4239-828e-8bed244c0afb"))
+
+	fake := NewFakeClient()
+	fake.On(http.MethodPost, "/24e84e8f-75cf-4239-828e-8bed244c0afb").
+		Respond(http.StatusOK, []byte(`{"status": "ok"}`))
+
+	call := NewCall[any](fake).
 		Method(http.MethodPost).
 		ContentType(ContentTypeJSONEachRow).
 		RequestSniffed(func(data []byte, err error) {
@@ -90,15 +87,13 @@ func TestRequestStream_FromChannel(t *testing.T) {
 
 	var receivedData []byte
 	testEndpoint := NewEndpoint("webhook-site-request-stream-example").
-		Request(BaseURL("https://webhook.site/24e84e8f-75cf-4239-828e-8bed244c0afb")).
-		Response(
-			MockedRes(func(res Response) {
-				res.SetStatus(http.StatusOK)
-				res.SetBody(io.NopCloser(strings.NewReader(`{"status": "ok"}`)))
-			}),
-		)
-
-	call := NewCall[any](Fasthttp()).
+		Request(BaseURL("https://webhook.site/24e84e8f-75cf-4239-828e-8bed244c0afb"))
+
+	fake := NewFakeClient()
+	fake.On(http.MethodPost, "/24e84e8f-75cf-4239-828e-8bed244c0afb").
+		Respond(http.StatusOK, []byte(`{"status": "ok"}`))
+
+	call := NewCall[any](fake).
 		Method(http.MethodPost).
 		ContentType(ContentTypeJSONEachRow).
 		RequestSniffed(func(data []byte, err error) {
@@ -127,29 +122,26 @@ func TestRequestStream_FromChannel(t *testing.T) {
 }
 
 func TestRequestStream_FromReader(t *testing.T) {
-	buf := bytes.NewBuffer(nil)
+	r, w := io.Pipe()
 
 	go func() {
-		buf.WriteString(`{"t":"2022-09-01T00:58:15+02:00","T":39}`)
-		buf.WriteByte('\n')
-		buf.WriteString(`{"t":"2022-09-01T00:59:15+02:00","T":40}`)
-		buf.WriteByte('\n')
+		_, _ = io.WriteString(w, `{"t":"2022-09-01T00:58:15+02:00","T":39}`+"\n")
+		_, _ = io.WriteString(w, `{"t":"2022-09-01T00:59:15+02:00","T":40}`+"\n")
+		_ = w.Close()
 	}()
 
 	streamFactory := NewProxyStreamFactory(1 << 10)
-	stream := NewStreamFromReader(buf, streamFactory)
+	stream := NewStreamFromReader(r, streamFactory)
 
 	var receivedData []byte
 	testEndpoint := NewEndpoint("webhook-site-request-stream-example").
-		Request(BaseURL("https://webhook.site/24e84e8f-75cf-4239-828e-8bed244c0afb")).
-		Response(
-			MockedRes(func(res Response) {
-				res.SetStatus(http.StatusOK)
-				res.SetBody(io.NopCloser(strings.NewReader(`{"status": "ok"}`)))
-			}),
-		)
-
-	call := NewCall[any](NetHttp()).
+		Request(BaseURL("https://webhook.site/24e84e8f-75cf-4239-828e-8bed244c0afb"))
+
+	fake := NewFakeClient()
+	fake.On(http.MethodPost, "/24e84e8f-75cf-4239-828e-8bed244c0afb").
+		Respond(http.StatusOK, []byte(`{"status": "ok"}`))
+
+	call := NewCall[any](fake).
 		Method(http.MethodPost).
 		RequestSniffed(func(data []byte, err error) {
 			receivedData = append(receivedData, data...)
@@ -194,15 +186,12 @@ func Example_requestStream_fromSlice() {
 	stream := Slice[DataPoint](data)
 
 	mockEndpoint := NewEndpoint("DataIngestion").
-		Request(BaseURL("https://api.example.com")).
-		Response(
-			MockedRes(func(res Response) {
-				res.SetBody(io.NopCloser(strings.NewReader(`{"status": "received"}`)))
-				res.SetStatus(http.StatusOK)
-			}),
-		)
-
-	call := NewCall[any](NewMockHttpClientAdapter()).
+		Request(BaseURL("https://api.example.com"))
+
+	fake := NewFakeClient()
+	fake.On(http.MethodPost, "/data").Respond(http.StatusOK, []byte(`{"status": "received"}`))
+
+	call := NewCall[any](fake).
 		URI("/data").
 		Method(http.MethodPost).
 		ContentType(ContentTypeJSONEachRow).
@@ -235,15 +224,12 @@ func Example_requestStream_withSniffer() {
 	stream := Slice[Event](events)
 
 	mockEndpoint := NewEndpoint("Analytics").
-		Request(BaseURL("https://api.example.com")).
-		Response(
-			MockedRes(func(res Response) {
-				res.SetBody(io.NopCloser(strings.NewReader(`{"processed": true}`)))
-				res.SetStatus(http.StatusOK)
-			}),
-		)
-
-	call := NewCall[any](NewMockHttpClientAdapter()).
+		Request(BaseURL("https://api.example.com"))
+
+	fake := NewFakeClient()
+	fake.On(http.MethodPost, "/events").Respond(http.StatusOK, []byte(`{"processed": true}`))
+
+	call := NewCall[any](fake).
 		URI("/events").
 		Method(http.MethodPost).
 		ContentType(ContentTypeJSONEachRow).
@@ -3,9 +3,7 @@ package withttp
 import (
 	"context"
 	"fmt"
-	"io"
 	"net/http"
-	"strings"
 	"testing"
 
 	"github.com/sonirico/withttp/csvparser"
@@ -13,18 +11,19 @@ import (
 
 var (
 	getRepoStatsEndpoint = NewEndpoint("GetRepoStats").
-		Request(BaseURL("http://example.com")).
-		Response(
-			MockedRes(func(res Response) {
-				mockResponse := `rank,repo_name,stars
-1,freeCodeCamp,341271
-2,996.ICU,261139`
-				res.SetBody(io.NopCloser(strings.NewReader(mockResponse)))
-				res.SetStatus(http.StatusOK)
-			}),
-		)
+		Request(BaseURL("http://example.com/stats.csv"))
 )
 
+// newRepoStatsFake stubs getRepoStatsEndpoint.
+func newRepoStatsFake() *FakeClient {
+	fake := NewFakeClient()
+	fake.On(http.MethodGet, "/stats.csv").
+		Respond(http.StatusOK, []byte(`rank,repo_name,stars
+1,freeCodeCamp,341271
+2,996.ICU,261139`))
+	return fake
+}
+
 type Repo struct {
 	Rank  int
 	Name  string
@@ -54,7 +53,7 @@ func TestResponseStream_ParseCSV(t *testing.T) {
 		),
 	)
 
-	call := NewCall[Repo](Fasthttp()).
+	call := NewCall[Repo](newRepoStatsFake()).
 		Method(http.MethodGet).
 		Header("User-Agent", "withttp/0.6.0 See https://github.com/sonirico/withttp", false).
 		ParseCSV(ignoreLines, parser, func(r Repo) bool {
@@ -91,9 +90,11 @@ func TestResponseStream_ParseCSVChannel(t *testing.T) {
 	ignoreLines := 1 // Skip header line
 	repoChan := make(chan Repo)
 	repos := make([]Repo, 0)
+	done := make(chan struct{})
 
-	// Collect repos from channel
+	// Collect repos from channel, which is closed once parsed
 	go func() {
+		defer close(done)
 		for repo := range repoChan {
 			repos = append(repos, repo)
 		}
@@ -118,7 +119,7 @@ func TestResponseStream_ParseCSVChannel(t *testing.T) {
 		),
 	)
 
-	call := NewCall[Repo](Fasthttp()).
+	call := NewCall[Repo](newRepoStatsFake()).
 		Method(http.MethodGet).
 		Header("User-Agent", "withttp/0.6.0 See https://github.com/sonirico/withttp", false).
 		ParseStreamChan(NewCSVStreamFactory[Repo](ignoreLines, parser), repoChan).
@@ -129,14 +130,7 @@ func TestResponseStream_ParseCSVChannel(t *testing.T) {
 		t.Fatalf("Failed to call endpoint: %v", err)
 	}
 
-	// Give the goroutine time to process
-	// In a real test, you might use a sync mechanism
-	if len(repos) == 0 {
-		// Channel might still be processing, wait a bit
-		for i := 0; i < 10 && len(repos) == 0; i++ {
-			// Small delay to allow goroutine to process
-		}
-	}
+	<-done
 
 	// Verify we parsed the expected repositories
 	if len(repos) != 2 {
@@ -169,13 +163,10 @@ func Example_parseCSV() {
 2,gin,65000`
 
 	endpoint := NewEndpoint("RepoStats").
-		Request(BaseURL("https://api.example.com")).
-		Response(
-			MockedRes(func(res Response) {
-				res.SetBody(io.NopCloser(strings.NewReader(csvData)))
-				res.SetStatus(http.StatusOK)
-			}),
-		)
+		Request(BaseURL("https://api.example.com"))
+
+	fake := NewFakeClient()
+	fake.On(http.MethodGet, "/top-repos.csv").Respond(http.StatusOK, []byte(csvData))
 
 	parser := csvparser.New[Repository](
 		csvparser.SeparatorComma,
@@ -196,7 +187,7 @@ func Example_parseCSV() {
 		),
 	)
 
-	call := NewCall[Repository](NewMockHttpClientAdapter()).
+	call := NewCall[Repository](fake).
 		URI("/top-repos.csv").
 		Method(http.MethodGet).
 		ParseCSV(1, parser, func(repo Repository) bool { // skip 1 header line
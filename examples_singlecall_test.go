@@ -3,9 +3,7 @@ package withttp
 import (
 	"context"
 	"fmt"
-	"io"
 	"net/http"
-	"strings"
 	"testing"
 )
 
@@ -49,23 +47,19 @@ func TestSingleCall_Example(t *testing.T) {
 }
 
 func TestSingleCall_MockedExample(t *testing.T) {
-	// This test uses a mocked endpoint to test the functionality without making real API calls
+	// This test stubs the endpoint to test the functionality without making real API calls
 	mockEndpoint := NewEndpoint("MockGithubAPI").
-		Request(BaseURL("http://example.com")).
-		Response(
-			MockedRes(func(res Response) {
-				res.SetBody(
-					io.NopCloser(
-						strings.NewReader(
-							`{"id": 12345, "html_url": "https://github.com/sonirico/withttp"}`,
-						),
-					),
-				)
-				res.SetStatus(http.StatusOK)
-			}),
-		)
+		Request(BaseURL("http://example.com"))
 
-	call := NewCall[githubRepoInfo](NetHttp()).
+	fake := NewFakeClient()
+	fake.On(http.MethodGet, "/repos/sonirico/withttp").
+		Respond(
+			http.StatusOK,
+			[]byte(`{"id": 12345, "html_url": "https://github.com/sonirico/withttp"}`),
+		).
+		Times(1)
+
+	call := NewCall[githubRepoInfo](fake).
 		URI("repos/sonirico/withttp").
 		Method(http.MethodGet).
 		Header("User-Agent", "withttp/0.5.1 See https://github.com/sonirico/withttp", false).
@@ -84,6 +78,8 @@ func TestSingleCall_MockedExample(t *testing.T) {
 	if call.BodyParsed.URL != "https://github.com/sonirico/withttp" {
 		t.Errorf("Expected URL 'https://github.com/sonirico/withttp', got %s", call.BodyParsed.URL)
 	}
+
+	fake.AssertExpectations(t)
 }
 
 // Example_singleCall demonstrates making a simple HTTP GET request.
@@ -93,17 +89,15 @@ func Example_singleCall() {
 		Name string `json:"name"`
 	}
 
-	// Create a mocked endpoint for the example
 	endpoint := NewEndpoint("GithubAPI").
-		Request(BaseURL("https://api.github.com")).
-		Response(
-			MockedRes(func(res Response) {
-				res.SetBody(io.NopCloser(strings.NewReader(`{"id": 12345, "name": "withttp"}`)))
-				res.SetStatus(http.StatusOK)
-			}),
-		)
-
-	call := NewCall[RepoInfo](NewMockHttpClientAdapter()).
+		Request(BaseURL("https://api.github.com"))
+
+	// Stub the responses of the endpoint for the example
+	fake := NewFakeClient()
+	fake.On(http.MethodGet, "/repos/user/repo").
+		Respond(http.StatusOK, []byte(`{"id": 12345, "name": "withttp"}`))
+
+	call := NewCall[RepoInfo](fake).
 		URI("/repos/user/repo").
 		Method(http.MethodGet).
 		Header("User-Agent", "withttp-example", false).
@@ -0,0 +1,353 @@
+package withttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrNoStub = errors.New("no stub matches the request")
+
+type (
+	// TestingT is the subset of testing.TB expectations are asserted with.
+	TestingT interface {
+		Helper()
+		Errorf(format string, args ...any)
+	}
+
+	// FakeClient serves requests out of stubs, registered through On, instead of sending them,
+	// capturing them to be inspected afterwards. Unlike mocked responses, see MockedRes, stubs act
+	// as the transport, so that retries, authentication and parsers see their responses as sent by
+	// servers. It is safe for concurrent use.
+	FakeClient struct {
+		mu       sync.Mutex
+		stubs    []*Stub
+		requests []FakeRequest
+	}
+
+	// Stub matches requests by method, path, query string parameters, headers and body, responding
+	// to them as told. Stubs are set up before being used.
+	Stub struct {
+		method, pattern string
+		query           url.Values
+		headers         http.Header
+		body            []byte
+		jsonBody        any
+		matchesJSON     bool
+
+		status     int
+		resHeaders http.Header
+		resBody    []byte
+		streamed   bool
+		delay      time.Duration
+		err        error
+		times      int
+
+		client *FakeClient
+		calls  int
+	}
+
+	// FakeRequest is a request sent through a fake client. Streamed bodies are not captured.
+	FakeRequest struct {
+		Method string
+		URL    *url.URL
+		Header http.Header
+		Body   []byte
+		// Stub that responded, if any.
+		Stub *Stub
+	}
+)
+
+func NewFakeClient() *FakeClient {
+	return &FakeClient{}
+}
+
+// On registers a stub matching requests sent with `method`, any if empty, to paths matching
+// `pattern`, as per path.Match, such as "/users/*/orders", any if empty. Stubs are matched in the
+// order they were registered, and respond with 200 OK unless told otherwise.
+func (f *FakeClient) On(method, pattern string) *Stub {
+	stub := &Stub{
+		method:     method,
+		pattern:    pattern,
+		query:      make(url.Values),
+		headers:    make(http.Header),
+		resHeaders: make(http.Header),
+		status:     http.StatusOK,
+		times:      -1,
+		client:     f,
+	}
+
+	f.mu.Lock()
+	f.stubs = append(f.stubs, stub)
+	f.mu.Unlock()
+
+	return stub
+}
+
+func (f *FakeClient) Request(ctx context.Context) (Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	return adaptReqMock(req), nil
+}
+
+func (f *FakeClient) Do(ctx context.Context, req Request) (Response, error) {
+	u := *req.URL()
+	// Paths joined to hosts, see URI, are relative, though sent as absolute ones.
+	if u.Host != "" && !strings.HasPrefix(u.Path, "/") {
+		u.Path = "/" + u.Path
+	}
+
+	captured := FakeRequest{
+		Method: req.Method(),
+		URL:    &u,
+		Header: make(http.Header),
+	}
+
+	req.RangeHeaders(func(key, value string) {
+		captured.Header.Add(key, value)
+	})
+
+	if body, ok := RequestPayload(req); ok {
+		captured.Body = append([]byte(nil), body...)
+	}
+
+	f.mu.Lock()
+	for _, stub := range f.stubs {
+		if stub.matches(captured) {
+			stub.calls++
+			captured.Stub = stub
+			break
+		}
+	}
+	f.requests = append(f.requests, captured)
+	f.mu.Unlock()
+
+	if captured.Stub == nil {
+		return nil, errors.Wrapf(ErrNoStub, "got: '%s %s'", captured.Method, captured.URL)
+	}
+
+	return captured.Stub.respond(ctx)
+}
+
+// Requests returns the requests sent so far, in the order they were.
+func (f *FakeClient) Requests() []FakeRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]FakeRequest(nil), f.requests...)
+}
+
+// AssertExpectations fails `t` unless stubs expected to be called a number of times, see
+// Stub.Times, were, and every request sent matched some stub.
+func (f *FakeClient) AssertExpectations(t TestingT) {
+	t.Helper()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, stub := range f.stubs {
+		if stub.times >= 0 && stub.calls != stub.times {
+			t.Errorf("unexpected calls to stub %s %s, want %d, have %d",
+				stub.method, stub.pattern, stub.times, stub.calls)
+		}
+	}
+
+	for _, req := range f.requests {
+		if req.Stub == nil {
+			t.Errorf("unexpected request, matching no stub: %s %s", req.Method, req.URL)
+		}
+	}
+}
+
+// WithQuery matches requests whose query string parameter `key` is `value`.
+func (s *Stub) WithQuery(key, value string) *Stub {
+	s.query.Add(key, value)
+	return s
+}
+
+// WithHeader matches requests whose header `key` is `value`.
+func (s *Stub) WithHeader(key, value string) *Stub {
+	s.headers.Add(key, value)
+	return s
+}
+
+// WithBody matches requests whose body is `body`.
+func (s *Stub) WithBody(body []byte) *Stub {
+	s.body = body
+	return s
+}
+
+// WithJSONBody matches requests whose body is JSON equal to `v` once encoded, whatever the order
+// of their fields and their formatting.
+func (s *Stub) WithJSONBody(v any) *Stub {
+	s.jsonBody, s.matchesJSON = normalizeJSON(v), true
+	return s
+}
+
+// Respond responds with `status` and `body`.
+func (s *Stub) Respond(status int, body []byte) *Stub {
+	s.status, s.resBody, s.streamed = status, body, false
+	return s
+}
+
+// RespondJSON responds with `status` and `v` encoded as JSON.
+func (s *Stub) RespondJSON(status int, v any) *Stub {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return s.Fail(err)
+	}
+	s.resHeaders.Set("content-type", ContentTypeJSON)
+	return s.Respond(status, body)
+}
+
+// RespondNDJSON responds with `status` and `items` streamed as JSON each row, of unknown length.
+func (s *Stub) RespondNDJSON(status int, items ...any) *Stub {
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	for _, item := range items {
+		if err := enc.Encode(item); err != nil {
+			return s.Fail(err)
+		}
+	}
+	s.resHeaders.Set("content-type", ContentTypeJSONEachRow)
+	s.Respond(status, buf.Bytes())
+	s.streamed = true
+	return s
+}
+
+// RespondHeader responds with the header `key` set to `value`.
+func (s *Stub) RespondHeader(key, value string) *Stub {
+	s.resHeaders.Add(key, value)
+	return s
+}
+
+// Delay responds after `d`, or fails once the context of requests is done.
+func (s *Stub) Delay(d time.Duration) *Stub {
+	s.delay = d
+	return s
+}
+
+// Fail fails requests with `err` instead of responding to them.
+func (s *Stub) Fail(err error) *Stub {
+	s.err = err
+	return s
+}
+
+// Times expects the stub to be called `n` times, see FakeClient.AssertExpectations, after which it
+// no longer matches requests, so that others registered later respond to them instead.
+func (s *Stub) Times(n int) *Stub {
+	s.times = n
+	return s
+}
+
+// Calls tells how many requests the stub responded to.
+func (s *Stub) Calls() int {
+	s.client.mu.Lock()
+	defer s.client.mu.Unlock()
+
+	return s.calls
+}
+
+func (s *Stub) matches(req FakeRequest) bool {
+	if s.times >= 0 && s.calls >= s.times {
+		return false
+	}
+
+	if s.method != "" && s.method != req.Method {
+		return false
+	}
+
+	if s.pattern != "" {
+		if ok, _ := path.Match(s.pattern, req.URL.Path); !ok {
+			return false
+		}
+	}
+
+	query := req.URL.Query()
+	for key, values := range s.query {
+		for _, value := range values {
+			if !slices.Contains(query[key], value) {
+				return false
+			}
+		}
+	}
+
+	for key, values := range s.headers {
+		for _, value := range values {
+			if !slices.Contains(req.Header.Values(key), value) {
+				return false
+			}
+		}
+	}
+
+	if s.body != nil && !bytes.Equal(s.body, req.Body) {
+		return false
+	}
+
+	if s.matchesJSON {
+		var body any
+		if err := json.Unmarshal(req.Body, &body); err != nil || !reflect.DeepEqual(body, s.jsonBody) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *Stub) respond(ctx context.Context) (Response, error) {
+	if s.delay > 0 {
+		timer := time.NewTimer(s.delay)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	res := &http.Response{
+		Status:        strconv.Itoa(s.status) + " " + http.StatusText(s.status),
+		StatusCode:    s.status,
+		Header:        s.resHeaders.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(s.resBody)),
+		ContentLength: int64(len(s.resBody)),
+	}
+
+	if s.streamed {
+		res.ContentLength = -1
+	}
+
+	return adaptResMock(res), nil
+}
+
+// normalizeJSON returns `v` as decoded out of its JSON encoding, so that it compares equal to
+// bodies decoded alike, or the error encoding it, which no body decodes to.
+func normalizeJSON(v any) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	var normalized any
+	_ = json.Unmarshal(data, &normalized)
+	return normalized
+}
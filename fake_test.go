@@ -0,0 +1,253 @@
+package withttp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type recordedT struct {
+	errors []string
+}
+
+func (t *recordedT) Helper() {}
+
+func (t *recordedT) Errorf(format string, args ...any) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+// failingJSON fails to be encoded as JSON with `err`.
+type failingJSON struct {
+	err error
+}
+
+func (f failingJSON) MarshalJSON() ([]byte, error) {
+	return nil, f.err
+}
+
+func TestFakeClient(t *testing.T) {
+	type order struct {
+		Amount float64 `json:"amount"`
+		Pair   string  `json:"pair"`
+	}
+
+	errRefused := errors.New("refused")
+
+	type (
+		args struct {
+			stub func(f *FakeClient)
+			call func(ctx context.Context, cli Client) (any, error)
+		}
+
+		want struct {
+			value    any
+			err      error
+			failures int
+		}
+
+		testCase struct {
+			name string
+			args args
+			want want
+		}
+	)
+
+	tests := []testCase{
+		{
+			name: "json",
+			args: args{
+				stub: func(f *FakeClient) {
+					f.On(http.MethodPost, "/users/*/orders").
+						WithQuery("dry", "1").
+						WithHeader("X-Tenant", "acme").
+						WithJSONBody(order{Amount: 1, Pair: "BTC/USDT"}).
+						RespondJSON(http.StatusCreated, order{Amount: 1, Pair: "BTC/USDT"}).
+						Times(1)
+				},
+				call: func(ctx context.Context, cli Client) (any, error) {
+					call := NewCall[order](cli).
+						URL("http://example.com/users/1/orders?dry=1").
+						Method(http.MethodPost).
+						Header("X-Tenant", "acme", true).
+						RawBody([]byte(`{ "pair": "BTC/USDT", "amount": 1.0 }`)).
+						ExpectedStatusCodes(http.StatusCreated).
+						ParseJSON()
+					err := call.Call(ctx)
+					return call.BodyParsed, err
+				},
+			},
+			want: want{value: order{Amount: 1, Pair: "BTC/USDT"}},
+		},
+		{
+			name: "streamed",
+			args: args{
+				stub: func(f *FakeClient) {
+					f.On(http.MethodGet, "/orders").
+						RespondNDJSON(http.StatusOK, order{Amount: 1, Pair: "A"}, order{Amount: 2, Pair: "B"})
+				},
+				call: func(ctx context.Context, cli Client) (any, error) {
+					var orders []order
+					err := NewCall[order](cli).
+						URL("http://example.com/orders").
+						ParseJSONEachRow(func(o order) bool {
+							orders = append(orders, o)
+							return true
+						}).
+						Call(ctx)
+					return orders, err
+				},
+			},
+			want: want{value: []order{{Amount: 1, Pair: "A"}, {Amount: 2, Pair: "B"}}},
+		},
+		{
+			name: "streamed unencodable",
+			args: args{
+				stub: func(f *FakeClient) {
+					f.On(http.MethodGet, "/orders").
+						RespondNDJSON(http.StatusOK, order{Amount: 1, Pair: "A"}, failingJSON{errRefused})
+				},
+				call: func(ctx context.Context, cli Client) (any, error) {
+					return nil, NewCall[any](cli).URL("http://example.com/orders").Call(ctx)
+				},
+			},
+			want: want{err: errRefused},
+		},
+		{
+			name: "endpoint path",
+			args: args{
+				stub: func(f *FakeClient) {
+					f.On(http.MethodGet, "/orders/*").Respond(http.StatusNoContent, nil).Times(1)
+				},
+				call: func(ctx context.Context, cli Client) (any, error) {
+					call := NewCall[any](cli).URI("orders/1")
+					endpoint := NewEndpoint("Orders").Request(BaseURL("http://example.com"))
+					if err := call.CallEndpoint(ctx, endpoint); err != nil {
+						return nil, err
+					}
+					return call.Res.Status(), nil
+				},
+			},
+			want: want{value: http.StatusNoContent},
+		},
+		{
+			name: "in sequence",
+			args: args{
+				stub: func(f *FakeClient) {
+					f.On("", "").Respond(http.StatusServiceUnavailable, nil).Times(1)
+					f.On("", "").Respond(http.StatusOK, []byte("ok"))
+				},
+				call: func(ctx context.Context, cli Client) (any, error) {
+					var statuses []int
+					for i := 0; i < 2; i++ {
+						call := NewCall[any](cli).URL("http://example.com")
+						if err := call.Call(ctx); err != nil {
+							return nil, err
+						}
+						statuses = append(statuses, call.Res.Status())
+					}
+					return statuses, nil
+				},
+			},
+			want: want{value: []int{http.StatusServiceUnavailable, http.StatusOK}},
+		},
+		{
+			name: "failed",
+			args: args{
+				stub: func(f *FakeClient) {
+					f.On(http.MethodGet, "/").Fail(errRefused)
+				},
+				call: func(ctx context.Context, cli Client) (any, error) {
+					return nil, NewCall[any](cli).URL("http://example.com/").Call(ctx)
+				},
+			},
+			want: want{err: errRefused},
+		},
+		{
+			name: "delayed",
+			args: args{
+				stub: func(f *FakeClient) {
+					f.On(http.MethodGet, "/").Delay(time.Minute)
+				},
+				call: func(ctx context.Context, cli Client) (any, error) {
+					ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+					defer cancel()
+					return nil, NewCall[any](cli).URL("http://example.com/").Call(ctx)
+				},
+			},
+			want: want{err: context.DeadlineExceeded},
+		},
+		{
+			name: "unmatched",
+			args: args{
+				stub: func(f *FakeClient) {
+					f.On(http.MethodPost, "/").Times(1)
+					f.On(http.MethodGet, "/").WithQuery("page", "2")
+				},
+				call: func(ctx context.Context, cli Client) (any, error) {
+					return nil, NewCall[any](cli).URL("http://example.com/?page=1").Call(ctx)
+				},
+			},
+			want: want{err: ErrNoStub, failures: 2},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake := NewFakeClient()
+			test.args.stub(fake)
+
+			value, err := test.args.call(context.TODO(), fake)
+			if !errors.Is(err, test.want.err) {
+				t.Fatalf("unexpected error, want %v, have %v", test.want.err, err)
+			}
+
+			if !reflect.DeepEqual(value, test.want.value) {
+				t.Errorf("unexpected value, want %+v, have %+v", test.want.value, value)
+			}
+
+			rt := &recordedT{}
+			fake.AssertExpectations(rt)
+			if len(rt.errors) != test.want.failures {
+				t.Errorf("unexpected failures, want %d, have %q", test.want.failures, rt.errors)
+			}
+
+			for _, req := range fake.Requests() {
+				if req.URL.Host != "example.com" {
+					t.Errorf("unexpected request, have %s %s", req.Method, req.URL)
+				}
+			}
+		})
+	}
+}
+
+// Example_fakeClient demonstrates how to stub responses as sent by servers.
+func Example_fakeClient() {
+	type Order struct {
+		Amount float64 `json:"amount"`
+		Pair   string  `json:"pair"`
+	}
+
+	fake := NewFakeClient()
+	fake.On(http.MethodGet, "/orders/*").
+		RespondJSON(http.StatusOK, Order{Amount: 100.5, Pair: "BTC/USD"})
+
+	call := NewCall[Order](fake).
+		URL("http://example.com/orders/1").
+		ParseJSON().
+		ExpectedStatusCodes(http.StatusOK)
+
+	if err := call.Call(context.Background()); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Order amount: %.1f, Pair: %s\n", call.BodyParsed.Amount, call.BodyParsed.Pair)
+	fmt.Printf("Requests: %d\n", len(fake.Requests()))
+	// Output: Order amount: 100.5, Pair: BTC/USD
+	// Requests: 1
+}
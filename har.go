@@ -0,0 +1,437 @@
+package withttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultHARMaxBodySize caps the bytes of bodies recorded into HAR archives.
+const DefaultHARMaxBodySize = 1 << 20
+
+type (
+	// HARConfig configures how calls are recorded into HAR archives.
+	HARConfig struct {
+		// MaxBodySize caps the bytes of every body recorded, those beyond being left out. Defaults to
+		// DefaultHARMaxBodySize. Negative values leave bodies out altogether.
+		MaxBodySize int
+		// Redaction is the policy URLs, headers and bodies are redacted with. Defaults to
+		// DefaultRedactionPolicy. An empty policy redacts nothing.
+		Redaction *RedactionPolicy
+	}
+
+	// HARRecorder records requests sent through its middleware, and their responses, as entries of a
+	// HAR 1.2 archive, see http://www.softwareishard.com/blog/har-12-spec. Response bodies are
+	// recorded as they are read. It is safe for concurrent use.
+	HARRecorder struct {
+		cfg HARConfig
+
+		mu      sync.Mutex
+		entries []*harRecord
+	}
+
+	// harRecord is an entry being recorded, whose response body may still be read.
+	harRecord struct {
+		mu sync.Mutex
+
+		entry harEntry
+		// received and read tell when responses were received and their bodies read to the end, or
+		// closed, along with the timings of the attempt, if known.
+		received, read time.Time
+		timings        Timings
+		body           *cappedBuffer
+		encoding       string
+	}
+
+	// cappedBuffer keeps up to `max` bytes written to it, while counting them all.
+	cappedBuffer struct {
+		bytes.Buffer
+
+		max int
+		n   int
+	}
+
+	// harBody records response bodies as they are read.
+	harBody struct {
+		io.ReadCloser
+
+		record *harRecord
+	}
+
+	harLog struct {
+		Version string     `json:"version"`
+		Creator harCreator `json:"creator"`
+		Entries []harEntry `json:"entries"`
+	}
+
+	harCreator struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+
+	harEntry struct {
+		StartedDateTime time.Time   `json:"startedDateTime"`
+		Time            float64     `json:"time"`
+		Request         harRequest  `json:"request"`
+		Response        harResponse `json:"response"`
+		Cache           struct{}    `json:"cache"`
+		Timings         harTimings  `json:"timings"`
+		Comment         string      `json:"comment,omitempty"`
+	}
+
+	harRequest struct {
+		Method      string       `json:"method"`
+		URL         string       `json:"url"`
+		HTTPVersion string       `json:"httpVersion"`
+		Cookies     []harNameVal `json:"cookies"`
+		Headers     []harNameVal `json:"headers"`
+		QueryString []harNameVal `json:"queryString"`
+		PostData    *harPostData `json:"postData,omitempty"`
+		HeadersSize int          `json:"headersSize"`
+		BodySize    int          `json:"bodySize"`
+	}
+
+	harPostData struct {
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text"`
+		Comment  string `json:"comment,omitempty"`
+	}
+
+	harResponse struct {
+		Status      int          `json:"status"`
+		StatusText  string       `json:"statusText"`
+		HTTPVersion string       `json:"httpVersion"`
+		Cookies     []harNameVal `json:"cookies"`
+		Headers     []harNameVal `json:"headers"`
+		Content     harContent   `json:"content"`
+		RedirectURL string       `json:"redirectURL"`
+		HeadersSize int          `json:"headersSize"`
+		BodySize    int          `json:"bodySize"`
+	}
+
+	harContent struct {
+		Size     int    `json:"size"`
+		MimeType string `json:"mimeType"`
+		Text     string `json:"text,omitempty"`
+		Encoding string `json:"encoding,omitempty"`
+		Comment  string `json:"comment,omitempty"`
+	}
+
+	harNameVal struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+
+	// harTimings are in milliseconds, -1 meaning they do not apply or are unknown.
+	harTimings struct {
+		Blocked float64 `json:"blocked"`
+		DNS     float64 `json:"dns"`
+		Connect float64 `json:"connect"`
+		Send    float64 `json:"send"`
+		Wait    float64 `json:"wait"`
+		Receive float64 `json:"receive"`
+		SSL     float64 `json:"ssl"`
+	}
+)
+
+// NewHARRecorder creates a recorder, to be attached to clients through its Middleware.
+func NewHARRecorder(cfg HARConfig) *HARRecorder {
+	if cfg.MaxBodySize == 0 {
+		cfg.MaxBodySize = DefaultHARMaxBodySize
+	}
+	if cfg.Redaction == nil {
+		cfg.Redaction = DefaultRedactionPolicy()
+	}
+	return &HARRecorder{cfg: cfg}
+}
+
+// Middleware records every request sent through it, along with its response. Attempts at sending
+// requests are recorded on their own, provided it is a client middleware. Dialing timings are only
+// known for calls, see Timings.
+func (r *HARRecorder) Middleware() Middleware {
+	return MiddlewareFunc(func(ctx context.Context, req Request, next DoFunc) (Response, error) {
+		record := &harRecord{}
+		record.entry.StartedDateTime = time.Now()
+		record.entry.Request = r.request(req)
+		// Requests failing are told by the comment of their entries.
+		record.entry.Response = harResponse{
+			Cookies:     []harNameVal{},
+			Headers:     []harNameVal{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		}
+
+		r.mu.Lock()
+		r.entries = append(r.entries, record)
+		r.mu.Unlock()
+
+		res, err := next(ctx, req)
+
+		record.mu.Lock()
+		defer record.mu.Unlock()
+
+		record.received = time.Now()
+		if recorder := timingsRecorderFrom(ctx); recorder != nil {
+			record.timings = recorder.snapshot()
+		}
+
+		if err != nil {
+			record.entry.Comment = err.Error()
+			return res, err
+		}
+
+		record.entry.Response = r.response(res)
+		record.encoding, _ = res.Header("content-encoding")
+		record.body = &cappedBuffer{max: r.cfg.MaxBodySize}
+
+		if body := res.Body(); body != nil {
+			res.SetBody(&harBody{ReadCloser: body, record: record})
+		}
+
+		return res, err
+	})
+}
+
+// WriteTo writes the archive of the entries recorded so far into `w`, as JSON.
+func (r *HARRecorder) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	records := append([]*harRecord(nil), r.entries...)
+	r.mu.Unlock()
+
+	archive := harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "withttp"},
+		Entries: make([]harEntry, 0, len(records)),
+	}
+
+	for _, record := range records {
+		archive.Entries = append(archive.Entries, record.snapshot(r.cfg))
+	}
+
+	data, err := json.MarshalIndent(struct {
+		Log harLog `json:"log"`
+	}{archive}, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+func (r *HARRecorder) request(req Request) harRequest {
+	policy := r.cfg.Redaction
+	u := req.URL()
+
+	hr := harRequest{
+		Method:      req.Method(),
+		URL:         policy.URL(u),
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []harNameVal{},
+		Headers:     harHeaders(policy, req),
+		QueryString: []harNameVal{},
+		HeadersSize: -1,
+		BodySize:    -1,
+	}
+
+	if redacted, err := u.Parse(hr.URL); err == nil && redacted.RawQuery != "" {
+		for _, param := range strings.Split(redacted.RawQuery, "&") {
+			key, value, _ := strings.Cut(param, "=")
+			key, _ = url.QueryUnescape(key)
+			value, _ = url.QueryUnescape(value)
+			hr.QueryString = append(hr.QueryString, harNameVal{Name: key, Value: value})
+		}
+	}
+
+	mimeType, _ := req.Header("content-type")
+
+	body, ok := RequestPayload(req)
+	switch {
+	case !ok:
+		hr.PostData = &harPostData{MimeType: mimeType, Comment: "streamed body not recorded"}
+	case len(body) > 0:
+		hr.BodySize = len(body)
+		hr.PostData = &harPostData{MimeType: mimeType}
+		text, encoding, comment := harText(policy, body, len(body), r.cfg.MaxBodySize)
+		if StrIsset(encoding) {
+			// Post data has no encoding of its own.
+			comment = strings.TrimPrefix(comment+", "+encoding+" encoded", ", ")
+		}
+		hr.PostData.Text, hr.PostData.Comment = text, comment
+	default:
+		hr.BodySize = 0
+	}
+
+	return hr
+}
+
+func (r *HARRecorder) response(res Response) harResponse {
+	status := res.Status()
+	mimeType, _ := res.Header("content-type")
+	location, _ := res.Header("location")
+
+	return harResponse{
+		Status:      status,
+		StatusText:  http.StatusText(status),
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []harNameVal{},
+		Headers:     harHeaders(r.cfg.Redaction, res),
+		Content:     harContent{MimeType: mimeType},
+		RedirectURL: location,
+		HeadersSize: -1,
+		BodySize:    -1,
+	}
+}
+
+// snapshot returns the entry as recorded so far, its response body decoded as per its
+// Content-Encoding, unless cut short.
+func (h *harRecord) snapshot(cfg HARConfig) harEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	entry := h.entry
+	entry.Timings = harTimings{Blocked: -1, DNS: -1, Connect: -1, Wait: -1, Receive: -1, SSL: -1}
+
+	if h.received.IsZero() {
+		// Still waiting for the response.
+		return entry
+	}
+
+	if h.body != nil {
+		body, size := h.body.Bytes(), h.body.n
+		entry.Response.BodySize = size
+
+		if size == len(body) && StrIsset(h.encoding) {
+			decoded := DecodeContentEncoding(io.NopCloser(bytes.NewReader(body)), h.encoding)
+			if decoded, err := io.ReadAll(decoded); err == nil {
+				body, size = decoded, len(decoded)
+			}
+		}
+
+		content := &entry.Response.Content
+		content.Size = size
+		content.Text, content.Encoding, content.Comment = harText(
+			cfg.Redaction, body, size, cfg.MaxBodySize,
+		)
+	}
+
+	timings, wait := h.timings, h.received.Sub(entry.StartedDateTime)
+
+	if !timings.ConnReused && timings.Connect > 0 {
+		entry.Timings.DNS = harMillis(timings.DNS)
+		// Connecting includes TLS handshakes, as told by SSL.
+		entry.Timings.Connect = harMillis(timings.Connect + timings.TLSHandshake)
+		if timings.TLSHandshake > 0 {
+			entry.Timings.SSL = harMillis(timings.TLSHandshake)
+		}
+		wait -= timings.DNS + timings.Connect + timings.TLSHandshake
+	}
+
+	// Some clients, such as fasthttp, read bodies before returning responses.
+	receive := timings.BodyRead
+	if timings.TTFB > 0 {
+		wait = timings.TTFB
+	} else {
+		wait -= receive
+	}
+
+	entry.Timings.Wait = harMillis(wait)
+
+	end := h.received
+	if !h.read.IsZero() {
+		end = h.read
+		receive += h.read.Sub(h.received)
+	}
+	if !h.read.IsZero() || receive > 0 {
+		entry.Timings.Receive = harMillis(receive)
+	}
+
+	entry.Time = harMillis(end.Sub(entry.StartedDateTime))
+
+	return entry
+}
+
+func (b *harBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+
+	b.record.mu.Lock()
+	_, _ = b.record.body.Write(p[:n])
+	if err == io.EOF && b.record.read.IsZero() {
+		b.record.read = time.Now()
+	}
+	b.record.mu.Unlock()
+
+	return n, err
+}
+
+func (b *harBody) Close() error {
+	b.record.mu.Lock()
+	if b.record.read.IsZero() {
+		b.record.read = time.Now()
+	}
+	b.record.mu.Unlock()
+
+	return b.ReadCloser.Close()
+}
+
+func (w *cappedBuffer) Write(p []byte) (int, error) {
+	w.n += len(p)
+	if room := w.max - w.Len(); room > 0 {
+		if len(p) > room {
+			_, _ = w.Buffer.Write(p[:room])
+		} else {
+			_, _ = w.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+// harText returns `body`, out of `size` bytes, as the text of HAR contents, redacted as per
+// `policy`, capped to `max` bytes and base64 encoded unless UTF-8. Bodies cut short are left out if
+// fields are to be redacted, as they cannot be.
+func harText(policy *RedactionPolicy, body []byte, size, max int) (text, encoding, comment string) {
+	truncated := len(body) < size
+
+	switch {
+	case max < 0:
+		return "", "", "body not recorded"
+	case truncated && len(policy.BodyFields) > 0:
+		return "", "", "body too large to be redacted"
+	case !truncated:
+		body = policy.Body(body)
+	}
+
+	if len(body) > max {
+		body, truncated = body[:max], true
+	}
+
+	if truncated {
+		comment = "body truncated"
+	}
+
+	if utf8.Valid(body) {
+		return string(body), "", comment
+	}
+
+	return base64.StdEncoding.EncodeToString(body), "base64", comment
+}
+
+func harHeaders(policy *RedactionPolicy, h header) []harNameVal {
+	headers := make([]harNameVal, 0)
+	for _, kv := range sortedHeaders(h) {
+		headers = append(headers, harNameVal{Name: kv[0], Value: policy.Header(kv[0], kv[1])})
+	}
+	return headers
+}
+
+func harMillis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,188 @@
+package withttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestHARRecorder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=abc")
+		_, _ = w.Write([]byte(`{"id":1,"token":"abc"}`))
+	}))
+	defer srv.Close()
+
+	type (
+		args struct {
+			cfg  HARConfig
+			url  string
+			body string
+		}
+
+		want struct {
+			request  harPostData
+			response harContent
+			status   int
+			cookie   string
+			comment  bool
+		}
+
+		testCase struct {
+			name string
+			args args
+			want want
+		}
+	)
+
+	tests := []testCase{
+		{
+			name: "recorded",
+			args: args{url: srv.URL + "/items?token=abc&page=2"},
+			want: want{
+				request: harPostData{MimeType: ContentTypeJSON, Text: `{"password":"[REDACTED]"}`},
+				response: harContent{
+					Size:     22,
+					MimeType: ContentTypeJSON,
+					Text:     `{"id":1,"token":"[REDACTED]"}`,
+				},
+				status: http.StatusOK,
+				cookie: DefaultRedactionMask,
+			},
+		},
+		{
+			name: "compacted",
+			args: args{url: srv.URL, body: `{ "password": "hunter2" }`},
+			want: want{
+				request: harPostData{MimeType: ContentTypeJSON, Text: `{"password":"[REDACTED]"}`},
+				response: harContent{
+					Size:     22,
+					MimeType: ContentTypeJSON,
+					Text:     `{"id":1,"token":"[REDACTED]"}`,
+				},
+				status: http.StatusOK,
+				cookie: DefaultRedactionMask,
+			},
+		},
+		{
+			name: "truncated",
+			args: args{cfg: HARConfig{MaxBodySize: 6, Redaction: &RedactionPolicy{}}, url: srv.URL},
+			want: want{
+				request: harPostData{
+					MimeType: ContentTypeJSON,
+					Text:     `{"pass`,
+					Comment:  "body truncated",
+				},
+				response: harContent{
+					Size:     22,
+					MimeType: ContentTypeJSON,
+					Comment:  "body truncated",
+					Text:     `{"id":`,
+				},
+				status: http.StatusOK,
+				cookie: "session=abc",
+			},
+		},
+		{
+			name: "too large to be redacted",
+			args: args{cfg: HARConfig{MaxBodySize: 6}, url: srv.URL},
+			want: want{
+				request: harPostData{MimeType: ContentTypeJSON, Text: `{"pass`, Comment: "body truncated"},
+				response: harContent{
+					Size:     22,
+					MimeType: ContentTypeJSON,
+					Comment:  "body too large to be redacted",
+				},
+				status: http.StatusOK,
+				cookie: DefaultRedactionMask,
+			},
+		},
+		{
+			name: "failed",
+			args: args{url: "http://127.0.0.1:1"},
+			want: want{
+				request: harPostData{MimeType: ContentTypeJSON, Text: `{"password":"[REDACTED]"}`},
+				comment: true,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		for name, cli := range map[string]Client{
+			"net/http": NetHttpClient(&http.Client{Transport: &http.Transport{}}),
+			"fasthttp": FasthttpTimedClient(&fasthttp.Client{}),
+		} {
+			t.Run(test.name+" "+name, func(t *testing.T) {
+				recorder := NewHARRecorder(test.args.cfg)
+				cli := WithMiddlewares(cli, recorder.Middleware())
+
+				body := test.args.body
+				if body == "" {
+					body = `{"password":"hunter2"}`
+				}
+
+				_ = NewCall[any](cli).
+					URL(test.args.url).
+					Method(http.MethodPost).
+					ContentType(ContentTypeJSON).
+					RawBody([]byte(body)).
+					ReadBody().
+					Call(context.TODO())
+
+				buf := new(bytes.Buffer)
+				if _, err := recorder.WriteTo(buf); err != nil {
+					t.Fatalf("unexpected error, want none, have %v", err)
+				}
+
+				var archive struct {
+					Log harLog `json:"log"`
+				}
+				if err := json.Unmarshal(buf.Bytes(), &archive); err != nil {
+					t.Fatalf("unexpected error, want none, have %v", err)
+				}
+
+				if n := len(archive.Log.Entries); n != 1 {
+					t.Fatalf("unexpected entries, want 1, have %d", n)
+				}
+
+				entry := archive.Log.Entries[0]
+
+				if have := *entry.Request.PostData; have != test.want.request {
+					t.Errorf("unexpected request body, want %+v, have %+v", test.want.request, have)
+				}
+
+				if have := entry.Response.Content; have != test.want.response {
+					t.Errorf("unexpected response body, want %+v, have %+v", test.want.response, have)
+				}
+
+				if have := entry.Response.Status; have != test.want.status {
+					t.Errorf("unexpected status, want %d, have %d", test.want.status, have)
+				}
+
+				if have := entry.Comment != ""; have != test.want.comment {
+					t.Errorf("unexpected comment, want %t, have '%s'", test.want.comment, entry.Comment)
+				}
+
+				if test.want.status == 0 {
+					return
+				}
+
+				for _, h := range entry.Response.Headers {
+					if h.Name == "Set-Cookie" && h.Value != test.want.cookie {
+						t.Errorf("unexpected cookie, want '%s', have '%s'", test.want.cookie, h.Value)
+					}
+				}
+
+				if entry.Timings.Connect <= 0 || entry.Timings.Wait <= 0 || entry.Time <= 0 {
+					t.Errorf("unexpected timings, have %+v", entry.Timings)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package withttp
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// StatusClassError labels requests which got no response.
+const StatusClassError = "error"
+
+type (
+	// Metrics records client metrics, such as through Prometheus, see package promwithttp.
+	Metrics interface {
+		// Started is called right before requests are sent, which are in flight until finished.
+		Started(labels MetricLabels)
+		// Finished is called once responses are received, or sending requests failed, `took`
+		// long, as the status class is known.
+		Finished(labels MetricLabels, took time.Duration)
+		// Transferred is called once what was sent and received is known, which is when calls are
+		// done with for the last attempt at sending their requests, and right away otherwise.
+		Transferred(labels MetricLabels, transfer Transfer)
+	}
+
+	// MetricLabels tell requests apart.
+	MetricLabels struct {
+		// Endpoint is the name of the endpoint called, if any.
+		Endpoint string
+		Method   string
+		// StatusClass is the class of the response status, such as 2xx, or StatusClassError if no
+		// response was received. Empty until finished.
+		StatusClass string
+	}
+
+	// Transfer tells the sizes of bodies sent and received, the amount of items streamed and the
+	// timings of the attempt, which are only known for calls.
+	Transfer struct {
+		// RequestBytes is -1 if unknown, as for streamed bodies sent without calls.
+		RequestBytes  int64
+		ResponseBytes int64
+		ItemsSent     int64
+		ItemsReceived int64
+		Timings       Timings
+	}
+
+	callStatsKey struct{}
+
+	// countedRange counts the items ranged over.
+	countedRange[T any] struct {
+		rangeable[T]
+
+		n *atomic.Int64
+	}
+
+	// countedStream counts the items read successfully.
+	countedStream[T any] struct {
+		Stream[T]
+
+		n *atomic.Int64
+	}
+)
+
+// MetricsMiddleware records metrics of every request sent through clients. Calls sending requests
+// through them tell the endpoint called, the size of streamed bodies and the amount of items
+// streamed, which are otherwise unknown.
+func MetricsMiddleware(m Metrics) Middleware {
+	// Tells apart reports of this middleware from those of others in the same call.
+	key := new(byte)
+
+	return MiddlewareFunc(func(ctx context.Context, req Request, next DoFunc) (Response, error) {
+		labels := MetricLabels{Endpoint: EndpointName(ctx), Method: req.Method()}
+		m.Started(labels)
+
+		start := time.Now()
+		res, err := next(ctx, req)
+
+		labels.StatusClass = statusClass(res, err)
+		m.Finished(labels, time.Since(start))
+
+		transfer := Transfer{RequestBytes: -1}
+		if payload, ok := RequestPayload(req); ok {
+			transfer.RequestBytes = int64(len(payload))
+		}
+
+		stats, ok := ctx.Value(callStatsKey{}).(*callStats)
+		if !ok || err != nil {
+			m.Transferred(labels, transfer)
+			return res, err
+		}
+
+		transfer.Timings = stats.timings.snapshot()
+
+		stats.report(key, func(last bool) {
+			if last {
+				transfer.Timings = stats.timings.snapshot()
+				if transfer.RequestBytes < 0 {
+					transfer.RequestBytes = stats.bytesOut.Load()
+				}
+				transfer.ResponseBytes = stats.bytesIn.Load()
+				transfer.ItemsSent = stats.itemsSent.Load()
+				transfer.ItemsReceived = stats.itemsReceived.Load()
+			}
+			m.Transferred(labels, transfer)
+		})
+
+		return res, err
+	})
+}
+
+// EndpointName returns the name of the endpoint called by the call executing, if any, as seen by
+// middlewares.
+func EndpointName(ctx context.Context) string {
+	if stats, ok := ctx.Value(callStatsKey{}).(*callStats); ok {
+		return stats.endpoint
+	}
+	return ""
+}
+
+func statusClass(res Response, err error) string {
+	if err != nil || res == nil {
+		return StatusClassError
+	}
+	return strconv.Itoa(res.Status()/100) + "xx"
+}
+
+// report defers `fn` until the call is done with, calling the one deferred under the same `key`,
+// if any, right away, as its attempt was not the last one.
+func (s *callStats) report(key any, fn func(last bool)) {
+	s.mu.Lock()
+	prev := s.reports[key]
+	if s.reports == nil {
+		s.reports = make(map[any]func(bool))
+	}
+	s.reports[key] = fn
+	s.mu.Unlock()
+
+	if prev != nil {
+		prev(false)
+	}
+}
+
+// flush calls the reports deferred, once the call is done with.
+func (s *callStats) flush() {
+	s.mu.Lock()
+	reports := s.reports
+	s.reports = nil
+	s.mu.Unlock()
+
+	for _, fn := range reports {
+		fn(true)
+	}
+}
+
+func (r countedRange[T]) Range(fn func(int, T) bool) {
+	r.rangeable.Range(func(i int, x T) bool {
+		r.n.Add(1)
+		return fn(i, x)
+	})
+}
+
+func (s countedStream[T]) Next(ctx context.Context) bool {
+	if !s.Stream.Next(ctx) {
+		return false
+	}
+
+	if s.Err() == nil {
+		s.n.Add(1)
+	}
+
+	return true
+}
+
+// countedStreams counts the items received through streams of `factory`.
+func (c *Call[T]) countedStreams(factory StreamFactory[T]) StreamFactory[T] {
+	n := &c.stats.itemsReceived
+
+	return StreamFactoryFunc[T](func(r io.Reader) Stream[T] {
+		return countedStream[T]{Stream: factory.Get(r), n: n}
+	})
+}
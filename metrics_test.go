@@ -0,0 +1,159 @@
+package withttp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type recordedMetrics struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (m *recordedMetrics) record(format string, args ...any) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, fmt.Sprintf(format, args...))
+}
+
+func (m *recordedMetrics) Started(l MetricLabels) {
+	m.record("started %s %s", l.Endpoint, l.Method)
+}
+
+func (m *recordedMetrics) Finished(l MetricLabels, _ time.Duration) {
+	m.record("finished %s %s %s", l.Endpoint, l.Method, l.StatusClass)
+}
+
+func (m *recordedMetrics) Transferred(l MetricLabels, t Transfer) {
+	// Timings are tested on their own, mocked calls taking no time.
+	t.Timings = Timings{}
+	m.record("transferred %s %s %s %+v", l.Endpoint, l.Method, l.StatusClass, t)
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	type item struct {
+		ID int `json:"id"`
+	}
+
+	errRefused := errors.New("refused")
+
+	zeroTimings := fmt.Sprintf("%+v", Timings{})
+
+	respond := func(status int, body string) Middleware {
+		return MiddlewareFunc(func(ctx context.Context, req Request, next DoFunc) (Response, error) {
+			res, err := next(ctx, req)
+			res.SetStatus(status)
+			res.SetBody(io.NopCloser(strings.NewReader(body)))
+			return res, err
+		})
+	}
+
+	type (
+		args struct {
+			inner Middleware
+			call  func(cli Client) error
+		}
+
+		want struct {
+			events []string
+			err    error
+		}
+
+		testCase struct {
+			name string
+			args args
+			want want
+		}
+	)
+
+	tests := []testCase{
+		{
+			name: "streamed",
+			args: args{
+				inner: respond(http.StatusOK, "{\"id\":1}\n{\"id\":2}\n"),
+				call: func(cli Client) error {
+					return NewCall[item](cli).
+						Method(http.MethodPost).
+						ContentType(ContentTypeJSONEachRow).
+						RequestStreamBody(RequestStreamBody[item, int](Slice[int]{1, 2, 3})).
+						ParseJSONEachRow(func(item) bool { return true }).
+						CallEndpoint(context.TODO(), NewEndpoint("items"))
+				},
+			},
+			want: want{
+				events: []string{
+					"started items POST",
+					"finished items POST 2xx",
+					"transferred items POST 2xx " +
+						"{RequestBytes:6 ResponseBytes:18 ItemsSent:3 ItemsReceived:2 Timings:" + zeroTimings + "}",
+				},
+			},
+		},
+		{
+			name: "failed",
+			args: args{
+				inner: MiddlewareFunc(func(context.Context, Request, DoFunc) (Response, error) {
+					return nil, errRefused
+				}),
+				call: func(cli Client) error {
+					return NewCall[item](cli).
+						Method(http.MethodPut).
+						RawBody([]byte(`{"id":1}`)).
+						CallEndpoint(context.TODO(), NewEndpoint("item"))
+				},
+			},
+			want: want{
+				events: []string{
+					"started item PUT",
+					"finished item PUT error",
+					"transferred item PUT error " +
+						"{RequestBytes:8 ResponseBytes:0 ItemsSent:0 ItemsReceived:0 Timings:" + zeroTimings + "}",
+				},
+				err: errRefused,
+			},
+		},
+		{
+			name: "without calls",
+			args: args{
+				inner: respond(http.StatusNotFound, ""),
+				call: func(cli Client) error {
+					req, _ := cli.Request(context.TODO())
+					_, err := cli.Do(context.TODO(), req)
+					return err
+				},
+			},
+			want: want{
+				events: []string{
+					"started  GET",
+					"finished  GET 4xx",
+					"transferred  GET 4xx " +
+						"{RequestBytes:0 ResponseBytes:0 ItemsSent:0 ItemsReceived:0 Timings:" + zeroTimings + "}",
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			metrics := &recordedMetrics{}
+			cli := WithMiddlewares(NewMockHttpClientAdapter(), MetricsMiddleware(metrics), test.args.inner)
+
+			if err := test.args.call(cli); !errors.Is(err, test.want.err) {
+				t.Errorf("unexpected error, want %v, have %v", test.want.err, err)
+			}
+
+			have, want := strings.Join(metrics.events, "\n"), strings.Join(test.want.events, "\n")
+			if have != want {
+				t.Errorf("unexpected metrics, want:\n%s\nhave:\n%s", want, have)
+			}
+		})
+	}
+}
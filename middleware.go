@@ -0,0 +1,44 @@
+package withttp
+
+import "context"
+
+type (
+	// Middleware decorates a client, usually to act on requests right before they are sent or on
+	// responses as soon as they are received.
+	Middleware func(next Client) Client
+
+	// DoFunc sends a request, as Client.Do does.
+	DoFunc func(ctx context.Context, req Request) (Response, error)
+
+	doClient struct {
+		Client
+
+		do DoFunc
+	}
+)
+
+func (c doClient) Do(ctx context.Context, req Request) (Response, error) {
+	return c.do(ctx, req)
+}
+
+// MiddlewareFunc builds a middleware which sends requests through `fn`, which is expected to call
+// `next` to actually send them.
+func MiddlewareFunc(fn func(ctx context.Context, req Request, next DoFunc) (Response, error)) Middleware {
+	return func(next Client) Client {
+		return doClient{
+			Client: next,
+			do: func(ctx context.Context, req Request) (Response, error) {
+				return fn(ctx, req, next.Do)
+			},
+		}
+	}
+}
+
+// WithMiddlewares decorates `cli` with `mws`, the first of them being the outermost one, hence the
+// first to see requests.
+func WithMiddlewares(cli Client, mws ...Middleware) Client {
+	for i := len(mws) - 1; i >= 0; i-- {
+		cli = mws[i](cli)
+	}
+	return cli
+}
@@ -0,0 +1,170 @@
+// Package oauth2 obtains OAuth2 access tokens, as per RFC 6749, through the client credentials and
+// refresh token grants, sending token requests with withttp itself. Tokens are supplied as
+// withttp.TokenSource, hence plug into calls with Call.TokenAuth, into endpoints with
+// withttp.WithTokenSource and into clients with withttp.TokenSourceMiddleware. Calls and clients
+// force a token refresh and retry once when the server replies with 401 Unauthorized.
+package oauth2
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/sonirico/withttp"
+)
+
+const (
+	// AuthStyleHeader sends client credentials with HTTP Basic authentication.
+	AuthStyleHeader AuthStyle = iota
+	// AuthStyleParams sends client credentials as client_id and client_secret form parameters.
+	AuthStyleParams
+)
+
+var (
+	ErrNoAccessToken = errors.New("no access token")
+)
+
+type (
+	// AuthStyle tells how client credentials are sent to the token endpoint.
+	AuthStyle int
+
+	Config struct {
+		TokenURL     string
+		ClientID     string
+		ClientSecret string
+		Scopes       []string
+		AuthStyle    AuthStyle
+		// Client sends token requests. Defaults to withttp.NetHttp().
+		Client withttp.Client
+		// ExpirySkew is how long before their expiry tokens are refreshed. Defaults to
+		// withttp.DefaultTokenExpiryLeeway.
+		ExpirySkew time.Duration
+	}
+
+	// Error is the error response of the token endpoint, see RFC 6749, section 5.2.
+	Error struct {
+		StatusCode  int
+		Code        string
+		Description string
+	}
+
+	tokenResponse struct {
+		AccessToken      string `json:"access_token"`
+		TokenType        string `json:"token_type"`
+		ExpiresIn        int64  `json:"expires_in"`
+		RefreshToken     string `json:"refresh_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+
+	// grant requests tokens from the token endpoint. Refresh token grants keep the latest refresh
+	// token handed over by the server, which may rotate it. Not safe for concurrent use on its own,
+	// hence always wrapped by a withttp.CachedTokenSource.
+	grant struct {
+		cfg          Config
+		grantType    string
+		refreshToken string
+	}
+)
+
+func (e *Error) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("oauth2: %s: %s (status %d)", e.Code, e.Description, e.StatusCode)
+	}
+	return fmt.Sprintf("oauth2: %s (status %d)", e.Code, e.StatusCode)
+}
+
+// ClientCredentials supplies tokens obtained with the client credentials grant, cached until they
+// are about to expire.
+func (c Config) ClientCredentials() *withttp.CachedTokenSource {
+	return c.cached(&grant{cfg: c, grantType: "client_credentials"})
+}
+
+// RefreshToken supplies tokens obtained by exchanging `refreshToken`, cached until they are about to
+// expire. Refresh tokens rotated by the server are used from then on.
+func (c Config) RefreshToken(refreshToken string) *withttp.CachedTokenSource {
+	return c.cached(&grant{cfg: c, grantType: "refresh_token", refreshToken: refreshToken})
+}
+
+func (c Config) cached(g *grant) *withttp.CachedTokenSource {
+	skew := c.ExpirySkew
+	if skew == 0 {
+		skew = withttp.DefaultTokenExpiryLeeway
+	}
+	return withttp.NewCachedTokenSource(g, skew)
+}
+
+func (g *grant) Token(ctx context.Context) (withttp.Token, error) {
+	form := url.Values{"grant_type": {g.grantType}}
+
+	if g.grantType == "refresh_token" {
+		form.Set("refresh_token", g.refreshToken)
+	}
+
+	if len(g.cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(g.cfg.Scopes, " "))
+	}
+
+	client := g.cfg.Client
+	if client == nil {
+		client = withttp.NetHttp()
+	}
+
+	call := withttp.NewCall[tokenResponse](client).
+		URL(g.cfg.TokenURL).
+		Method(http.MethodPost).
+		Header("accept", "application/json", true)
+
+	if g.cfg.AuthStyle == AuthStyleParams {
+		form.Set("client_id", g.cfg.ClientID)
+		form.Set("client_secret", g.cfg.ClientSecret)
+	} else {
+		// RFC 6749, section 2.3.1, mandates to form-encode credentials first.
+		call = call.BasicAuth(url.QueryEscape(g.cfg.ClientID), url.QueryEscape(g.cfg.ClientSecret))
+	}
+
+	issuedAt := time.Now()
+
+	err := call.
+		ContentType("application/x-www-form-urlencoded").
+		RawBody([]byte(form.Encode())).
+		ParseJSON().
+		Call(ctx)
+
+	if call.Res != nil && call.Res.Status() != http.StatusOK {
+		return withttp.Token{}, &Error{
+			StatusCode:  call.Res.Status(),
+			Code:        call.BodyParsed.Error,
+			Description: call.BodyParsed.ErrorDescription,
+		}
+	}
+
+	if err != nil {
+		return withttp.Token{}, errors.Wrap(err, "oauth2: token request")
+	}
+
+	res := call.BodyParsed
+	if res.AccessToken == "" {
+		return withttp.Token{}, ErrNoAccessToken
+	}
+
+	if res.RefreshToken != "" {
+		g.refreshToken = res.RefreshToken
+	}
+
+	token := withttp.Token{Value: res.AccessToken, Type: res.TokenType}
+	if strings.EqualFold(token.Type, "bearer") {
+		token.Type = "Bearer"
+	}
+
+	if res.ExpiresIn > 0 {
+		token.Expiry = issuedAt.Add(time.Duration(res.ExpiresIn) * time.Second)
+	}
+
+	return token, nil
+}
@@ -0,0 +1,213 @@
+package oauth2
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/sonirico/withttp"
+)
+
+// newTokenServer issues tokens numbered after the amount of token requests it got so far.
+func newTokenServer(t *testing.T, issued *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("unexpected error, want none, have %v", err)
+		}
+
+		id, secret, ok := r.BasicAuth()
+		if !ok {
+			id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+		}
+
+		w.Header().Set("content-type", "application/json")
+
+		if id != "client" || secret != "s3cr3t" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid_client","error_description":"who are you?"}`))
+			return
+		}
+
+		switch r.PostForm.Get("grant_type") {
+		case "client_credentials":
+			if scope := r.PostForm.Get("scope"); scope != "read write" {
+				t.Errorf("unexpected scope, want 'read write', have '%s'", scope)
+			}
+		case "refresh_token":
+			n := atomic.LoadInt32(issued)
+			if want := fmt.Sprintf("refresh-%d", n); r.PostForm.Get("refresh_token") != want {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+				return
+			}
+		}
+
+		n := atomic.AddInt32(issued, 1)
+		_, _ = fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"bearer","expires_in":3600,"refresh_token":"refresh-%d"}`, n, n)
+	}))
+}
+
+func TestConfig_ClientCredentials(t *testing.T) {
+	type (
+		args struct {
+			style  AuthStyle
+			secret string
+		}
+
+		want struct {
+			token string
+			err   error
+		}
+
+		testCase struct {
+			name string
+			args args
+			want want
+		}
+	)
+
+	tests := []testCase{
+		{
+			name: "credentials in header",
+			args: args{style: AuthStyleHeader, secret: "s3cr3t"},
+			want: want{token: "Bearer token-1"},
+		},
+		{
+			name: "credentials in params",
+			args: args{style: AuthStyleParams, secret: "s3cr3t"},
+			want: want{token: "Bearer token-1"},
+		},
+		{
+			name: "invalid credentials",
+			args: args{style: AuthStyleHeader, secret: "guess"},
+			want: want{err: &Error{StatusCode: http.StatusUnauthorized, Code: "invalid_client"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var issued int32
+			srv := newTokenServer(t, &issued)
+			defer srv.Close()
+
+			ts := Config{
+				TokenURL:     srv.URL,
+				ClientID:     "client",
+				ClientSecret: test.args.secret,
+				Scopes:       []string{"read", "write"},
+				AuthStyle:    test.args.style,
+			}.ClientCredentials()
+
+			for i := 0; i < 3; i++ {
+				token, err := ts.Token(context.TODO())
+
+				var oauthErr *Error
+				if test.want.err != nil {
+					if !errors.As(err, &oauthErr) || oauthErr.Code != "invalid_client" ||
+						oauthErr.StatusCode != http.StatusUnauthorized {
+						t.Fatalf("unexpected error, want %v, have %v", test.want.err, err)
+					}
+					return
+				}
+
+				if err != nil {
+					t.Fatalf("unexpected error, want none, have %v", err)
+				}
+
+				if token.Header() != test.want.token {
+					t.Errorf("unexpected token, want '%s', have '%s'", test.want.token, token.Header())
+				}
+			}
+
+			if issued != 1 {
+				t.Errorf("unexpected token requests, want 1, have %d", issued)
+			}
+		})
+	}
+}
+
+func TestConfig_RefreshToken(t *testing.T) {
+	var issued int32
+	srv := newTokenServer(t, &issued)
+	defer srv.Close()
+
+	ts := Config{TokenURL: srv.URL, ClientID: "client", ClientSecret: "s3cr3t"}.
+		RefreshToken("refresh-0")
+
+	for i, want := range []string{"token-1", "token-2", "token-3"} {
+		if i > 0 {
+			ts.Invalidate()
+		}
+
+		// Every refresh must use the refresh token rotated by the previous one.
+		token, err := ts.Token(context.TODO())
+		if err != nil {
+			t.Fatalf("unexpected error, want none, have %v", err)
+		}
+
+		if token.Value != want {
+			t.Errorf("unexpected token, want '%s', have '%s'", want, token.Value)
+		}
+	}
+}
+
+func TestAuth_RetriesOnUnauthorized(t *testing.T) {
+	var issued int32
+	tokenSrv := newTokenServer(t, &issued)
+	defer tokenSrv.Close()
+
+	var attempts int32
+	apiSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+
+		// First token gets revoked before its expiry.
+		if r.Header.Get("authorization") != "Bearer token-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if body, _ := io.ReadAll(r.Body); string(body) != `{"name":"I am a payload"}` {
+			t.Errorf("unexpected body on attempt %d, have '%s'", attempts, body)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer apiSrv.Close()
+
+	cfg := Config{
+		TokenURL:     tokenSrv.URL,
+		ClientID:     "client",
+		ClientSecret: "s3cr3t",
+		Scopes:       []string{"read", "write"},
+	}
+
+	for name, cli := range map[string]withttp.Client{
+		"net/http": withttp.NetHttpClient(&http.Client{}),
+		"fasthttp": withttp.Fasthttp(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			atomic.StoreInt32(&issued, 0)
+			atomic.StoreInt32(&attempts, 0)
+
+			call := withttp.NewCall[any](cli).
+				URL(apiSrv.URL).
+				Method(http.MethodPost).
+				RawBody([]byte(`{"name":"I am a payload"}`)).
+				TokenAuth(cfg.ClientCredentials()).
+				ExpectedStatusCodes(http.StatusNoContent)
+
+			if err := call.Call(context.TODO()); err != nil {
+				t.Fatalf("unexpected error, want none, have %v", err)
+			}
+
+			if attempts != 2 || issued != 2 {
+				t.Errorf("unexpected attempts and tokens, want 2 and 2, have %d and %d", attempts, issued)
+			}
+		})
+	}
+}
@@ -0,0 +1,306 @@
+// Package otelwithttp traces withttp calls with OpenTelemetry, as client spans following the HTTP
+// semantic conventions, named after the endpoint called, if any, or else after the method. The
+// trace context is propagated to servers through request headers, W3C traceparent by default, on
+// every attempt at sending requests, being retries recorded as span events, as is the progress of
+// streamed bodies. Plugs into calls with withttp.Call.WithTracer:
+//
+//	call := withttp.NewCall[any](withttp.Fasthttp()).WithTracer(otelwithttp.Tracer())
+package otelwithttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/sonirico/withttp"
+)
+
+const (
+	// ScopeName is the instrumentation scope of spans.
+	ScopeName = "github.com/sonirico/withttp/otelwithttp"
+
+	// DefaultProgressInterval is the amount of bytes sent or received between progress events.
+	DefaultProgressInterval = 1 << 20
+
+	// EventRetry is recorded as requests are sent again, such as after a 401 Unauthorized.
+	EventRetry = "retry"
+	// EventRequestProgress is recorded as streamed request bodies are sent.
+	EventRequestProgress = "request.progress"
+	// EventResponseProgress is recorded as response bodies are read.
+	EventResponseProgress = "response.progress"
+
+	// BytesKey is the amount of bytes sent or received so far, as of progress events.
+	BytesKey = attribute.Key("bytes")
+)
+
+type (
+	Option func(*tracer)
+
+	tracer struct {
+		provider         trace.TracerProvider
+		propagator       propagation.TextMapPropagator
+		redaction        *withttp.RedactionPolicy
+		progressInterval int
+
+		tracer trace.Tracer
+	}
+
+	// callTrace records a single execution of a call into its span. Bodies may be streamed from
+	// other goroutines, hence the lock.
+	callTrace struct {
+		*tracer
+
+		span     trace.Span
+		endpoint string
+
+		mu       sync.Mutex
+		attempts int
+		sent     progress
+		received progress
+	}
+
+	// progress counts bytes, telling whether a progress event is due.
+	progress struct {
+		n, reported int
+	}
+
+	// headerCarrier propagates trace contexts through request headers.
+	headerCarrier struct {
+		req withttp.Request
+	}
+)
+
+// WithTracerProvider creates spans with `provider` instead of the global one.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(t *tracer) {
+		t.provider = provider
+	}
+}
+
+// WithPropagators injects trace contexts with `propagator` instead of the global one.
+func WithPropagators(propagator propagation.TextMapPropagator) Option {
+	return func(t *tracer) {
+		t.propagator = propagator
+	}
+}
+
+// WithRedactionPolicy masks query string parameters of url.full as told by `policy` instead of
+// withttp.DefaultRedactionPolicy.
+func WithRedactionPolicy(policy withttp.RedactionPolicy) Option {
+	return func(t *tracer) {
+		t.redaction = &policy
+	}
+}
+
+// WithProgressInterval records progress events every `n` bytes sent or received, if positive, or
+// never otherwise.
+func WithProgressInterval(n int) Option {
+	return func(t *tracer) {
+		t.progressInterval = n
+	}
+}
+
+// Tracer creates a withttp.CallTracer recording calls as OpenTelemetry spans.
+func Tracer(opts ...Option) withttp.CallTracer {
+	t := &tracer{
+		provider:         otel.GetTracerProvider(),
+		propagator:       otel.GetTextMapPropagator(),
+		redaction:        withttp.DefaultRedactionPolicy(),
+		progressInterval: DefaultProgressInterval,
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	t.tracer = t.provider.Tracer(ScopeName)
+	return t
+}
+
+func (t *tracer) StartCall(ctx context.Context, endpoint string) (context.Context, withttp.CallTrace) {
+	name := endpoint
+	if name == "" {
+		// Renamed after the method once known.
+		name = "HTTP"
+	}
+
+	ctx, span := t.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+
+	return ctx, &callTrace{tracer: t, span: span, endpoint: endpoint}
+}
+
+func (t *callTrace) Attempt(ctx context.Context, req withttp.Request, attempt int) {
+	t.mu.Lock()
+	t.attempts = attempt
+	t.mu.Unlock()
+
+	if attempt > 1 {
+		t.span.AddEvent(EventRetry, trace.WithAttributes(semconv.HTTPRequestResendCount(attempt-1)))
+	}
+
+	t.propagator.Inject(ctx, headerCarrier{req: req})
+}
+
+func (t *callTrace) Sent(n int) {
+	t.mu.Lock()
+	due, total := t.sent.add(n, t.progressInterval)
+	t.mu.Unlock()
+
+	if due {
+		t.span.AddEvent(EventRequestProgress, trace.WithAttributes(BytesKey.Int(total)))
+	}
+}
+
+func (t *callTrace) Received(n int) {
+	t.mu.Lock()
+	due, total := t.received.add(n, t.progressInterval)
+	t.mu.Unlock()
+
+	if due {
+		t.span.AddEvent(EventResponseProgress, trace.WithAttributes(BytesKey.Int(total)))
+	}
+}
+
+func (t *callTrace) End(req withttp.Request, res withttp.Response, err error) {
+	defer t.span.End()
+
+	t.mu.Lock()
+	attempts, sent, received := t.attempts, t.sent.n, t.received.n
+	t.mu.Unlock()
+
+	if req != nil {
+		t.span.SetAttributes(t.requestAttributes(req)...)
+
+		if t.endpoint == "" {
+			t.span.SetName(req.Method())
+		}
+
+		if payload, ok := withttp.RequestPayload(req); ok {
+			sent = len(payload)
+		}
+		if sent > 0 {
+			t.span.SetAttributes(semconv.HTTPRequestBodySize(sent))
+		}
+	}
+
+	if attempts > 1 {
+		t.span.SetAttributes(semconv.HTTPRequestResendCount(attempts - 1))
+	}
+
+	status := 0
+	if res != nil {
+		status = res.Status()
+		t.span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+	}
+
+	if received > 0 {
+		t.span.SetAttributes(semconv.HTTPResponseBodySize(received))
+	}
+
+	switch {
+	case err != nil:
+		t.span.RecordError(err)
+		t.span.SetStatus(codes.Error, err.Error())
+		t.span.SetAttributes(semconv.ErrorTypeKey.String(errorType(err, status)))
+	case status >= http.StatusBadRequest:
+		t.span.SetStatus(codes.Error, "")
+		t.span.SetAttributes(semconv.ErrorTypeKey.String(strconv.Itoa(status)))
+	}
+}
+
+func (t *callTrace) requestAttributes(req withttp.Request) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{method(req.Method())}
+
+	u := req.URL()
+	if u == nil {
+		return attrs
+	}
+
+	redacted := *u
+	redacted.User = nil
+	attrs = append(attrs, semconv.URLFull(t.redaction.URL(&redacted)))
+
+	if host := u.Hostname(); host != "" {
+		attrs = append(attrs, semconv.ServerAddress(host))
+	}
+
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		port, err = net.LookupPort("tcp", u.Scheme)
+	}
+	if err == nil {
+		attrs = append(attrs, semconv.ServerPort(port))
+	}
+
+	return attrs
+}
+
+// add counts `n` bytes, telling whether another `interval` bytes went by since last reported.
+func (p *progress) add(n, interval int) (bool, int) {
+	p.n += n
+
+	if interval <= 0 || p.n-p.reported < interval {
+		return false, p.n
+	}
+
+	p.reported = p.n
+	return true, p.n
+}
+
+func (c headerCarrier) Get(key string) string {
+	value, _ := c.req.Header(key)
+	return value
+}
+
+func (c headerCarrier) Set(key, value string) {
+	c.req.SetHeader(key, value)
+}
+
+func (c headerCarrier) Keys() []string {
+	var keys []string
+	c.req.RangeHeaders(func(key, _ string) {
+		keys = append(keys, key)
+	})
+	return keys
+}
+
+// method returns the http.request.method attribute, being methods other than the well known ones
+// reported as _OTHER.
+func method(m string) attribute.KeyValue {
+	switch m {
+	case "":
+		return semconv.HTTPRequestMethodGet
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return semconv.HTTPRequestMethodKey.String(m)
+	default:
+		return semconv.HTTPRequestMethodOther
+	}
+}
+
+// errorType returns the status code of unexpected responses, or the type of the innermost error
+// otherwise.
+func errorType(err error, status int) string {
+	if status >= http.StatusBadRequest {
+		return strconv.Itoa(status)
+	}
+
+	for {
+		inner := errors.Unwrap(err)
+		if inner == nil {
+			return fmt.Sprintf("%T", err)
+		}
+		err = inner
+	}
+}
@@ -0,0 +1,230 @@
+package otelwithttp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/sonirico/withttp"
+)
+
+func TestTracer(t *testing.T) {
+	var (
+		mu           sync.Mutex
+		traceparents []string
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		traceparents = append(traceparents, r.Header.Get("traceparent"))
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case "/digest":
+			if r.Header.Get("authorization") == "" {
+				w.Header().Set("www-authenticate", `Digest realm="test", qop="auth", nonce="abc"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		case "/fail":
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"id":1}`))
+	}))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL)
+	port, _ := strconv.Atoi(u.Port())
+
+	type (
+		args struct {
+			endpoint  *withttp.Endpoint
+			configure func(*withttp.Call[any]) *withttp.Call[any]
+		}
+
+		want struct {
+			name   string
+			attrs  map[attribute.Key]attribute.Value
+			events []string
+			status codes.Code
+			sent   int
+		}
+
+		testCase struct {
+			name string
+			args args
+			want want
+		}
+	)
+
+	tests := []testCase{
+		{
+			name: "retried",
+			args: args{
+				endpoint: withttp.NewEndpoint("users"),
+				configure: func(call *withttp.Call[any]) *withttp.Call[any] {
+					return call.
+						URL(srv.URL+"/digest?token=s3cr3t").
+						Method(http.MethodPost).
+						RawBody([]byte(`{"id":1}`)).
+						DigestAuth("user", "pass")
+				},
+			},
+			want: want{
+				name: "users",
+				attrs: map[attribute.Key]attribute.Value{
+					"http.request.method":       attribute.StringValue(http.MethodPost),
+					"url.full":                  attribute.StringValue(srv.URL + "/digest?token=[REDACTED]"),
+					"server.address":            attribute.StringValue("127.0.0.1"),
+					"server.port":               attribute.IntValue(port),
+					"http.response.status_code": attribute.IntValue(http.StatusOK),
+					"http.request.resend_count": attribute.IntValue(1),
+					"http.request.body.size":    attribute.IntValue(8),
+					"http.response.body.size":   attribute.IntValue(8),
+				},
+				events: []string{EventRetry, EventResponseProgress},
+				status: codes.Unset,
+				sent:   2,
+			},
+		},
+		{
+			name: "failed",
+			args: args{
+				endpoint: withttp.NewEndpoint("fail"),
+				configure: func(call *withttp.Call[any]) *withttp.Call[any] {
+					return call.URL(srv.URL + "/fail").ExpectedStatusCodes(http.StatusOK)
+				},
+			},
+			want: want{
+				name: "fail",
+				attrs: map[attribute.Key]attribute.Value{
+					"http.request.method":       attribute.StringValue(http.MethodGet),
+					"http.response.status_code": attribute.IntValue(http.StatusInternalServerError),
+					"error.type":                attribute.StringValue("500"),
+				},
+				events: []string{"exception"},
+				status: codes.Error,
+				sent:   1,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		for name, cli := range map[string]withttp.Client{
+			"net/http": withttp.NetHttpClient(&http.Client{}),
+			"fasthttp": withttp.Fasthttp(),
+		} {
+			t.Run(test.name+" "+name, func(t *testing.T) {
+				mu.Lock()
+				traceparents = nil
+				mu.Unlock()
+
+				recorder := tracetest.NewSpanRecorder()
+				provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+				tracer := Tracer(
+					WithTracerProvider(provider),
+					WithPropagators(propagation.TraceContext{}),
+					WithProgressInterval(1),
+				)
+
+				call := test.args.configure(withttp.NewCall[any](cli).WithTracer(tracer)).ReadBody()
+				_ = call.CallEndpoint(context.TODO(), test.args.endpoint)
+
+				spans := recorder.Ended()
+				if len(spans) != 1 {
+					t.Fatalf("unexpected amount of spans, want 1, have %d", len(spans))
+				}
+
+				span := spans[0]
+
+				if span.Name() != test.want.name {
+					t.Errorf("unexpected name, want '%s', have '%s'", test.want.name, span.Name())
+				}
+
+				attrs := make(map[attribute.Key]attribute.Value)
+				for _, attr := range span.Attributes() {
+					attrs[attr.Key] = attr.Value
+				}
+
+				for key, value := range test.want.attrs {
+					if attrs[key] != value {
+						t.Errorf("unexpected %s, want %v, have %v", key, value.Emit(), attrs[key].Emit())
+					}
+				}
+
+				var events []string
+				for _, event := range span.Events() {
+					// Responses may be read in several chunks.
+					if n := len(events); n > 0 && event.Name == EventResponseProgress &&
+						events[n-1] == EventResponseProgress {
+						continue
+					}
+					events = append(events, event.Name)
+				}
+
+				if strings.Join(events, ",") != strings.Join(test.want.events, ",") {
+					t.Errorf("unexpected events, want %v, have %v", test.want.events, events)
+				}
+
+				if span.Status().Code != test.want.status {
+					t.Errorf("unexpected status, want %v, have %v", test.want.status, span.Status().Code)
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+
+				if len(traceparents) != test.want.sent {
+					t.Fatalf("unexpected requests, want %d, have %d", test.want.sent, len(traceparents))
+				}
+
+				want := "00-" + span.SpanContext().TraceID().String() + "-" +
+					span.SpanContext().SpanID().String() + "-01"
+
+				for _, traceparent := range traceparents {
+					if traceparent != want {
+						t.Errorf("unexpected traceparent, want '%s', have '%s'", want, traceparent)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestCallTrace_Progress(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	_, trace := Tracer(WithTracerProvider(provider), WithProgressInterval(4)).
+		StartCall(context.TODO(), "upload")
+
+	for _, n := range []int{3, 2, 1, 4} {
+		trace.Sent(n)
+	}
+	trace.Received(3)
+	trace.End(nil, nil, nil)
+
+	var events []string
+	for _, event := range recorder.Ended()[0].Events() {
+		events = append(events, fmt.Sprintf("%s %d", event.Name, event.Attributes[0].Value.AsInt64()))
+	}
+
+	want := EventRequestProgress + " 5," + EventRequestProgress + " 10"
+	if strings.Join(events, ",") != want {
+		t.Errorf("unexpected events, want %s, have %v", want, events)
+	}
+}
@@ -0,0 +1,234 @@
+// Package promwithttp exposes client metrics of withttp calls to Prometheus, as recorded by
+// withttp.MetricsMiddleware, labelled by endpoint name, method and status class:
+//
+//	metrics := promwithttp.New()
+//	prometheus.MustRegister(metrics)
+//
+//	cli := withttp.WithMiddlewares(withttp.Fasthttp(), withttp.MetricsMiddleware(metrics))
+package promwithttp
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/sonirico/withttp"
+)
+
+const (
+	// DefaultNamespace prefixes metric names.
+	DefaultNamespace = "withttp"
+
+	// DirectionSent labels items of streamed request bodies.
+	DirectionSent = "sent"
+	// DirectionReceived labels items of streamed response bodies.
+	DirectionReceived = "received"
+
+	// Phases label the timings of requests, see withttp.Timings.
+	PhaseDNS          = "dns"
+	PhaseConnect      = "connect"
+	PhaseTLSHandshake = "tls"
+	PhaseTTFB         = "ttfb"
+	PhaseBodyRead     = "body_read"
+)
+
+var (
+	// DefaultSizeBuckets range from 64B to 64MiB.
+	DefaultSizeBuckets = prometheus.ExponentialBuckets(64, 4, 11)
+)
+
+type (
+	Option func(*options)
+
+	options struct {
+		namespace      string
+		constLabels    prometheus.Labels
+		latencyBuckets []float64
+		sizeBuckets    []float64
+	}
+
+	// Metrics implements withttp.Metrics as a prometheus.Collector, to be registered.
+	Metrics struct {
+		requests     *prometheus.CounterVec
+		duration     *prometheus.HistogramVec
+		inFlight     *prometheus.GaugeVec
+		requestSize  *prometheus.HistogramVec
+		responseSize *prometheus.HistogramVec
+		items        *prometheus.CounterVec
+		phases       *prometheus.HistogramVec
+	}
+)
+
+// WithNamespace prefixes metric names with `namespace` instead of DefaultNamespace.
+func WithNamespace(namespace string) Option {
+	return func(o *options) {
+		o.namespace = namespace
+	}
+}
+
+// WithConstLabels labels every metric with `labels`.
+func WithConstLabels(labels prometheus.Labels) Option {
+	return func(o *options) {
+		o.constLabels = labels
+	}
+}
+
+// WithLatencyBuckets buckets request durations, in seconds, instead of prometheus.DefBuckets.
+func WithLatencyBuckets(buckets ...float64) Option {
+	return func(o *options) {
+		o.latencyBuckets = buckets
+	}
+}
+
+// WithSizeBuckets buckets body sizes, in bytes, instead of DefaultSizeBuckets.
+func WithSizeBuckets(buckets ...float64) Option {
+	return func(o *options) {
+		o.sizeBuckets = buckets
+	}
+}
+
+// New creates the metrics, named as in:
+//
+//	withttp_client_requests_total{endpoint, method, status_class}
+//	withttp_client_request_duration_seconds{endpoint, method, status_class}
+//	withttp_client_requests_in_flight{endpoint, method}
+//	withttp_client_request_size_bytes{endpoint, method, status_class}
+//	withttp_client_response_size_bytes{endpoint, method, status_class}
+//	withttp_client_stream_items_total{endpoint, method, status_class, direction}
+//	withttp_client_phase_duration_seconds{endpoint, method, status_class, phase}
+func New(opts ...Option) *Metrics {
+	o := options{
+		namespace:      DefaultNamespace,
+		latencyBuckets: prometheus.DefBuckets,
+		sizeBuckets:    DefaultSizeBuckets,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	labels := []string{"endpoint", "method", "status_class"}
+
+	return &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   o.namespace,
+			Subsystem:   "client",
+			Name:        "requests_total",
+			Help:        "Requests sent.",
+			ConstLabels: o.constLabels,
+		}, labels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   o.namespace,
+			Subsystem:   "client",
+			Name:        "request_duration_seconds",
+			Help:        "Time until responses are received, or sending requests failed.",
+			ConstLabels: o.constLabels,
+			Buckets:     o.latencyBuckets,
+		}, labels),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace:   o.namespace,
+			Subsystem:   "client",
+			Name:        "requests_in_flight",
+			Help:        "Requests sent whose responses were not received yet.",
+			ConstLabels: o.constLabels,
+		}, labels[:2]),
+		requestSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   o.namespace,
+			Subsystem:   "client",
+			Name:        "request_size_bytes",
+			Help:        "Size of request bodies.",
+			ConstLabels: o.constLabels,
+			Buckets:     o.sizeBuckets,
+		}, labels),
+		responseSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   o.namespace,
+			Subsystem:   "client",
+			Name:        "response_size_bytes",
+			Help:        "Size of response bodies read.",
+			ConstLabels: o.constLabels,
+			Buckets:     o.sizeBuckets,
+		}, labels),
+		items: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   o.namespace,
+			Subsystem:   "client",
+			Name:        "stream_items_total",
+			Help:        "Items of streamed bodies sent or received.",
+			ConstLabels: o.constLabels,
+		}, append(labels, "direction")),
+		phases: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   o.namespace,
+			Subsystem:   "client",
+			Name:        "phase_duration_seconds",
+			Help:        "Time spent on every phase of requests, see withttp.Timings.",
+			ConstLabels: o.constLabels,
+			Buckets:     o.latencyBuckets,
+		}, append(labels, "phase")),
+	}
+}
+
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, c := range m.collectors() {
+		c.Describe(ch)
+	}
+}
+
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range m.collectors() {
+		c.Collect(ch)
+	}
+}
+
+func (m *Metrics) Started(labels withttp.MetricLabels) {
+	m.inFlight.WithLabelValues(labels.Endpoint, labels.Method).Inc()
+}
+
+func (m *Metrics) Finished(labels withttp.MetricLabels, took time.Duration) {
+	m.inFlight.WithLabelValues(labels.Endpoint, labels.Method).Dec()
+	m.requests.WithLabelValues(labelValues(labels)...).Inc()
+	m.duration.WithLabelValues(labelValues(labels)...).Observe(took.Seconds())
+}
+
+func (m *Metrics) Transferred(labels withttp.MetricLabels, transfer withttp.Transfer) {
+	values := labelValues(labels)
+
+	if transfer.RequestBytes >= 0 {
+		m.requestSize.WithLabelValues(values...).Observe(float64(transfer.RequestBytes))
+	}
+
+	if labels.StatusClass != withttp.StatusClassError {
+		m.responseSize.WithLabelValues(values...).Observe(float64(transfer.ResponseBytes))
+	}
+
+	if transfer.ItemsSent > 0 {
+		m.items.WithLabelValues(append(values, DirectionSent)...).Add(float64(transfer.ItemsSent))
+	}
+
+	if transfer.ItemsReceived > 0 {
+		m.items.WithLabelValues(append(values, DirectionReceived)...).Add(float64(transfer.ItemsReceived))
+	}
+
+	timings := transfer.Timings
+
+	for phase, took := range map[string]time.Duration{
+		PhaseDNS:          timings.DNS,
+		PhaseConnect:      timings.Connect,
+		PhaseTLSHandshake: timings.TLSHandshake,
+		PhaseTTFB:         timings.TTFB,
+		PhaseBodyRead:     timings.BodyRead,
+	} {
+		// Phases which did not take place, or whose timings are unknown, are zero.
+		if took > 0 {
+			m.phases.WithLabelValues(append(values, phase)...).Observe(took.Seconds())
+		}
+	}
+}
+
+func (m *Metrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.requests, m.duration, m.inFlight, m.requestSize, m.responseSize, m.items, m.phases,
+	}
+}
+
+func labelValues(labels withttp.MetricLabels) []string {
+	return []string{labels.Endpoint, labels.Method, labels.StatusClass}
+}
@@ -0,0 +1,137 @@
+package promwithttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/valyala/fasthttp"
+
+	"github.com/sonirico/withttp"
+)
+
+func TestMetrics(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/digest":
+			if r.Header.Get("authorization") == "" {
+				w.Header().Set("www-authenticate", `Digest realm="test", qop="auth", nonce="abc"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte("{\"id\":1}\n{\"id\":2}\n"))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	type item struct {
+		ID int `json:"id"`
+	}
+
+	for name, cli := range map[string]withttp.Client{
+		"net/http": withttp.NetHttpClient(&http.Client{}),
+		"fasthttp": withttp.FasthttpTimedClient(&fasthttp.Client{}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			metrics := New(WithSizeBuckets(10, 100))
+			cli := withttp.WithMiddlewares(cli, withttp.MetricsMiddleware(metrics))
+
+			err := withttp.NewCall[item](cli).
+				URL(srv.URL+"/digest").
+				Method(http.MethodPost).
+				RawBody([]byte(`{"id":1}`)).
+				DigestAuth("user", "pass").
+				ParseJSONEachRow(func(item) bool { return true }).
+				CallEndpoint(context.TODO(), withttp.NewEndpoint("items"))
+			if err != nil {
+				t.Fatalf("unexpected error, want none, have %v", err)
+			}
+
+			err = withttp.NewCall[item](cli).
+				URL(srv.URL).
+				ExpectedStatusCodes(http.StatusOK).
+				CallEndpoint(context.TODO(), withttp.NewEndpoint("fail"))
+			if err == nil {
+				t.Fatalf("unexpected error, want some, have none")
+			}
+
+			want := `
+# HELP withttp_client_requests_in_flight Requests sent whose responses were not received yet.
+# TYPE withttp_client_requests_in_flight gauge
+withttp_client_requests_in_flight{endpoint="fail",method="GET"} 0
+withttp_client_requests_in_flight{endpoint="items",method="POST"} 0
+# HELP withttp_client_requests_total Requests sent.
+# TYPE withttp_client_requests_total counter
+withttp_client_requests_total{endpoint="fail",method="GET",status_class="5xx"} 1
+withttp_client_requests_total{endpoint="items",method="POST",status_class="2xx"} 1
+withttp_client_requests_total{endpoint="items",method="POST",status_class="4xx"} 1
+# HELP withttp_client_request_size_bytes Size of request bodies.
+# TYPE withttp_client_request_size_bytes histogram
+withttp_client_request_size_bytes_bucket{endpoint="fail",method="GET",status_class="5xx",le="10"} 1
+withttp_client_request_size_bytes_bucket{endpoint="fail",method="GET",status_class="5xx",le="100"} 1
+withttp_client_request_size_bytes_bucket{endpoint="fail",method="GET",status_class="5xx",le="+Inf"} 1
+withttp_client_request_size_bytes_sum{endpoint="fail",method="GET",status_class="5xx"} 0
+withttp_client_request_size_bytes_count{endpoint="fail",method="GET",status_class="5xx"} 1
+withttp_client_request_size_bytes_bucket{endpoint="items",method="POST",status_class="2xx",le="10"} 1
+withttp_client_request_size_bytes_bucket{endpoint="items",method="POST",status_class="2xx",le="100"} 1
+withttp_client_request_size_bytes_bucket{endpoint="items",method="POST",status_class="2xx",le="+Inf"} 1
+withttp_client_request_size_bytes_sum{endpoint="items",method="POST",status_class="2xx"} 8
+withttp_client_request_size_bytes_count{endpoint="items",method="POST",status_class="2xx"} 1
+withttp_client_request_size_bytes_bucket{endpoint="items",method="POST",status_class="4xx",le="10"} 1
+withttp_client_request_size_bytes_bucket{endpoint="items",method="POST",status_class="4xx",le="100"} 1
+withttp_client_request_size_bytes_bucket{endpoint="items",method="POST",status_class="4xx",le="+Inf"} 1
+withttp_client_request_size_bytes_sum{endpoint="items",method="POST",status_class="4xx"} 8
+withttp_client_request_size_bytes_count{endpoint="items",method="POST",status_class="4xx"} 1
+# HELP withttp_client_response_size_bytes Size of response bodies read.
+# TYPE withttp_client_response_size_bytes histogram
+withttp_client_response_size_bytes_bucket{endpoint="fail",method="GET",status_class="5xx",le="10"} 1
+withttp_client_response_size_bytes_bucket{endpoint="fail",method="GET",status_class="5xx",le="100"} 1
+withttp_client_response_size_bytes_bucket{endpoint="fail",method="GET",status_class="5xx",le="+Inf"} 1
+withttp_client_response_size_bytes_sum{endpoint="fail",method="GET",status_class="5xx"} 0
+withttp_client_response_size_bytes_count{endpoint="fail",method="GET",status_class="5xx"} 1
+withttp_client_response_size_bytes_bucket{endpoint="items",method="POST",status_class="2xx",le="10"} 0
+withttp_client_response_size_bytes_bucket{endpoint="items",method="POST",status_class="2xx",le="100"} 1
+withttp_client_response_size_bytes_bucket{endpoint="items",method="POST",status_class="2xx",le="+Inf"} 1
+withttp_client_response_size_bytes_sum{endpoint="items",method="POST",status_class="2xx"} 18
+withttp_client_response_size_bytes_count{endpoint="items",method="POST",status_class="2xx"} 1
+withttp_client_response_size_bytes_bucket{endpoint="items",method="POST",status_class="4xx",le="10"} 1
+withttp_client_response_size_bytes_bucket{endpoint="items",method="POST",status_class="4xx",le="100"} 1
+withttp_client_response_size_bytes_bucket{endpoint="items",method="POST",status_class="4xx",le="+Inf"} 1
+withttp_client_response_size_bytes_sum{endpoint="items",method="POST",status_class="4xx"} 0
+withttp_client_response_size_bytes_count{endpoint="items",method="POST",status_class="4xx"} 1
+# HELP withttp_client_stream_items_total Items of streamed bodies sent or received.
+# TYPE withttp_client_stream_items_total counter
+withttp_client_stream_items_total{direction="received",endpoint="items",method="POST",status_class="2xx"} 2
+`
+
+			err = testutil.CollectAndCompare(metrics, strings.NewReader(want),
+				"withttp_client_requests_in_flight",
+				"withttp_client_requests_total",
+				"withttp_client_request_size_bytes",
+				"withttp_client_response_size_bytes",
+				"withttp_client_stream_items_total",
+			)
+			if err != nil {
+				t.Error(err)
+			}
+
+			if n := testutil.CollectAndCount(metrics, "withttp_client_request_duration_seconds"); n != 3 {
+				t.Errorf("unexpected durations, want 3, have %d", n)
+			}
+
+			var ttfb dto.Metric
+			observer := metrics.phases.WithLabelValues("items", http.MethodPost, "2xx", PhaseTTFB)
+			_ = observer.(prometheus.Histogram).Write(&ttfb)
+			if n := ttfb.GetHistogram().GetSampleCount(); n != 1 {
+				t.Errorf("unexpected ttfb samples, want 1, have %d", n)
+			}
+		})
+	}
+}
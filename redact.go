@@ -0,0 +1,206 @@
+package withttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// DefaultRedactionMask replaces redacted values.
+const DefaultRedactionMask = "[REDACTED]"
+
+type (
+	// RedactionPolicy tells which headers, query string parameters and JSON body fields, compared
+	// case insensitively, are masked before being logged or dumped. Body fields are masked at any
+	// depth, whatever their value.
+	RedactionPolicy struct {
+		Headers     []string
+		QueryParams []string
+		BodyFields  []string
+		// Mask replaces redacted values. Defaults to DefaultRedactionMask.
+		Mask string
+	}
+
+	// redactionFrame tracks the JSON object or array being redacted, and how many tokens of it,
+	// keys included, were written.
+	redactionFrame struct {
+		object bool
+		n      int
+	}
+)
+
+// DefaultRedactionPolicy masks credentials commonly found in headers, query strings and bodies.
+func DefaultRedactionPolicy() *RedactionPolicy {
+	return &RedactionPolicy{
+		Headers: []string{
+			"authorization", "proxy-authorization", "cookie", "set-cookie", "x-api-key",
+			"x-amz-security-token",
+		},
+		QueryParams: []string{
+			"access_token", "api_key", "apikey", "token", "signature", "x-amz-signature",
+			"x-amz-security-token",
+		},
+		BodyFields: []string{
+			"password", "secret", "client_secret", "access_token", "refresh_token", "id_token", "token",
+		},
+	}
+}
+
+// Header returns `value`, masked if the header `key` is to be redacted.
+func (p *RedactionPolicy) Header(key, value string) string {
+	if containsFold(p.Headers, key) {
+		return p.mask()
+	}
+	return value
+}
+
+// URL returns `u` as a string, its password and redacted query string parameters masked.
+func (p *RedactionPolicy) URL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+
+	redacted := *u
+
+	if _, ok := redacted.User.Password(); ok {
+		redacted.User = url.UserPassword(redacted.User.Username(), p.mask())
+	}
+
+	if redacted.RawQuery != "" && len(p.QueryParams) > 0 {
+		params := strings.Split(redacted.RawQuery, "&")
+		for i, param := range params {
+			key, _, _ := strings.Cut(param, "=")
+
+			unescaped, err := url.QueryUnescape(key)
+			if err == nil && containsFold(p.QueryParams, unescaped) {
+				params[i] = key + "=" + p.mask()
+			}
+		}
+		redacted.RawQuery = strings.Join(params, "&")
+	}
+
+	return redacted.String()
+}
+
+// Body returns `data` with its redacted fields masked, provided it is JSON, or a stream of JSON
+// values, such as JSON lines, whose formatting is then compacted. Anything else is kept as it is.
+func (p *RedactionPolicy) Body(data []byte) []byte {
+	trimmed := bytes.TrimSpace(data)
+	if len(p.BodyFields) == 0 || len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return data
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var (
+		out   = new(bytes.Buffer)
+		stack []redactionFrame
+	)
+
+	// separate writes what goes before the next token: line breaks between top level values,
+	// colons after keys and commas after anything else.
+	separate := func() {
+		if len(stack) == 0 {
+			if out.Len() > 0 {
+				out.WriteByte('\n')
+			}
+			return
+		}
+
+		frame := &stack[len(stack)-1]
+		switch {
+		case frame.object && frame.n%2 == 1:
+			out.WriteByte(':')
+		case frame.n > 0:
+			out.WriteByte(',')
+		}
+		frame.n++
+	}
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return data
+		}
+
+		if delim, ok := tok.(json.Delim); ok {
+			if delim == '}' || delim == ']' {
+				stack = stack[:len(stack)-1]
+			} else {
+				separate()
+				stack = append(stack, redactionFrame{object: delim == '{'})
+			}
+			out.WriteByte(byte(delim))
+			continue
+		}
+
+		isKey := len(stack) > 0 && stack[len(stack)-1].object && stack[len(stack)-1].n%2 == 0
+
+		separate()
+		writeJSONToken(out, tok)
+
+		if key, _ := tok.(string); isKey && containsFold(p.BodyFields, key) {
+			if err = skipJSONValue(dec); err != nil {
+				return data
+			}
+			separate()
+			writeJSONToken(out, p.mask())
+		}
+	}
+
+	return out.Bytes()
+}
+
+func (p *RedactionPolicy) mask() string {
+	if StrIsset(p.Mask) {
+		return p.Mask
+	}
+	return DefaultRedactionMask
+}
+
+func writeJSONToken(w *bytes.Buffer, tok json.Token) {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(tok)
+	// Encode terminates values with a line break.
+	w.Truncate(w.Len() - 1)
+}
+
+// skipJSONValue skips the next value of `dec`, be it a scalar, an object or an array.
+func skipJSONValue(dec *json.Decoder) error {
+	depth := 0
+
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		if delim, ok := tok.(json.Delim); ok {
+			if delim == '{' || delim == '[' {
+				depth++
+			} else {
+				depth--
+			}
+		}
+
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,238 @@
+package withttp
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrStreamedPayload = errors.New("streamed payload cannot be signed")
+)
+
+type (
+	// Signer signs requests, usually over their method, URL, headers and body, by setting headers
+	// or query string parameters on them. Signers see requests as they are sent, that is, once
+	// every option is configured and every middleware but signing ones is done with them.
+	Signer interface {
+		Sign(ctx context.Context, req Request) error
+	}
+
+	SignerFunc func(ctx context.Context, req Request) error
+
+	// bufferedRequest is implemented by requests able to hand their body over without consuming
+	// it, which those with streamed bodies cannot.
+	bufferedRequest interface {
+		bufferedBody() ([]byte, bool)
+	}
+
+	// sizedRequest is implemented by requests needing to be told the size of streamed bodies
+	// apart from SetBodyStream.
+	sizedRequest interface {
+		setContentLength(size int64)
+	}
+
+	// SigningInput is what HMACSigner canonicalizes into the message to sign.
+	SigningInput struct {
+		Method string
+		// Path is the escaped path of the request URL.
+		Path string
+		// Query is the raw query string of the request URL.
+		Query string
+		// Headers holds the signed headers, as "name:value" lines whose names are lower case, in the
+		// order they were declared.
+		Headers []string
+		Payload []byte
+
+		Timestamp string
+		Nonce     string
+	}
+
+	// Canonicalizer builds the message HMACSigner signs out of the request.
+	Canonicalizer func(in SigningInput) string
+
+	// HMACSigner signs requests with an HMAC over a canonical representation of them, as most
+	// crypto exchanges and webhook providers mandate. Zero values of its optional fields are
+	// replaced by sensible defaults, noted below.
+	HMACSigner struct {
+		Secret []byte
+		// Hash is the hash function of the HMAC. Defaults to SHA-256.
+		Hash func() hash.Hash
+		// Encode encodes the HMAC into the signature. Defaults to hex.
+		Encode func([]byte) string
+		// Header is the header carrying the signature. Defaults to "x-signature".
+		Header string
+		// Canonicalize builds the message to sign. Defaults to CanonicalLines.
+		Canonicalize Canonicalizer
+		// SignedHeaders are the headers, if any, whose values take part in the signature.
+		SignedHeaders []string
+
+		// TimestampHeader is the header, if any, carrying the timestamp the request was signed at.
+		TimestampHeader string
+		// Timestamp formats the time requests are signed at. Defaults to Unix milliseconds.
+		Timestamp func(time.Time) string
+		// NonceHeader is the header, if any, carrying a nonce, unique to every signed request.
+		NonceHeader string
+		// Nonce generates nonces. Defaults to 16 random bytes, hex encoded.
+		Nonce func() string
+
+		Now func() time.Time
+	}
+)
+
+func (f SignerFunc) Sign(ctx context.Context, req Request) error {
+	return f(ctx, req)
+}
+
+// Sign signs the call with `signers`, in order, right before sending it.
+func Sign[T any](signers ...Signer) CallReqOptionFunc[T] {
+	return func(c *Call[T], _ Request) error {
+		c.reqSigners = append(c.reqSigners, signers...)
+		return nil
+	}
+}
+
+// SignMiddleware signs every request sent by the client with `signers`, in order. It is meant to
+// be the innermost middleware, so that requests are signed as they are finally sent.
+func SignMiddleware(signers ...Signer) Middleware {
+	return MiddlewareFunc(func(ctx context.Context, req Request, next DoFunc) (Response, error) {
+		for _, signer := range signers {
+			if err := signer.Sign(ctx, req); err != nil {
+				return nil, err
+			}
+		}
+		return next(ctx, req)
+	})
+}
+
+// RequestPayload returns the body of `req` without consuming it, which is not possible for streamed
+// bodies, in which case false is returned.
+func RequestPayload(req Request) ([]byte, bool) {
+	if br, ok := req.(bufferedRequest); ok {
+		return br.bufferedBody()
+	}
+	return nil, false
+}
+
+// CanonicalLines joins the method, path, query string sorted by key, timestamp, nonce, signed
+// headers and the hex encoded SHA-256 of the payload with line breaks.
+func CanonicalLines(in SigningInput) string {
+	lines := []string{in.Method, in.Path, sortedQuery(in.Query), in.Timestamp, in.Nonce}
+	lines = append(lines, in.Headers...)
+	lines = append(lines, sha256Hex(in.Payload))
+	return strings.Join(lines, "\n")
+}
+
+// CanonicalConcat concatenates the timestamp, method, path, query string, if any, and payload, as
+// exchanges such as Coinbase or OKX do.
+func CanonicalConcat(in SigningInput) string {
+	b := new(strings.Builder)
+	b.WriteString(in.Timestamp)
+	b.WriteString(in.Method)
+	b.WriteString(in.Path)
+	if in.Query != "" {
+		b.WriteByte('?')
+		b.WriteString(in.Query)
+	}
+	b.Write(in.Payload)
+	return b.String()
+}
+
+// Sign sets the signature header of `req`, along with the timestamp and nonce ones, if any. Streamed
+// bodies cannot be signed.
+func (s HMACSigner) Sign(_ context.Context, req Request) error {
+	payload, ok := RequestPayload(req)
+	if !ok {
+		return ErrStreamedPayload
+	}
+
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+
+	u := req.URL()
+
+	in := SigningInput{
+		Method:  req.Method(),
+		Path:    u.EscapedPath(),
+		Query:   u.RawQuery,
+		Payload: payload,
+	}
+
+	if s.Timestamp != nil {
+		in.Timestamp = s.Timestamp(now())
+	} else {
+		in.Timestamp = strconv.FormatInt(now().UnixMilli(), 10)
+	}
+
+	if StrIsset(s.NonceHeader) {
+		if s.Nonce != nil {
+			in.Nonce = s.Nonce()
+		} else {
+			in.Nonce = newNonce()
+		}
+		req.SetHeader(s.NonceHeader, in.Nonce)
+	}
+
+	if StrIsset(s.TimestampHeader) {
+		req.SetHeader(s.TimestampHeader, in.Timestamp)
+	}
+
+	for _, key := range s.SignedHeaders {
+		value, _ := req.Header(key)
+		in.Headers = append(in.Headers, strings.ToLower(key)+":"+strings.TrimSpace(value))
+	}
+
+	canonicalize, newHash, encode, header := s.Canonicalize, s.Hash, s.Encode, s.Header
+	if canonicalize == nil {
+		canonicalize = CanonicalLines
+	}
+	if newHash == nil {
+		newHash = sha256.New
+	}
+	if encode == nil {
+		encode = hex.EncodeToString
+	}
+	if !StrIsset(header) {
+		header = "x-signature"
+	}
+
+	mac := hmac.New(newHash, s.Secret)
+	mac.Write([]byte(canonicalize(in)))
+
+	req.SetHeader(header, encode(mac.Sum(nil)))
+
+	return nil
+}
+
+// sortedQuery sorts the parameters of `query` by key, then by value, keeping their encoding.
+func sortedQuery(query string) string {
+	if query == "" {
+		return ""
+	}
+
+	params := strings.Split(query, "&")
+	sort.Slice(params, func(i, j int) bool {
+		ki, vi, _ := strings.Cut(params[i], "=")
+		kj, vj, _ := strings.Cut(params[j], "=")
+		if ki != kj {
+			return ki < kj
+		}
+		return vi < vj
+	})
+	return strings.Join(params, "&")
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
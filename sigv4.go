@@ -0,0 +1,361 @@
+package withttp
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// PayloadSigned signs the SHA-256 of the body, hence it must not be streamed.
+	PayloadSigned PayloadMode = iota
+	// PayloadUnsigned leaves the body out of the signature, as S3 allows over TLS.
+	PayloadUnsigned
+	// PayloadStreaming sends the body in aws-chunked encoding, each chunk signed on its own, so that
+	// streamed bodies are signed as they are sent. Their decoded length must be known in advance,
+	// through the x-amz-decoded-content-length header, unless they are not streamed.
+	PayloadStreaming
+)
+
+const (
+	// DefaultSigV4ChunkSize is the size of the chunks streamed payloads are split into.
+	DefaultSigV4ChunkSize = 64 * 1024
+
+	sigV4Algorithm        = "AWS4-HMAC-SHA256"
+	sigV4ChunkAlgorithm   = "AWS4-HMAC-SHA256-PAYLOAD"
+	sigV4TimeFormat       = "20060102T150405Z"
+	sigV4DateFormat       = "20060102"
+	sigV4UnsignedPayload  = "UNSIGNED-PAYLOAD"
+	sigV4StreamingPayload = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+)
+
+var (
+	ErrMissingDecodedLength = errors.New("missing decoded content length")
+	ErrUnknownPayloadMode   = errors.New("unknown payload mode")
+)
+
+var (
+	// sigV4IgnoredHeaders are left out of signatures, as transports and proxies may alter them.
+	sigV4IgnoredHeaders = map[string]bool{
+		"authorization":     true,
+		"connection":        true,
+		"expect":            true,
+		"transfer-encoding": true,
+		"user-agent":        true,
+		"x-amzn-trace-id":   true,
+	}
+)
+
+type (
+	// PayloadMode tells how SigV4 signs request bodies.
+	PayloadMode int
+
+	// SigV4 signs requests with AWS Signature Version 4, as AWS services and S3 compatible stores
+	// require. Every header of the request takes part in the signature, but a few that transports
+	// may alter, such as the user agent.
+	SigV4 struct {
+		AccessKeyID     string
+		SecretAccessKey string
+		// SessionToken is sent along temporary credentials, if any.
+		SessionToken string
+
+		Region  string
+		Service string
+
+		Payload PayloadMode
+		// ChunkSize is the size of the chunks of streamed payloads. Defaults to
+		// DefaultSigV4ChunkSize.
+		ChunkSize int
+
+		Now func() time.Time
+	}
+
+	// awsChunkedStream encodes whatever is read from the underlying stream in aws-chunked encoding,
+	// every chunk signature chaining the previous one, starting at the signature of the request.
+	awsChunkedStream struct {
+		inner io.ReadWriteCloser
+
+		key       []byte
+		timestamp string
+		scope     string
+		prev      string
+
+		chunk []byte
+		buf   bytes.Buffer
+		done  bool
+	}
+)
+
+// Sign sets the authorization header of `req`, along with the x-amz-* ones the signature covers.
+func (s SigV4) Sign(_ context.Context, req Request) error {
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+
+	t := now().UTC()
+	timestamp := t.Format(sigV4TimeFormat)
+	scope := strings.Join([]string{t.Format(sigV4DateFormat), s.Region, s.Service, "aws4_request"}, "/")
+
+	var (
+		payloadHash string
+		stream      *awsChunkedStream
+	)
+
+	switch s.Payload {
+	case PayloadSigned:
+		payload, ok := RequestPayload(req)
+		if !ok {
+			return ErrStreamedPayload
+		}
+		payloadHash = sha256Hex(payload)
+	case PayloadUnsigned:
+		payloadHash = sigV4UnsignedPayload
+	case PayloadStreaming:
+		var err error
+		if stream, err = s.chunked(req); err != nil {
+			return err
+		}
+		payloadHash = sigV4StreamingPayload
+	default:
+		return errors.Wrapf(ErrUnknownPayloadMode, "got: '%d'", s.Payload)
+	}
+
+	req.SetHeader("x-amz-date", timestamp)
+	req.SetHeader("x-amz-content-sha256", payloadHash)
+	if StrIsset(s.SessionToken) {
+		req.SetHeader("x-amz-security-token", s.SessionToken)
+	}
+
+	canonical, signedHeaders := s.canonicalRequest(req, payloadHash)
+
+	key := []byte("AWS4" + s.SecretAccessKey)
+	for _, part := range strings.Split(scope, "/") {
+		key = hmacSHA256(key, part)
+	}
+
+	toSign := strings.Join([]string{sigV4Algorithm, timestamp, scope, sha256Hex([]byte(canonical))}, "\n")
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+
+	req.SetHeader("authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, s.AccessKeyID, scope, signedHeaders, signature))
+
+	if stream != nil {
+		stream.key, stream.timestamp, stream.scope, stream.prev = key, timestamp, scope, signature
+	}
+
+	return nil
+}
+
+// canonicalRequest returns the canonical form of `req` and the names of the headers it signs.
+func (s SigV4) canonicalRequest(req Request, payloadHash string) (string, string) {
+	u := req.URL()
+
+	headers := map[string][]string{"host": {u.Host}}
+	req.RangeHeaders(func(key, value string) {
+		key = strings.ToLower(key)
+		if key == "host" || sigV4IgnoredHeaders[key] {
+			return
+		}
+		headers[key] = append(headers[key], strings.Join(strings.Fields(value), " "))
+	})
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	canonicalHeaders := new(strings.Builder)
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.Join(headers[name], ",") + "\n")
+	}
+
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+
+	path = awsEscape(path, true)
+	if s.Service != "s3" {
+		// Every service but S3 escapes paths twice.
+		path = awsEscape(path, true)
+	}
+
+	signedHeaders := strings.Join(names, ";")
+
+	return strings.Join([]string{
+		req.Method(),
+		path,
+		awsQuery(u.RawQuery),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n"), signedHeaders
+}
+
+// chunked replaces the body of `req` with its aws-chunked encoding, announcing its decoded length.
+func (s SigV4) chunked(req Request) (*awsChunkedStream, error) {
+	size := s.ChunkSize
+	if size <= 0 {
+		size = DefaultSigV4ChunkSize
+	}
+
+	var (
+		body    io.ReadWriteCloser
+		decoded int64
+	)
+
+	if payload, ok := RequestPayload(req); ok {
+		body, decoded = closableReaderWriter{ReadWriter: bytes.NewBuffer(payload)}, int64(len(payload))
+	} else {
+		value, _ := req.Header("x-amz-decoded-content-length")
+
+		var err error
+		if decoded, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return nil, errors.Wrapf(ErrMissingDecodedLength, "got: '%s'", value)
+		}
+		body = req.BodyStream()
+	}
+
+	encoding := "aws-chunked"
+	if current, ok := req.Header("content-encoding"); ok {
+		encoding += "," + current
+	}
+
+	req.SetHeader("content-encoding", encoding)
+	req.SetHeader("x-amz-decoded-content-length", strconv.FormatInt(decoded, 10))
+
+	stream := &awsChunkedStream{inner: body, chunk: make([]byte, size)}
+	length := awsChunkedLength(decoded, int64(size))
+
+	req.SetBodyStream(stream, int(length))
+	if sr, ok := req.(sizedRequest); ok {
+		sr.setContentLength(length)
+	}
+
+	return stream, nil
+}
+
+// awsChunkedLength returns the length of `decoded` bytes once encoded in chunks of `size` bytes.
+func awsChunkedLength(decoded, size int64) int64 {
+	chunk := func(n int64) int64 {
+		// <hex size>;chunk-signature=<64 hex>\r\n<data>\r\n
+		return int64(len(strconv.FormatInt(n, 16))) + 17 + 64 + 2 + n + 2
+	}
+
+	length := decoded/size*chunk(size) + chunk(0)
+	if rest := decoded % size; rest > 0 {
+		length += chunk(rest)
+	}
+	return length
+}
+
+func (s *awsChunkedStream) Read(p []byte) (int, error) {
+	for s.buf.Len() == 0 {
+		if s.done {
+			return 0, io.EOF
+		}
+
+		n, err := io.ReadFull(s.inner, s.chunk)
+		if n > 0 {
+			s.writeChunk(s.chunk[:n])
+		}
+
+		switch err {
+		case nil:
+		case io.EOF, io.ErrUnexpectedEOF:
+			// Streams are terminated by an empty chunk.
+			s.writeChunk(nil)
+			s.done = true
+		default:
+			return 0, err
+		}
+	}
+
+	return s.buf.Read(p)
+}
+
+func (s *awsChunkedStream) writeChunk(data []byte) {
+	toSign := strings.Join([]string{
+		sigV4ChunkAlgorithm, s.timestamp, s.scope, s.prev, sha256Hex(nil), sha256Hex(data),
+	}, "\n")
+	s.prev = hex.EncodeToString(hmacSHA256(s.key, toSign))
+
+	fmt.Fprintf(&s.buf, "%x;chunk-signature=%s\r\n", len(data), s.prev)
+	s.buf.Write(data)
+	s.buf.WriteString("\r\n")
+}
+
+func (s *awsChunkedStream) Write(p []byte) (int, error) {
+	return s.inner.Write(p)
+}
+
+func (s *awsChunkedStream) Close() error {
+	return s.inner.Close()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsEscape escapes `s` as per RFC 3986, leaving unreserved characters, and slashes if told so,
+// unescaped, as AWS canonicalization mandates.
+func awsEscape(s string, slash bool) string {
+	b := new(strings.Builder)
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', slash && c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(b, "%%%02X", c)
+		}
+	}
+
+	return b.String()
+}
+
+// awsQuery canonicalizes `query` as AWS does, by escaping its decoded parameters and sorting them
+// by key, then by value.
+func awsQuery(query string) string {
+	values, _ := url.ParseQuery(query)
+
+	params := make([][2]string, 0, len(values))
+	for key, vals := range values {
+		for _, val := range vals {
+			params = append(params, [2]string{awsEscape(key, false), awsEscape(val, false)})
+		}
+	}
+
+	sort.Slice(params, func(i, j int) bool {
+		if params[i][0] != params[j][0] {
+			return params[i][0] < params[j][0]
+		}
+		return params[i][1] < params[j][1]
+	})
+
+	b := new(strings.Builder)
+	for i, param := range params {
+		if i > 0 {
+			b.WriteByte('&')
+		}
+		b.WriteString(param[0] + "=" + param[1])
+	}
+	return b.String()
+}
@@ -2,13 +2,20 @@ package withttp
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"io"
 
+	"github.com/pkg/errors"
+
 	"github.com/sonirico/withttp/csvparser"
 )
 
+var (
+	newLine = []byte{'\n'}
+)
+
 type (
 	Stream[T any] interface {
 		Next(ctx context.Context) bool
@@ -34,6 +41,9 @@ type (
 		inner Stream[[]byte]
 
 		err error
+
+		recordErrs recordErrorHandler
+		lines      int
 	}
 
 	CSVStream[T any] struct {
@@ -46,16 +56,37 @@ type (
 		parser csvparser.Parser[T]
 
 		ignoreLines int
-		ignoreErr   bool
+		recordErrs  recordErrorHandler
+
+		headerBound bool
 
+		lines    int
 		rowCount int
 	}
 
+	FixedWidthStream[T any] struct {
+		current T
+
+		inner Stream[[]byte]
+
+		err error
+
+		parser csvparser.FixedWidthParser[T]
+
+		ignoreLines int
+		recordErrs  recordErrorHandler
+		lines       int
+	}
+
 	NewLineStream struct {
 		current []byte
 
 		scanner *bufio.Scanner
 
+		skipBlankLines bool
+		line           int
+		linesRead      int
+
 		err error
 	}
 
@@ -91,12 +122,20 @@ func (s *ProxyStream) Err() error {
 }
 
 func (s *NewLineStream) Next(_ context.Context) bool {
-	if !s.scanner.Scan() {
-		s.err = s.scanner.Err()
-		return false
+	for {
+		if !s.scanner.Scan() {
+			s.err = s.scanner.Err()
+			return false
+		}
+
+		s.current = s.scanner.Bytes()
+		s.line = s.linesRead + 1
+		s.linesRead += 1 + bytes.Count(s.current, newLine)
+
+		if !s.skipBlankLines || BtsIsset(s.current) {
+			return true
+		}
 	}
-	s.current = s.scanner.Bytes()
-	return true
 }
 
 func (s *NewLineStream) Data() []byte {
@@ -111,14 +150,31 @@ func (s *NewLineStream) Err() error {
 	return s.scanner.Err()
 }
 
+// Line returns the 1-based line number where the last record read starts, blank lines included.
+// Records are assumed to be terminated by a line break.
+func (s *NewLineStream) Line() int {
+	return s.line
+}
+
 func (s *JSONEachRowStream[T]) Next(ctx context.Context) bool {
-	if !s.inner.Next(ctx) {
-		return false
-	}
+	for s.inner.Next(ctx) {
+		s.lines = lineNumber(s.inner, s.lines+1)
 
-	s.err = json.Unmarshal(s.inner.Data(), &s.current)
+		var zeroed T
+		raw := s.inner.Data()
+		s.current = zeroed
+		s.err = json.Unmarshal(raw, &s.current)
 
-	return true
+		if s.err == nil {
+			return true
+		}
+
+		if s.err = s.recordErrs.handle(s.lines, raw, s.err); s.err != nil {
+			return true
+		}
+	}
+
+	return false
 }
 
 func (s *JSONEachRowStream[T]) Data() T {
@@ -133,22 +189,36 @@ func (s *JSONEachRowStream[T]) Err() error {
 	return s.inner.Err()
 }
 
+// Skipped returns the amount of records discarded because they could not be decoded.
+func (s *JSONEachRowStream[T]) Skipped() int {
+	return s.recordErrs.skipped
+}
+
 func (s *CSVStream[T]) next(ctx context.Context, shouldParse bool) bool {
 	if !s.inner.Next(ctx) {
 		return false
 	}
 
+	s.lines = lineNumber(s.inner, s.lines+1)
+
 	if !shouldParse {
 		return true
 	}
 
-	var zeroed T // TODO: json too?
+	var zeroed T
 	line := s.inner.Data()
 	s.current = zeroed
 	s.err = s.parser.Parse(line, &s.current)
 
-	if s.err == nil || s.ignoreErr {
+	if s.err == nil {
 		s.rowCount++
+		return true
+	}
+
+	// Parsers report lines relative to the record.
+	var parseErr *csvparser.ParseError
+	if errors.As(s.err, &parseErr) {
+		parseErr.Line += s.lines - 1
 	}
 
 	return true
@@ -159,7 +229,34 @@ func (s *CSVStream[T]) Next(ctx context.Context) bool {
 		_ = s.next(ctx, false)
 		s.ignoreLines--
 	}
-	return s.next(ctx, true)
+
+	if !s.headerBound && s.parser.HeaderAware() {
+		s.headerBound = true
+
+		if !s.next(ctx, false) {
+			return false
+		}
+
+		parser, err := s.parser.BindHeader(s.inner.Data())
+		if err != nil {
+			s.err = err
+			return true
+		}
+
+		s.parser = parser
+	}
+
+	for s.next(ctx, true) {
+		if s.err == nil {
+			return true
+		}
+
+		if s.err = s.recordErrs.handle(s.lines, s.inner.Data(), s.err); s.err != nil {
+			return true
+		}
+	}
+
+	return false
 }
 
 func (s *CSVStream[T]) Data() T {
@@ -174,13 +271,78 @@ func (s *CSVStream[T]) Err() error {
 	return s.inner.Err()
 }
 
-func NewNewLineStream(r io.Reader) Stream[[]byte] {
-	return &NewLineStream{scanner: bufio.NewScanner(r)}
+// Skipped returns the amount of rows discarded because they could not be parsed.
+func (s *CSVStream[T]) Skipped() int {
+	return s.recordErrs.skipped
+}
+
+func (s *FixedWidthStream[T]) Next(ctx context.Context) bool {
+	for ; s.ignoreLines > 0; s.ignoreLines-- {
+		if !s.inner.Next(ctx) {
+			return false
+		}
+		s.lines = lineNumber(s.inner, s.lines+1)
+	}
+
+	for s.inner.Next(ctx) {
+		s.lines = lineNumber(s.inner, s.lines+1)
+
+		var zeroed T
+		raw := s.inner.Data()
+		s.current = zeroed
+		s.err = s.parser.Parse(raw, &s.current)
+
+		if s.err == nil {
+			return true
+		}
+
+		var parseErr *csvparser.ParseError
+		if errors.As(s.err, &parseErr) {
+			parseErr.Line = s.lines
+		}
+
+		if s.err = s.recordErrs.handle(s.lines, raw, s.err); s.err != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *FixedWidthStream[T]) Data() T {
+	return s.current
+}
+
+func (s *FixedWidthStream[T]) Err() error {
+	if s.err != nil {
+		return s.err
+	}
+
+	return s.inner.Err()
+}
+
+// Skipped returns the amount of records discarded because they could not be parsed.
+func (s *FixedWidthStream[T]) Skipped() int {
+	return s.recordErrs.skipped
+}
+
+func NewNewLineStream(r io.Reader, opts ...StreamOption) Stream[[]byte] {
+	return newLineStream(r, newStreamOptions(opts).line)
 }
 
-func NewNewLineStreamFactory() StreamFactory[[]byte] {
+func newLineStream(r io.Reader, opts LineStreamOptions) *NewLineStream {
+	scanner := bufio.NewScanner(r)
+
+	initial, max := opts.buffer()
+	scanner.Buffer(make([]byte, 0, initial), max)
+	scanner.Split(opts.splitFunc())
+
+	return &NewLineStream{scanner: scanner, skipBlankLines: opts.SkipBlankLines}
+}
+
+func NewNewLineStreamFactory(opts ...StreamOption) StreamFactory[[]byte] {
 	return StreamFactoryFunc[[]byte](func(r io.Reader) Stream[[]byte] {
-		return NewNewLineStream(r)
+		return NewNewLineStream(r, opts...)
 	})
 }
 
@@ -194,29 +356,76 @@ func NewProxyStreamFactory(bufferSize int) StreamFactory[[]byte] {
 	})
 }
 
-func NewJSONEachRowStream[T any](r io.Reader) Stream[T] {
+func NewJSONEachRowStream[T any](r io.Reader, opts ...StreamOption) Stream[T] {
+	o := newStreamOptions(opts)
 	return &JSONEachRowStream[T]{
-		inner: NewNewLineStream(r),
+		inner:      newLineStream(r, o.line),
+		recordErrs: newRecordErrorHandler(o.recordErrors),
 	}
 }
 
-func NewCSVStream[T any](r io.Reader, ignoreLines int, parser csvparser.Parser[T]) Stream[T] {
+// NewCSVStream parses every line of `r` with `parser` after skipping the first `ignoreLines`. Header
+// aware parsers are bound to the line which follows them, see csvparser.Parser.BindHeader. TSV is
+// parsed likewise by passing a csvparser.NewTSV parser.
+func NewCSVStream[T any](
+	r io.Reader,
+	ignoreLines int,
+	parser csvparser.Parser[T],
+	opts ...StreamOption,
+) Stream[T] {
+	o := newStreamOptions(opts)
+
+	if parser.RFC4180() && o.line.Split == nil && len(o.line.Delimiter) == 0 {
+		// Quoted fields may hold line breaks.
+		o.line.Split = csvparser.ScanRecords
+	}
+
 	return &CSVStream[T]{
-		inner:       NewNewLineStream(r),
+		inner:       newLineStream(r, o.line),
 		parser:      parser,
 		ignoreLines: ignoreLines,
-		ignoreErr:   false,
+		recordErrs:  newRecordErrorHandler(o.recordErrors),
 	}
 }
 
-func NewJSONEachRowStreamFactory[T any]() StreamFactory[T] {
+func NewJSONEachRowStreamFactory[T any](opts ...StreamOption) StreamFactory[T] {
+	return StreamFactoryFunc[T](func(r io.Reader) Stream[T] {
+		return NewJSONEachRowStream[T](r, opts...)
+	})
+}
+
+func NewCSVStreamFactory[T any](
+	ignoreLines int,
+	parser csvparser.Parser[T],
+	opts ...StreamOption,
+) StreamFactory[T] {
 	return StreamFactoryFunc[T](func(r io.Reader) Stream[T] {
-		return NewJSONEachRowStream[T](r)
+		return NewCSVStream[T](r, ignoreLines, parser, opts...)
 	})
 }
 
-func NewCSVStreamFactory[T any](ignoreLines int, parser csvparser.Parser[T]) StreamFactory[T] {
+// NewFixedWidthStream parses every line of `r` with `parser` after skipping the first `ignoreLines`.
+func NewFixedWidthStream[T any](
+	r io.Reader,
+	ignoreLines int,
+	parser csvparser.FixedWidthParser[T],
+	opts ...StreamOption,
+) Stream[T] {
+	o := newStreamOptions(opts)
+	return &FixedWidthStream[T]{
+		inner:       newLineStream(r, o.line),
+		parser:      parser,
+		ignoreLines: ignoreLines,
+		recordErrs:  newRecordErrorHandler(o.recordErrors),
+	}
+}
+
+func NewFixedWidthStreamFactory[T any](
+	ignoreLines int,
+	parser csvparser.FixedWidthParser[T],
+	opts ...StreamOption,
+) StreamFactory[T] {
 	return StreamFactoryFunc[T](func(r io.Reader) Stream[T] {
-		return NewCSVStream[T](r, ignoreLines, parser)
+		return NewFixedWidthStream[T](r, ignoreLines, parser, opts...)
 	})
 }
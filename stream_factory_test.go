@@ -0,0 +1,507 @@
+package withttp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/sonirico/withttp/csvparser"
+)
+
+func TestNewLineStream_Options(t *testing.T) {
+	type (
+		args struct {
+			payload string
+			opts    LineStreamOptions
+		}
+
+		want struct {
+			records []string
+			err     error
+		}
+
+		testCase struct {
+			name string
+			args args
+			want want
+		}
+	)
+
+	long := strings.Repeat("a", 2*DefaultLineStreamMaxBufferSize)
+
+	tests := []testCase{
+		{
+			name: "default options split on new lines and drop carriage returns",
+			args: args{
+				payload: "a\r\nb\nc",
+			},
+			want: want{
+				records: []string{"a", "b", "c"},
+			},
+		},
+		{
+			name: "default options keep blank lines",
+			args: args{
+				payload: "a\n\nb\n",
+			},
+			want: want{
+				records: []string{"a", "", "b"},
+			},
+		},
+		{
+			name: "skip blank lines",
+			args: args{
+				payload: "a\n\n  \t\nb\n\n",
+				opts:    LineStreamOptions{SkipBlankLines: true},
+			},
+			want: want{
+				records: []string{"a", "b"},
+			},
+		},
+		{
+			name: "null byte delimiter",
+			args: args{
+				payload: "a\nb\x00c\x00",
+				opts:    LineStreamOptions{Delimiter: []byte{0}},
+			},
+			want: want{
+				records: []string{"a\nb", "c"},
+			},
+		},
+		{
+			name: "multi byte delimiter",
+			args: args{
+				payload: "a\nb\r\nc",
+				opts:    LineStreamOptions{Delimiter: []byte("\r\n")},
+			},
+			want: want{
+				records: []string{"a\nb", "c"},
+			},
+		},
+		{
+			name: "record separator delimiter with trimmed carriage returns",
+			args: args{
+				payload: "a\r\x1eb\x1ec\r",
+				opts:    LineStreamOptions{Delimiter: []byte{0x1e}, TrimCR: true},
+			},
+			want: want{
+				records: []string{"a", "b", "c"},
+			},
+		},
+		{
+			name: "custom split function",
+			args: args{
+				payload: "a b  c",
+				opts:    LineStreamOptions{Split: bufio.ScanWords},
+			},
+			want: want{
+				records: []string{"a", "b", "c"},
+			},
+		},
+		{
+			name: "lines longer than the default buffer fail by default",
+			args: args{
+				payload: long + "\nb",
+			},
+			want: want{
+				records: []string{},
+				err:     bufio.ErrTooLong,
+			},
+		},
+		{
+			name: "lines longer than the default buffer with bigger max buffer",
+			args: args{
+				payload: long + "\nb",
+				opts:    LineStreamOptions{MaxBufferSize: 4 * DefaultLineStreamMaxBufferSize},
+			},
+			want: want{
+				records: []string{long, "b"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stream := NewNewLineStream(strings.NewReader(test.args.payload), test.args.opts)
+
+			records := make([]string, 0)
+			for stream.Next(context.TODO()) {
+				records = append(records, string(stream.Data()))
+			}
+
+			if !assertError(t, test.want.err, stream.Err()) {
+				t.FailNow()
+			}
+
+			if !reflect.DeepEqual(test.want.records, records) {
+				t.Errorf("unexpected records\nwant %q\nhave %q",
+					test.want.records, records)
+			}
+		})
+	}
+}
+
+func TestJSONEachRowStream_SkipBlankLines(t *testing.T) {
+	type row struct {
+		ID int `json:"id"`
+	}
+
+	payload := "{\"id\":1}\r\n\r\n{\"id\":2}\n"
+	rows := make([]row, 0)
+
+	err := ReadStream[row](
+		io.NopCloser(strings.NewReader(payload)),
+		NewJSONEachRowStreamFactory[row](LineStreamOptions{SkipBlankLines: true}),
+		func(r row) bool {
+			rows = append(rows, r)
+			return true
+		},
+	)
+
+	if !assertError(t, nil, err) {
+		t.FailNow()
+	}
+
+	if !reflect.DeepEqual([]row{{ID: 1}, {ID: 2}}, rows) {
+		t.Errorf("unexpected rows, have %v", rows)
+	}
+}
+
+func TestStream_RecordErrors(t *testing.T) {
+	type (
+		row struct {
+			ID   int    `json:"id"`
+			Name string `json:"name"`
+		}
+
+		deadLetter struct {
+			line int
+			raw  string
+		}
+
+		args struct {
+			factory StreamFactory[row]
+			payload string
+		}
+
+		want struct {
+			rows        []row
+			deadLetters []deadLetter
+			err         error
+		}
+
+		testCase struct {
+			name string
+			args args
+			want want
+		}
+	)
+
+	var deadLetters []deadLetter
+
+	onRecordError := func(lineNo int, raw []byte, _ error) {
+		deadLetters = append(deadLetters, deadLetter{line: lineNo, raw: string(raw)})
+	}
+
+	csvParser := csvparser.New[row](
+		csvparser.SeparatorComma,
+		csvparser.IntCol[row](csvparser.QuoteNone, nil, func(x *row, v int) { x.ID = v }),
+		csvparser.StringCol[row](csvparser.QuoteNone, nil, func(x *row, v string) { x.Name = v }),
+	)
+
+	tests := []testCase{
+		{
+			name: "json each row fails on the first malformed record by default",
+			args: args{
+				factory: NewJSONEachRowStreamFactory[row](),
+				payload: "{\"id\":1}\n{\"id\":\n{\"id\":3}",
+			},
+			want: want{
+				rows: []row{{ID: 1}},
+				err:  &json.SyntaxError{},
+			},
+		},
+		{
+			name: "json each row skips malformed records",
+			args: args{
+				factory: NewJSONEachRowStreamFactory[row](
+					RecordErrorOptions{OnRecordError: onRecordError},
+				),
+				payload: "{\"id\":1,\"name\":\"a\"}\n{\"id\":\n{\"id\":\"3\"}\n{\"id\":4}",
+			},
+			want: want{
+				rows: []row{{ID: 1, Name: "a"}, {ID: 4}},
+				deadLetters: []deadLetter{
+					{line: 2, raw: "{\"id\":"},
+					{line: 3, raw: "{\"id\":\"3\"}"},
+				},
+			},
+		},
+		{
+			name: "json each row reports physical line numbers when skipping blank lines",
+			args: args{
+				factory: NewJSONEachRowStreamFactory[row](
+					LineStreamOptions{SkipBlankLines: true},
+					RecordErrorOptions{OnRecordError: onRecordError},
+				),
+				payload: "{\"id\":1}\n\n\n{\"id\":\n{\"id\":2}",
+			},
+			want: want{
+				rows:        []row{{ID: 1}, {ID: 2}},
+				deadLetters: []deadLetter{{line: 4, raw: "{\"id\":"}},
+			},
+		},
+		{
+			name: "json each row aborts once the error budget is exceeded",
+			args: args{
+				factory: NewJSONEachRowStreamFactory[row](
+					RecordErrorOptions{MaxErrors: 1, OnRecordError: onRecordError},
+				),
+				payload: "{\"id\":1}\n{\n{\"id\":2}\n{\n{\"id\":3}",
+			},
+			want: want{
+				rows:        []row{{ID: 1}, {ID: 2}},
+				deadLetters: []deadLetter{{line: 2, raw: "{"}, {line: 4, raw: "{"}},
+				err:         ErrTooManyRecordErrors,
+			},
+		},
+		{
+			name: "csv skips rows which fail to parse",
+			args: args{
+				factory: NewCSVStreamFactory[row](1, csvParser,
+					RecordErrorOptions{MaxErrors: 2, OnRecordError: onRecordError},
+				),
+				payload: "id,name\n1,a\nx,b\n3,c",
+			},
+			want: want{
+				rows:        []row{{ID: 1, Name: "a"}, {ID: 3, Name: "c"}},
+				deadLetters: []deadLetter{{line: 3, raw: "x,b"}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			deadLetters = nil
+			rows := make([]row, 0)
+
+			err := ReadStream[row](
+				io.NopCloser(strings.NewReader(test.args.payload)),
+				test.args.factory,
+				func(r row) bool {
+					rows = append(rows, r)
+					return true
+				},
+			)
+
+			var syntaxErr *json.SyntaxError
+			switch {
+			case errors.As(test.want.err, &syntaxErr):
+				if !errors.As(err, &syntaxErr) {
+					t.Fatalf("unexpected error, want syntax error, have %v", err)
+				}
+			case test.want.err != nil:
+				if !errors.Is(err, test.want.err) {
+					t.Fatalf("unexpected error, want %v, have %v", test.want.err, err)
+				}
+			case err != nil:
+				t.Fatalf("unexpected error, want none, have %v", err)
+			}
+
+			if !reflect.DeepEqual(test.want.rows, rows) {
+				t.Errorf("unexpected rows\nwant %v\nhave %v", test.want.rows, rows)
+			}
+
+			if !reflect.DeepEqual(test.want.deadLetters, deadLetters) {
+				t.Errorf("unexpected dead letters\nwant %v\nhave %v",
+					test.want.deadLetters, deadLetters)
+			}
+		})
+	}
+}
+
+func TestCSVStream_RFC4180(t *testing.T) {
+	type row struct {
+		ID    int
+		Notes string
+	}
+
+	parser := csvparser.NewRFC4180[row](
+		csvparser.SeparatorComma,
+		csvparser.IntCol[row](csvparser.QuoteNone, nil, func(x *row, v int) { x.ID = v }),
+		csvparser.StringCol[row](csvparser.QuoteDouble, nil, func(x *row, v string) { x.Notes = v }),
+	)
+
+	payload := "id,notes\r\n1,\"first\r\nsecond, \"\"third\"\"\"\r\n2,plain\r\n3,\"broken\"quote\r\n"
+
+	rows := make([]row, 0)
+	err := ReadStream[row](
+		io.NopCloser(strings.NewReader(payload)),
+		NewCSVStreamFactory[row](1, parser),
+		func(r row) bool {
+			rows = append(rows, r)
+			return true
+		},
+	)
+
+	expected := []row{{ID: 1, Notes: "first\r\nsecond, \"third\""}, {ID: 2, Notes: "plain"}}
+	if !reflect.DeepEqual(expected, rows) {
+		t.Errorf("unexpected rows\nwant %q\nhave %q", expected, rows)
+	}
+
+	var parseErr *csvparser.ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("unexpected error, want *csvparser.ParseError, have %v", err)
+	}
+
+	if parseErr.Line != 5 || parseErr.Field != 2 || !errors.Is(err, csvparser.ErrExtraneousQuote) {
+		t.Errorf("unexpected error, want extraneous quote at line 5 field 2, have %v", parseErr)
+	}
+}
+
+func TestCSVStream_Header(t *testing.T) {
+	type row struct {
+		ID   int
+		Name string
+	}
+
+	parser := csvparser.New[row](
+		csvparser.SeparatorComma,
+		csvparser.Named[row]("id",
+			csvparser.IntCol[row](csvparser.QuoteNone, nil, func(x *row, v int) { x.ID = v })),
+		csvparser.Named[row]("name",
+			csvparser.StringCol[row](csvparser.QuoteNone, nil, func(x *row, v string) { x.Name = v }),
+			"repo_name"),
+	)
+
+	tests := []struct {
+		name    string
+		payload string
+		rows    []row
+		err     error
+	}{
+		{
+			name:    "columns are bound by the header after the ignored lines",
+			payload: "# exported at 2022-09-01\nrank,repo_name,ID\n1,freeCodeCamp,7\n2,996.ICU,3",
+			rows:    []row{{ID: 7, Name: "freeCodeCamp"}, {ID: 3, Name: "996.ICU"}},
+		},
+		{
+			name:    "missing columns",
+			payload: "# exported at 2022-09-01\nrank,ID\n1,7",
+			rows:    []row{},
+			err:     csvparser.ErrMissingColumn,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rows := make([]row, 0)
+			err := ReadStream[row](
+				io.NopCloser(strings.NewReader(test.payload)),
+				NewCSVStreamFactory[row](1, parser),
+				func(r row) bool {
+					rows = append(rows, r)
+					return true
+				},
+			)
+
+			if !errors.Is(err, test.err) {
+				t.Fatalf("unexpected error, want %v, have %v", test.err, err)
+			}
+
+			if !reflect.DeepEqual(test.rows, rows) {
+				t.Errorf("unexpected rows\nwant %v\nhave %v", test.rows, rows)
+			}
+		})
+	}
+}
+
+func TestTSVStream(t *testing.T) {
+	type row struct {
+		ID    int
+		Notes string
+	}
+
+	parser := csvparser.NewTSV[row](
+		csvparser.Named[row]("id", csvparser.IntCol[row](csvparser.QuoteNone, nil,
+			func(x *row, v int) { x.ID = v })),
+		csvparser.Named[row]("notes", csvparser.StringCol[row](csvparser.QuoteNone, nil,
+			func(x *row, v string) { x.Notes = v })),
+	)
+
+	payload := "notes\tid\n\"first\"\\tsecond\\nthird\t1\n\t2\n"
+
+	rows := make([]row, 0)
+	err := ReadStream[row](
+		io.NopCloser(strings.NewReader(payload)),
+		NewCSVStreamFactory[row](0, parser),
+		func(r row) bool {
+			rows = append(rows, r)
+			return true
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error, want none, have %v", err)
+	}
+
+	expected := []row{{ID: 1, Notes: "\"first\"\tsecond\nthird"}, {ID: 2}}
+	if !reflect.DeepEqual(expected, rows) {
+		t.Errorf("unexpected rows\nwant %q\nhave %q", expected, rows)
+	}
+}
+
+func TestFixedWidthStream(t *testing.T) {
+	type row struct {
+		Account string
+		Balance int64
+		Open    bool
+	}
+
+	parser := csvparser.NewFixedWidth[row](
+		' ',
+		csvparser.Fixed[row](0, 8, csvparser.StringCol[row](csvparser.QuoteNone, nil,
+			func(x *row, v string) { x.Account = v })),
+		csvparser.Fixed[row](8, 10, csvparser.Int64Col[row](csvparser.QuoteNone, nil,
+			func(x *row, v int64) { x.Balance = v })),
+		csvparser.Fixed[row](18, 1, csvparser.BoolCol[row](csvparser.QuoteNone, nil,
+			func(x *row, v bool) { x.Open = v }, "Y")),
+	)
+
+	payload := "" +
+		"ACCOUNT BALANCE   O\r\n" +
+		"ES12         -1500Y\r\n" +
+		"FR34     12x     N\r\n" +
+		"DE56           42\r\n"
+
+	rows := make([]row, 0)
+	var skipped []int
+
+	err := ReadStream[row](
+		io.NopCloser(strings.NewReader(payload)),
+		NewFixedWidthStreamFactory[row](1, parser, RecordErrorOptions{
+			OnRecordError: func(lineNo int, _ []byte, _ error) { skipped = append(skipped, lineNo) },
+		}),
+		func(r row) bool {
+			rows = append(rows, r)
+			return true
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error, want none, have %v", err)
+	}
+
+	expected := []row{{Account: "ES12", Balance: -1500, Open: true}, {Account: "DE56", Balance: 42}}
+	if !reflect.DeepEqual(expected, rows) {
+		t.Errorf("unexpected rows\nwant %+v\nhave %+v", expected, rows)
+	}
+
+	if !reflect.DeepEqual([]int{3}, skipped) {
+		t.Errorf("unexpected skipped lines, want [3], have %v", skipped)
+	}
+}
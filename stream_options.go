@@ -0,0 +1,166 @@
+package withttp
+
+import (
+	"bufio"
+	"bytes"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultLineStreamInitialBufferSize = 4 * 1024
+	DefaultLineStreamMaxBufferSize     = bufio.MaxScanTokenSize
+)
+
+type (
+	// StreamOption tunes the streams built by NewNewLineStream, NewJSONEachRowStream,
+	// NewCSVStream and their factories.
+	StreamOption interface {
+		configureStream(o *streamOptions)
+	}
+
+	// LineStreamOptions configures how a NewLineStream splits its reader into records.
+	// Zero values fall back to the defaults, which mimic bufio.Scanner with bufio.ScanLines.
+	LineStreamOptions struct {
+		// InitialBufferSize is the size of the buffer first allocated to hold a record.
+		InitialBufferSize int
+		// MaxBufferSize is the size of the longest record the stream is able to read. Longer ones
+		// make the stream fail with bufio.ErrTooLong.
+		MaxBufferSize int
+		// Split replaces the split function altogether. Delimiter and TrimCR are ignored when set.
+		Split bufio.SplitFunc
+		// Delimiter splits records on the given byte sequence instead of on new lines, e.g.
+		// "\r\n", "\x00" or the "\x1e" record separator.
+		Delimiter []byte
+		// TrimCR drops a trailing carriage return from every record split by Delimiter. Default
+		// new line splitting always drops it.
+		TrimCR bool
+		// SkipBlankLines discards records made only of white space.
+		SkipBlankLines bool
+	}
+
+	// RecordErrorOptions makes JSONEachRowStream and CSVStream skip records which fail to decode
+	// instead of ending the stream with an error.
+	RecordErrorOptions struct {
+		// MaxErrors is the amount of records which are allowed to be skipped. Once exceeded, the
+		// stream fails with ErrTooManyRecordErrors. Zero or less means no limit.
+		MaxErrors int
+		// OnRecordError, if set, receives every skipped record along with its line number and
+		// the decoding error, e.g. to forward them to a dead letter queue.
+		OnRecordError func(lineNo int, raw []byte, err error)
+	}
+
+	streamOptions struct {
+		line         LineStreamOptions
+		recordErrors *RecordErrorOptions
+	}
+
+	recordErrorHandler struct {
+		opts    *RecordErrorOptions
+		skipped int
+	}
+
+	liner interface {
+		Line() int
+	}
+)
+
+func (o LineStreamOptions) configureStream(opts *streamOptions) {
+	opts.line = o
+}
+
+func (o RecordErrorOptions) configureStream(opts *streamOptions) {
+	opts.recordErrors = &o
+}
+
+func (o LineStreamOptions) splitFunc() bufio.SplitFunc {
+	if o.Split != nil {
+		return o.Split
+	}
+
+	if len(o.Delimiter) > 0 {
+		return splitOnDelimiter(o.Delimiter, o.TrimCR)
+	}
+
+	return bufio.ScanLines
+}
+
+func (o LineStreamOptions) buffer() (initial, max int) {
+	initial, max = o.InitialBufferSize, o.MaxBufferSize
+	if initial <= 0 {
+		initial = DefaultLineStreamInitialBufferSize
+	}
+	if max <= 0 {
+		max = DefaultLineStreamMaxBufferSize
+	}
+	if initial > max {
+		initial = max
+	}
+	return
+}
+
+func newStreamOptions(opts []StreamOption) streamOptions {
+	o := streamOptions{}
+	for _, opt := range opts {
+		opt.configureStream(&o)
+	}
+	return o
+}
+
+func newRecordErrorHandler(opts *RecordErrorOptions) recordErrorHandler {
+	return recordErrorHandler{opts: opts}
+}
+
+// handle returns the error the stream has to stop with, if any, after `raw` failed to decode.
+func (h *recordErrorHandler) handle(lineNo int, raw []byte, err error) error {
+	if h.opts == nil {
+		return err
+	}
+
+	h.skipped++
+
+	if h.opts.OnRecordError != nil {
+		h.opts.OnRecordError(lineNo, bytes.Clone(raw), err)
+	}
+
+	if h.opts.MaxErrors > 0 && h.skipped > h.opts.MaxErrors {
+		return errors.Wrapf(ErrTooManyRecordErrors, "%d records skipped, last at line %d: %s",
+			h.skipped, lineNo, err)
+	}
+
+	return nil
+}
+
+// lineNumber returns the line number reported by the stream when available, `fallback` otherwise.
+func lineNumber(s any, fallback int) int {
+	if l, ok := s.(liner); ok {
+		return l.Line()
+	}
+	return fallback
+}
+
+func splitOnDelimiter(delim []byte, trimCR bool) bufio.SplitFunc {
+	token := func(data []byte) []byte {
+		if trimCR && len(data) > 0 && data[len(data)-1] == '\r' {
+			return data[:len(data)-1]
+		}
+		return data
+	}
+
+	return func(data []byte, atEOF bool) (advance int, tok []byte, err error) {
+		if atEOF && len(data) == 0 {
+			return 0, nil, nil
+		}
+
+		if i := bytes.Index(data, delim); i >= 0 {
+			return i + len(delim), token(data[:i]), nil
+		}
+
+		if atEOF {
+			return len(data), token(data), nil
+		}
+
+		// Request more data.
+		return 0, nil, nil
+	}
+}
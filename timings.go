@@ -0,0 +1,501 @@
+package withttp
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+const closedConnTTL = time.Minute
+
+type (
+	// Timings tells how long the phases of the last attempt at sending the request of a call took.
+	// Dialing phases are zero for reused connections. Adapters of fasthttp clients only know them
+	// if created by FasthttpTimedClient.
+	Timings struct {
+		DNS          time.Duration
+		Connect      time.Duration
+		TLSHandshake time.Duration
+		// TTFB, time to first byte, goes from requests being written until the first byte of their
+		// responses is received.
+		TTFB time.Duration
+		// BodyRead goes from the first byte of responses being received until their body is read
+		// to the end, or closed. fasthttp reads bodies before returning responses.
+		BodyRead time.Duration
+		// Total goes from the attempt starting until response bodies are read, or the call is done
+		// with.
+		Total      time.Duration
+		ConnReused bool
+	}
+
+	// timingsRecorder records the timings of the attempt in progress, as told by adapters, whose
+	// clients may tell them from other goroutines.
+	timingsRecorder struct {
+		mu sync.Mutex
+
+		attempt
+	}
+
+	attempt struct {
+		start, dnsStart, connectStart, tlsStart, wrote, firstByte time.Time
+		timings                                                   Timings
+	}
+
+	// timedBody tells the recorder once response bodies are read to the end, or closed.
+	timedBody struct {
+		io.ReadCloser
+
+		once     sync.Once
+		recorder *timingsRecorder
+	}
+
+	// timedDialer dials connections of fasthttp clients telling how long dialing took, which is
+	// matched with responses by the addresses of their connections. Connections closed are kept
+	// until their responses are matched, as when servers close them, or for closedConnTTL.
+	timedDialer struct {
+		mu    sync.Mutex
+		conns map[[2]string]*timedConn
+		// resolved caches the addresses host names resolve into, for as long as fasthttp does,
+		// see fasthttp.DefaultDNSCacheDuration.
+		resolved map[resolvedKey]resolvedAddrs
+	}
+
+	resolvedKey struct {
+		addr      string
+		dualStack bool
+	}
+
+	resolvedAddrs struct {
+		addrs []string
+		at    time.Time
+	}
+
+	// timedConn tracks when requests are written and responses start being read. Connections of
+	// fasthttp clients are used by one request at a time.
+	timedConn struct {
+		net.Conn
+
+		key [2]string
+
+		mu                         sync.Mutex
+		dns, connect, tls          time.Duration
+		used, reading              bool
+		wrote, firstByte, closedAt time.Time
+	}
+)
+
+// timingsRecorderFrom returns the recorder of the call executing, if any.
+func timingsRecorderFrom(ctx context.Context) *timingsRecorder {
+	if stats, ok := ctx.Value(callStatsKey{}).(*callStats); ok {
+		return &stats.timings
+	}
+	return nil
+}
+
+// begin starts recording another attempt.
+func (r *timingsRecorder) begin() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.attempt = attempt{start: time.Now()}
+}
+
+func (r *timingsRecorder) update(fn func(r *timingsRecorder)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	fn(r)
+}
+
+// bodyDone records responses bodies being read to the end, or closed, `at` a given time.
+func (r *timingsRecorder) bodyDone(at time.Time) {
+	r.update(func(r *timingsRecorder) {
+		if !r.firstByte.IsZero() {
+			r.timings.BodyRead = at.Sub(r.firstByte)
+		}
+		r.timings.Total = at.Sub(r.start)
+	})
+}
+
+// snapshot returns the timings recorded so far, the attempt being in progress until its response
+// body is read.
+func (r *timingsRecorder) snapshot() Timings {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	timings := r.timings
+	if timings.Total == 0 && !r.start.IsZero() {
+		timings.Total = time.Since(r.start)
+	}
+	return timings
+}
+
+// clientTrace records the timings of net/http clients.
+func (r *timingsRecorder) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			r.update(func(r *timingsRecorder) { r.dnsStart = time.Now() })
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			r.update(func(r *timingsRecorder) { r.timings.DNS = time.Since(r.dnsStart) })
+		},
+		ConnectStart: func(string, string) {
+			r.update(func(r *timingsRecorder) {
+				if r.connectStart.IsZero() {
+					r.connectStart = time.Now()
+				}
+			})
+		},
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				r.update(func(r *timingsRecorder) { r.timings.Connect = time.Since(r.connectStart) })
+			}
+		},
+		TLSHandshakeStart: func() {
+			r.update(func(r *timingsRecorder) { r.tlsStart = time.Now() })
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			r.update(func(r *timingsRecorder) { r.timings.TLSHandshake = time.Since(r.tlsStart) })
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			r.update(func(r *timingsRecorder) { r.timings.ConnReused = info.Reused })
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			r.update(func(r *timingsRecorder) { r.wrote = time.Now() })
+		},
+		GotFirstResponseByte: func() {
+			r.update(func(r *timingsRecorder) {
+				r.firstByte = time.Now()
+				if !r.wrote.IsZero() {
+					r.timings.TTFB = r.firstByte.Sub(r.wrote)
+				}
+			})
+		},
+	}
+}
+
+func (b *timedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.done()
+	}
+	return n, err
+}
+
+func (b *timedBody) Close() error {
+	b.done()
+	return b.ReadCloser.Close()
+}
+
+func (b *timedBody) done() {
+	b.once.Do(func() { b.recorder.bodyDone(time.Now()) })
+}
+
+// FasthttpTimedClient creates a fasthttp adapter whose calls tell the timings of dialing phases
+// and TTFB. Connections of `cli` are dialed by the adapter, which resolves host names, caching
+// them as fasthttp does, connects and performs TLS handshakes itself, hence `cli` is not to be
+// shared with other adapters.
+func FasthttpTimedClient(cli *fasthttp.Client) *FastHttpHttpClientAdapter {
+	dialer := &timedDialer{
+		conns:    make(map[[2]string]*timedConn),
+		resolved: make(map[resolvedKey]resolvedAddrs),
+	}
+
+	configure := cli.ConfigureClient
+	cli.ConfigureClient = func(hc *fasthttp.HostClient) error {
+		if configure != nil {
+			if err := configure(hc); err != nil {
+				return err
+			}
+		}
+
+		hc.Dial = dialer.dial(hc)
+		return nil
+	}
+
+	adapter := newFastHttpHttpClientAdapter(cli)
+	adapter.dialer = dialer
+	return adapter
+}
+
+// dial dials connections of `hc`, through its own dialer, if any, which then resolves host names.
+func (d *timedDialer) dial(hc *fasthttp.HostClient) fasthttp.DialFunc {
+	next, resolves := hc.Dial, hc.Dial == nil
+	if resolves {
+		next = fasthttp.Dial
+		if hc.DialDualStack {
+			next = fasthttp.DialDualStack
+		}
+	}
+
+	var (
+		tlsOnce   sync.Once
+		tlsConfig *tls.Config
+	)
+
+	return func(addr string) (net.Conn, error) {
+		var (
+			conn  = &timedConn{}
+			start = time.Now()
+			addrs = []string{addr}
+			raw   net.Conn
+			err   error
+		)
+
+		if resolves {
+			resolved, took, err := d.resolve(addr, hc.DialDualStack)
+			if err != nil {
+				return nil, err
+			}
+			if resolved != nil {
+				addrs, conn.dns, start = resolved, took, time.Now()
+			}
+		}
+
+		// Addresses are tried in turn, as fasthttp does.
+		for _, addr := range addrs {
+			if raw, err = next(addr); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		conn.connect = time.Since(start)
+		conn.Conn = raw
+
+		// TLS connections are returned as such, lest fasthttp performed handshakes again.
+		dialed := net.Conn(conn)
+		if _, ok := raw.(*tls.Conn); ok {
+			// Dialed over TLS already, by the dialer of hc, whose reads, writes and closes go
+			// unobserved. Connections are then deemed closed, hence forgotten once first used.
+			dialed, conn.closedAt = raw, time.Now()
+		} else if hc.IsTLS {
+			tlsOnce.Do(func() { tlsConfig = clientTLSConfig(hc.TLSConfig, hc.Addr) })
+
+			if dialed, err = handshake(conn, tlsConfig, hc.WriteTimeout); err != nil {
+				return nil, err
+			}
+			conn.tls = time.Since(start) - conn.connect
+		}
+
+		conn.key = [2]string{raw.LocalAddr().String(), raw.RemoteAddr().String()}
+		d.register(conn)
+
+		return dialed, nil
+	}
+}
+
+// register keeps track of `conn`, forgetting connections closed long ago.
+func (d *timedDialer) register(conn *timedConn) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for key, c := range d.conns {
+		c.mu.Lock()
+		if !c.closedAt.IsZero() && time.Since(c.closedAt) > closedConnTTL {
+			delete(d.conns, key)
+		}
+		c.mu.Unlock()
+	}
+
+	d.conns[conn.key] = conn
+}
+
+// record tells `r` the timings of the connection the response was received through, which are
+// then reported as those of a reused connection.
+func (d *timedDialer) record(r *timingsRecorder, res *fasthttp.Response) {
+	if res.LocalAddr() == nil || res.RemoteAddr() == nil {
+		return
+	}
+
+	key := [2]string{res.LocalAddr().String(), res.RemoteAddr().String()}
+
+	d.mu.Lock()
+	conn := d.conns[key]
+	d.mu.Unlock()
+
+	if conn == nil {
+		return
+	}
+
+	// Locks are taken in turn, never one within the other, as register locks connections within
+	// the dialer.
+	conn.mu.Lock()
+	used, closed := conn.used, !conn.closedAt.IsZero()
+	dns, connect, tlsTook := conn.dns, conn.connect, conn.tls
+	wrote, firstByte := conn.wrote, conn.firstByte
+	conn.used = true
+	conn.mu.Unlock()
+
+	if closed {
+		d.mu.Lock()
+		if d.conns[key] == conn {
+			delete(d.conns, key)
+		}
+		d.mu.Unlock()
+	}
+
+	r.update(func(r *timingsRecorder) {
+		r.timings.ConnReused = used
+		if !used {
+			r.timings.DNS, r.timings.Connect, r.timings.TLSHandshake = dns, connect, tlsTook
+		}
+		if !wrote.IsZero() && !firstByte.IsZero() {
+			r.firstByte = firstByte
+			r.timings.TTFB = firstByte.Sub(wrote)
+		}
+	})
+}
+
+func (c *timedConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+
+	c.mu.Lock()
+	if c.reading {
+		// Another request on the same connection.
+		c.reading, c.firstByte = false, time.Time{}
+	}
+	c.wrote = time.Now()
+	c.mu.Unlock()
+
+	return n, err
+}
+
+func (c *timedConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+
+	if n > 0 {
+		c.mu.Lock()
+		if !c.reading {
+			c.reading, c.firstByte = true, time.Now()
+		}
+		c.mu.Unlock()
+	}
+
+	return n, err
+}
+
+func (c *timedConn) Close() error {
+	c.mu.Lock()
+	c.closedAt = time.Now()
+	c.mu.Unlock()
+
+	return c.Conn.Close()
+}
+
+// resolve resolves the host of `addr` as the package level resolve does, caching addresses, and
+// tells how long resolving took, which is nothing for addresses cached.
+func (d *timedDialer) resolve(addr string, dualStack bool) ([]string, time.Duration, error) {
+	key := resolvedKey{addr: addr, dualStack: dualStack}
+
+	d.mu.Lock()
+	cached, ok := d.resolved[key]
+	d.mu.Unlock()
+
+	if ok && time.Since(cached.at) < fasthttp.DefaultDNSCacheDuration {
+		return cached.addrs, 0, nil
+	}
+
+	// Bounded as dialing is, lest requests hung on resolvers past their timeouts.
+	ctx, cancel := context.WithTimeout(context.Background(), fasthttp.DefaultDialTimeout)
+	defer cancel()
+
+	start := time.Now()
+	addrs, err := resolve(ctx, addr, dualStack)
+	if err != nil || addrs == nil {
+		return addrs, 0, err
+	}
+	took := time.Since(start)
+
+	d.mu.Lock()
+	d.resolved[key] = resolvedAddrs{addrs: addrs, at: time.Now()}
+	d.mu.Unlock()
+
+	return addrs, took, nil
+}
+
+// resolve resolves the host of `addr` into the addresses to dial, IPv4 ones unless `dualStack`, or
+// none if it is an IP address already.
+func resolve(ctx context.Context, addr string, dualStack bool) ([]string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if net.ParseIP(host) != nil {
+		return nil, nil
+	}
+
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		if dualStack || ip.IP.To4() != nil {
+			addrs = append(addrs, net.JoinHostPort(ip.IP.String(), port))
+		}
+	}
+
+	if len(addrs) == 0 {
+		return nil, &net.DNSError{Err: "no IPv4 addresses found", Name: host, IsNotFound: true}
+	}
+
+	return addrs, nil
+}
+
+// clientTLSConfig clones `cfg` as fasthttp does, verifying certificates against the host dialed.
+func clientTLSConfig(cfg *tls.Config, hostAddr string) *tls.Config {
+	if cfg == nil {
+		cfg = &tls.Config{}
+	} else {
+		cfg = cfg.Clone()
+	}
+
+	if cfg.ClientSessionCache == nil {
+		cfg.ClientSessionCache = tls.NewLRUClientSessionCache(0)
+	}
+
+	if cfg.ServerName == "" {
+		host, _, err := net.SplitHostPort(hostAddr)
+		if err != nil {
+			host = hostAddr
+		}
+		cfg.ServerName = host
+	}
+
+	return cfg
+}
+
+func handshake(raw net.Conn, cfg *tls.Config, timeout time.Duration) (*tls.Conn, error) {
+	if timeout <= 0 {
+		timeout = fasthttp.DefaultDialTimeout
+	}
+
+	conn := tls.Client(raw, cfg)
+
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		_ = raw.Close()
+		return nil, err
+	}
+
+	if err := conn.Handshake(); err != nil {
+		_ = raw.Close()
+		return nil, err
+	}
+
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		_ = raw.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
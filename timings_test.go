@@ -0,0 +1,222 @@
+package withttp
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestTimings(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		_, _ = w.Write([]byte(`{"id":1}`))
+	})
+
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	tlsSrv := httptest.NewTLSServer(handler)
+	defer tlsSrv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(tlsSrv.Certificate())
+
+	type (
+		args struct {
+			cli Client
+			url string
+		}
+
+		want struct {
+			dns, tls, dials bool
+		}
+
+		testCase struct {
+			name string
+			args args
+			want want
+		}
+	)
+
+	tests := []testCase{
+		{
+			name: "net/http",
+			args: args{cli: NetHttpClient(&http.Client{}), url: srv.URL},
+			want: want{dials: true},
+		},
+		{
+			name: "net/http resolving",
+			args: args{
+				cli: NetHttpClient(&http.Client{}),
+				url: strings.Replace(srv.URL, "127.0.0.1", "localhost", 1),
+			},
+			want: want{dns: true, dials: true},
+		},
+		{
+			name: "net/http over tls",
+			args: args{cli: NetHttpClient(tlsSrv.Client()), url: tlsSrv.URL},
+			want: want{tls: true, dials: true},
+		},
+		{
+			name: "fasthttp",
+			args: args{cli: Fasthttp(), url: srv.URL},
+		},
+		{
+			name: "fasthttp timed",
+			args: args{cli: FasthttpTimedClient(&fasthttp.Client{}), url: srv.URL},
+			want: want{dials: true},
+		},
+		{
+			name: "fasthttp timed resolving",
+			args: args{
+				cli: FasthttpTimedClient(&fasthttp.Client{}),
+				url: strings.Replace(srv.URL, "127.0.0.1", "localhost", 1),
+			},
+			want: want{dns: true, dials: true},
+		},
+		{
+			name: "fasthttp timed over tls",
+			args: args{
+				cli: FasthttpTimedClient(&fasthttp.Client{TLSConfig: &tls.Config{RootCAs: roots}}),
+				url: tlsSrv.URL,
+			},
+			want: want{tls: true, dials: true},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for i, reused := range []bool{false, true} {
+				call := NewCall[any](test.args.cli).
+					URL(test.args.url).
+					ExpectedStatusCodes(http.StatusOK).
+					ReadBody()
+				if err := call.Call(context.TODO()); err != nil {
+					t.Fatalf("unexpected error, want none, have %v", err)
+				}
+
+				timings := call.Timings
+				dialed := test.want.dials && !reused
+
+				if timings.ConnReused != (test.want.dials && reused) {
+					t.Errorf("call %d: unexpected reuse, have %+v", i, timings)
+				}
+				if (timings.DNS > 0) != (test.want.dns && dialed) {
+					t.Errorf("call %d: unexpected dns, have %+v", i, timings)
+				}
+				if (timings.Connect > 0) != dialed {
+					t.Errorf("call %d: unexpected connect, have %+v", i, timings)
+				}
+				if (timings.TLSHandshake > 0) != (test.want.tls && dialed) {
+					t.Errorf("call %d: unexpected tls handshake, have %+v", i, timings)
+				}
+				if (timings.TTFB >= 10*time.Millisecond) != test.want.dials {
+					t.Errorf("call %d: unexpected ttfb, have %+v", i, timings)
+				}
+				if timings.Total < 10*time.Millisecond {
+					t.Errorf("call %d: unexpected total, have %+v", i, timings)
+				}
+			}
+		})
+	}
+}
+
+func TestTimings_FasthttpResolvedCached(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"id":1}`))
+	}))
+	defer srv.Close()
+
+	fc := &fasthttp.Client{}
+	cli := FasthttpTimedClient(fc)
+	url := strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)
+
+	for i, cached := range []bool{false, true} {
+		// Connections are dialed again, host names being resolved out of the cache.
+		fc.CloseIdleConnections()
+
+		call := NewCall[any](cli).URL(url).ExpectedStatusCodes(http.StatusOK)
+		if err := call.Call(context.TODO()); err != nil {
+			t.Fatalf("unexpected error, want none, have %v", err)
+		}
+
+		if timings := call.Timings; (timings.DNS > 0) == cached || timings.Connect <= 0 {
+			t.Errorf("call %d: unexpected dialing timings, have %+v", i, timings)
+		}
+	}
+}
+
+func TestTimings_FasthttpConnectionsClosed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		// Every connection is closed once responded, while others are dialed.
+		w.Header().Set("connection", "close")
+		_, _ = w.Write([]byte(`{"id":1}`))
+	}))
+	defer srv.Close()
+
+	cli := FasthttpTimedClient(&fasthttp.Client{})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 16; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 16; j++ {
+					call := NewCall[any](cli).URL(srv.URL).ExpectedStatusCodes(http.StatusOK)
+					if err := call.Call(context.TODO()); err != nil {
+						t.Errorf("unexpected error, want none, have %v", err)
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("unexpected calls still running, want them done")
+	}
+}
+
+func TestTimings_FasthttpDialedOverTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"id":1}`))
+	}))
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+
+	fc := &fasthttp.Client{
+		ConfigureClient: func(hc *fasthttp.HostClient) error {
+			hc.Dial = func(addr string) (net.Conn, error) {
+				return tls.Dial("tcp", addr, &tls.Config{RootCAs: roots})
+			}
+			return nil
+		},
+	}
+
+	call := NewCall[any](FasthttpTimedClient(fc)).URL(srv.URL).ExpectedStatusCodes(http.StatusOK)
+	if err := call.Call(context.TODO()); err != nil {
+		t.Fatalf("unexpected error, want none, have %v", err)
+	}
+
+	if timings := call.Timings; timings.Connect <= 0 {
+		t.Errorf("unexpected dialing timings, have %+v", timings)
+	}
+}
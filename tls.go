@@ -0,0 +1,266 @@
+package withttp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/valyala/fasthttp"
+)
+
+var (
+	ErrNoCertificates         = errors.New("no certificates found")
+	ErrInvalidPin             = errors.New("invalid certificate pin")
+	ErrCertificatePinMismatch = errors.New("certificate pin mismatch")
+)
+
+type (
+	// TLSTransport builds TLS configurations, such as those of mutual TLS against services with
+	// certificates issued by private CAs, and the adapters using them. Errors found along the way
+	// are reported once built.
+	TLSTransport struct {
+		certs      []tls.Certificate
+		reloader   *certReloader
+		roots      *x509.CertPool
+		minVersion uint16
+		serverName string
+		pins       [][]byte
+
+		err error
+	}
+
+	// certReloader loads a key pair from disk, and again whenever either file is modified, so that
+	// certificates can be rotated without restarting. Should reloading fail, the last key pair
+	// loaded keeps being used.
+	certReloader struct {
+		certFile, keyFile string
+
+		mu      sync.Mutex
+		cert    *tls.Certificate
+		modTime time.Time
+	}
+)
+
+// NewTLSTransport creates a TLS transport builder defaulting to TLS 1.2 and system root CAs.
+func NewTLSTransport() *TLSTransport {
+	return &TLSTransport{minVersion: tls.VersionTLS12}
+}
+
+// ClientCert presents the PEM encoded certificate chain and private key to servers asking for them.
+func (t *TLSTransport) ClientCert(certPEM, keyPEM []byte) *TLSTransport {
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return t.fail(err)
+	}
+
+	t.certs = append(t.certs, cert)
+	return t
+}
+
+// ClientCertFile presents the PEM encoded certificate chain and private key found at the given
+// paths to servers asking for them. Files are reloaded as soon as they change, as noticed on the
+// next handshake, which makes it replace any other client certificate. TLS session resumption is
+// disabled for rotated certificates to be presented right away.
+func (t *TLSTransport) ClientCertFile(certFile, keyFile string) *TLSTransport {
+	reloader := &certReloader{certFile: certFile, keyFile: keyFile}
+	if _, err := reloader.load(); err != nil {
+		return t.fail(err)
+	}
+
+	t.reloader = reloader
+	return t
+}
+
+// RootCAs trusts the PEM encoded certificates instead of the system root CAs.
+func (t *TLSTransport) RootCAs(pemCerts ...[]byte) *TLSTransport {
+	if t.roots == nil {
+		t.roots = x509.NewCertPool()
+	}
+
+	for _, pem := range pemCerts {
+		if !t.roots.AppendCertsFromPEM(pem) {
+			return t.fail(ErrNoCertificates)
+		}
+	}
+
+	return t
+}
+
+// RootCAFile trusts the PEM encoded certificates found at the given paths instead of the system
+// root CAs.
+func (t *TLSTransport) RootCAFile(paths ...string) *TLSTransport {
+	for _, path := range paths {
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			return t.fail(err)
+		}
+
+		if t.RootCAs(pem); t.err != nil {
+			t.err = errors.Wrapf(t.err, "got: '%s'", path)
+			return t
+		}
+	}
+
+	return t
+}
+
+// MinVersion sets the minimum TLS version accepted, such as tls.VersionTLS13.
+func (t *TLSTransport) MinVersion(version uint16) *TLSTransport {
+	t.minVersion = version
+	return t
+}
+
+// ServerName overrides the name certificates of servers are verified against, also sent as SNI,
+// which otherwise is the host of the request URL.
+func (t *TLSTransport) ServerName(name string) *TLSTransport {
+	t.serverName = name
+	return t
+}
+
+// PinSHA256 pins the certificates of servers, whose chain must hold a certificate whose public key
+// SHA-256, base64 encoded, is among `pins`, as in:
+//
+//	openssl x509 -in cert.pem -pubkey -noout | openssl pkey -pubin -outform der |
+//		openssl dgst -sha256 -binary | base64
+func (t *TLSTransport) PinSHA256(pins ...string) *TLSTransport {
+	for _, pin := range pins {
+		digest, err := base64.StdEncoding.DecodeString(pin)
+		if err != nil || len(digest) != sha256.Size {
+			return t.fail(errors.Wrapf(ErrInvalidPin, "got: '%s'", pin))
+		}
+
+		t.pins = append(t.pins, digest)
+	}
+
+	return t
+}
+
+// Config returns the TLS configuration built so far.
+func (t *TLSTransport) Config() (*tls.Config, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+
+	cfg := &tls.Config{
+		Certificates: t.certs,
+		RootCAs:      t.roots,
+		MinVersion:   t.minVersion,
+		ServerName:   t.serverName,
+	}
+
+	if t.reloader != nil {
+		cfg.GetClientCertificate = t.reloader.getClientCertificate
+		// Resumed sessions would keep authenticating with the certificate they were created with.
+		cfg.SessionTicketsDisabled = true
+	}
+
+	if len(t.pins) > 0 {
+		cfg.VerifyConnection = t.verifyPins
+	}
+
+	return cfg, nil
+}
+
+// NetHttp creates a net/http adapter whose transport, otherwise as http.DefaultTransport, uses the
+// TLS configuration built so far.
+func (t *TLSTransport) NetHttp() (*NativeHttpClientAdapter, error) {
+	cfg, err := t.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = cfg
+
+	return NetHttpClient(&http.Client{Transport: transport}), nil
+}
+
+// Fasthttp creates a fasthttp adapter using the TLS configuration built so far.
+func (t *TLSTransport) Fasthttp() (*FastHttpHttpClientAdapter, error) {
+	cfg, err := t.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	return FasthttpClient(&fasthttp.Client{TLSConfig: cfg}), nil
+}
+
+func (t *TLSTransport) fail(err error) *TLSTransport {
+	if t.err == nil {
+		t.err = err
+	}
+	return t
+}
+
+// verifyPins runs once the chain of the server is verified, hence pins are checked against it.
+func (t *TLSTransport) verifyPins(cs tls.ConnectionState) error {
+	for _, chain := range cs.VerifiedChains {
+		for _, cert := range chain {
+			digest := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+
+			for _, pin := range t.pins {
+				if bytes.Equal(digest[:], pin) {
+					return nil
+				}
+			}
+		}
+	}
+
+	return errors.Wrapf(ErrCertificatePinMismatch, "got: '%s'", cs.ServerName)
+}
+
+// load returns the key pair, which is read again from disk if modified since last loaded.
+func (r *certReloader) load() (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	modTime, err := r.lastModified()
+	if err != nil {
+		return r.cert, err
+	}
+
+	if r.cert != nil && !modTime.After(r.modTime) {
+		return r.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return r.cert, err
+	}
+
+	r.cert, r.modTime = &cert, modTime
+	return r.cert, nil
+}
+
+func (r *certReloader) lastModified() (time.Time, error) {
+	var latest time.Time
+
+	for _, path := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return latest, err
+		}
+
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+
+	return latest, nil
+}
+
+func (r *certReloader) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	cert, err := r.load()
+	if cert != nil {
+		// Last key pair loaded is kept until files are fixed.
+		return cert, nil
+	}
+	return nil, err
+}
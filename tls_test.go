@@ -0,0 +1,312 @@
+package withttp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func newTestCert(
+	t *testing.T,
+	name string,
+	parent *testCert,
+	configure func(*x509.Certificate),
+) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	configure(template)
+
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func (c *testCert) pin() string {
+	digest := sha256.Sum256(c.cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(digest[:])
+}
+
+func TestTLSTransport(t *testing.T) {
+	ca := newTestCert(t, "ca", nil, func(c *x509.Certificate) {
+		c.IsCA, c.BasicConstraintsValid = true, true
+	})
+
+	server := newTestCert(t, "server", ca, func(c *x509.Certificate) {
+		c.DNSNames = []string{"internal.example"}
+		c.IPAddresses = []net.IP{net.IPv4(127, 0, 0, 1)}
+		c.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	})
+
+	newClientCert := func(name string) *testCert {
+		return newTestCert(t, name, ca, func(c *x509.Certificate) {
+			c.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+		})
+	}
+
+	alice, bob := newClientCert("alice"), newClientCert("bob")
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// New connections, hence handshakes, for every request.
+		w.Header().Set("connection", "close")
+		w.Header().Set("x-client", r.TLS.PeerCertificates[0].Subject.CommonName)
+		w.WriteHeader(http.StatusOK)
+	}))
+	srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{server.cert.Raw}, PrivateKey: server.key}},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}
+	// Failed handshakes are expected.
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+
+	writeClientCert := func(c *testCert, modTime time.Time) {
+		for path, data := range map[string][]byte{certFile: c.certPEM, keyFile: c.keyPEM} {
+			if err := os.WriteFile(path, data, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chtimes(path, modTime, modTime); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	type (
+		args struct {
+			transport func() *TLSTransport
+		}
+
+		want struct {
+			client string
+			err    error
+			failed bool
+		}
+
+		testCase struct {
+			name string
+			args args
+			want want
+		}
+	)
+
+	tests := []testCase{
+		{
+			name: "mutual tls",
+			args: args{
+				transport: func() *TLSTransport {
+					return NewTLSTransport().ClientCert(alice.certPEM, alice.keyPEM).RootCAs(ca.certPEM)
+				},
+			},
+			want: want{client: "alice"},
+		},
+		{
+			name: "missing client certificate",
+			args: args{
+				transport: func() *TLSTransport { return NewTLSTransport().RootCAs(ca.certPEM) },
+			},
+			want: want{failed: true},
+		},
+		{
+			name: "untrusted server",
+			args: args{
+				transport: func() *TLSTransport {
+					return NewTLSTransport().ClientCert(alice.certPEM, alice.keyPEM)
+				},
+			},
+			want: want{failed: true},
+		},
+		{
+			name: "server name override",
+			args: args{
+				transport: func() *TLSTransport {
+					return NewTLSTransport().
+						ClientCert(alice.certPEM, alice.keyPEM).
+						RootCAs(ca.certPEM).
+						ServerName("internal.example").
+						MinVersion(tls.VersionTLS13)
+				},
+			},
+			want: want{client: "alice"},
+		},
+		{
+			name: "server name mismatch",
+			args: args{
+				transport: func() *TLSTransport {
+					return NewTLSTransport().
+						ClientCert(alice.certPEM, alice.keyPEM).
+						RootCAs(ca.certPEM).
+						ServerName("other.example")
+				},
+			},
+			want: want{failed: true},
+		},
+		{
+			name: "pinned ca",
+			args: args{
+				transport: func() *TLSTransport {
+					return NewTLSTransport().
+						ClientCert(alice.certPEM, alice.keyPEM).
+						RootCAs(ca.certPEM).
+						PinSHA256(ca.pin())
+				},
+			},
+			want: want{client: "alice"},
+		},
+		{
+			name: "pin mismatch",
+			args: args{
+				transport: func() *TLSTransport {
+					return NewTLSTransport().
+						ClientCert(alice.certPEM, alice.keyPEM).
+						RootCAs(ca.certPEM).
+						PinSHA256(alice.pin())
+				},
+			},
+			want: want{err: ErrCertificatePinMismatch},
+		},
+		{
+			name: "invalid pin",
+			args: args{
+				transport: func() *TLSTransport { return NewTLSTransport().PinSHA256("c2hvcnQ=") },
+			},
+			want: want{err: ErrInvalidPin},
+		},
+		{
+			name: "invalid root ca",
+			args: args{
+				transport: func() *TLSTransport { return NewTLSTransport().RootCAs([]byte("garbage")) },
+			},
+			want: want{err: ErrNoCertificates},
+		},
+	}
+
+	for _, test := range tests {
+		for _, adapter := range []string{"net/http", "fasthttp"} {
+			t.Run(test.name+" "+adapter, func(t *testing.T) {
+				cli, err := newTestTLSClient(test.args.transport(), adapter)
+
+				if err == nil {
+					call := NewCall[any](cli).URL(srv.URL).ExpectedStatusCodes(http.StatusOK)
+					if err = call.Call(context.TODO()); err == nil {
+						if client, _ := call.Res.Header("x-client"); client != test.want.client {
+							t.Errorf("unexpected client, want '%s', have '%s'", test.want.client, client)
+						}
+					}
+				}
+
+				switch {
+				case test.want.failed:
+					if err == nil {
+						t.Errorf("unexpected error, want some, have none")
+					}
+				case !errors.Is(err, test.want.err):
+					t.Errorf("unexpected error, want %v, have %v", test.want.err, err)
+				}
+			})
+		}
+	}
+
+	t.Run("client certificate reload", func(t *testing.T) {
+		for _, adapter := range []string{"net/http", "fasthttp"} {
+			writeClientCert(alice, time.Now().Add(-time.Minute))
+
+			transport := NewTLSTransport().ClientCertFile(certFile, keyFile).RootCAs(ca.certPEM)
+
+			cli, err := newTestTLSClient(transport, adapter)
+			if err != nil {
+				t.Fatalf("unexpected error, want none, have %v", err)
+			}
+
+			for _, step := range []struct {
+				before func()
+				client string
+			}{
+				{client: "alice"},
+				{before: func() { writeClientCert(bob, time.Now()) }, client: "bob"},
+				// Broken files are ignored until fixed.
+				{before: func() { _ = os.WriteFile(certFile, []byte("garbage"), 0o600) }, client: "bob"},
+			} {
+				if step.before != nil {
+					step.before()
+				}
+
+				call := NewCall[any](cli).URL(srv.URL).ExpectedStatusCodes(http.StatusOK)
+				if err := call.Call(context.TODO()); err != nil {
+					t.Fatalf("%s: unexpected error, want none, have %v", adapter, err)
+				}
+
+				if client, _ := call.Res.Header("x-client"); client != step.client {
+					t.Errorf("%s: unexpected client, want '%s', have '%s'", adapter, step.client, client)
+				}
+			}
+		}
+	})
+}
+
+func newTestTLSClient(transport *TLSTransport, adapter string) (Client, error) {
+	if adapter == "fasthttp" {
+		return transport.Fasthttp()
+	}
+	return transport.NetHttp()
+}
@@ -0,0 +1,101 @@
+package withttp
+
+import (
+	"context"
+	"io"
+)
+
+type (
+	// CallTracer traces calls, such as through OpenTelemetry, see package otelwithttp.
+	CallTracer interface {
+		// StartCall is called as calls start, whose execution goes on with the context returned.
+		// `endpoint` is the name of the endpoint called, if any.
+		StartCall(ctx context.Context, endpoint string) (context.Context, CallTrace)
+	}
+
+	// CallTrace traces a single execution of a call.
+	CallTrace interface {
+		// Attempt is called right before every attempt at sending the request, starting at 1, as
+		// signers are, hence request headers may be set, such as to propagate the trace context.
+		Attempt(ctx context.Context, req Request, attempt int)
+		// Sent is called as chunks of streamed request bodies, `n` bytes long, are sent.
+		Sent(n int)
+		// Received is called as chunks of response bodies, `n` bytes long, are read.
+		Received(n int)
+		// End is called once the call is done with, `req` and `res` being nil if never created or
+		// received.
+		End(req Request, res Response, err error)
+	}
+
+	// progressReadCloser reports the amount of bytes of every chunk read from the inner reader.
+	progressReadCloser struct {
+		io.ReadCloser
+
+		progress func(n int)
+	}
+)
+
+func (r progressReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.progress(n)
+	}
+	return n, err
+}
+
+// WithTracer traces every execution of the call with `t`.
+func (c *Call[T]) WithTracer(t CallTracer) *Call[T] {
+	c.tracer = t
+	return c
+}
+
+// startTrace starts tracing the execution of the call, if traced.
+func (c *Call[T]) startTrace(ctx context.Context, e *Endpoint) context.Context {
+	c.trace = nil
+
+	if c.tracer == nil {
+		return ctx
+	}
+
+	name := ""
+	if e != nil {
+		name = e.name
+	}
+
+	ctx, c.trace = c.tracer.StartCall(ctx, name)
+	return ctx
+}
+
+func (c *Call[T]) endTrace(err error) {
+	if c.trace != nil {
+		c.trace.End(c.Req, c.Res, err)
+	}
+}
+
+// traceMiddleware tells the trace about every attempt at sending requests.
+func (c *Call[T]) traceMiddleware() Middleware {
+	var attempt int
+
+	return MiddlewareFunc(func(ctx context.Context, req Request, next DoFunc) (Response, error) {
+		attempt++
+		c.trace.Attempt(ctx, req, attempt)
+		return next(ctx, req)
+	})
+}
+
+// sentSniffer reports chunks of streamed request bodies to `sniffer`, and to the trace, if any,
+// counting the bytes sent.
+func (c *Call[T]) sentSniffer(sniffer func([]byte, error)) func([]byte, error) {
+	stats, trace := c.stats, c.trace
+
+	return func(bts []byte, err error) {
+		if err == nil && len(bts) > 0 {
+			stats.bytesOut.Add(int64(len(bts)))
+
+			if trace != nil {
+				trace.Sent(len(bts))
+			}
+		}
+		sniffer(bts, err)
+	}
+}
@@ -1,13 +1,82 @@
 package withttp
 
-import "io"
+import (
+	"bytes"
+	"io"
+	"sync"
+)
 
 type (
 	closableReaderWriter struct {
 		io.ReadWriter
 	}
+
+	// pipeBuffer is a buffer able to be read while being written, whose reads wait for writes
+	// until it is closed. Unlike io.Pipe, writes never block, so that writers are not held up by
+	// readers gone.
+	pipeBuffer struct {
+		mu     sync.Mutex
+		cond   *sync.Cond
+		buf    bytes.Buffer
+		closed bool
+		// err is returned by reads once drained, rather than io.EOF, if set.
+		err error
+	}
 )
 
 func (b closableReaderWriter) Close() error {
 	return nil
 }
+
+func newPipeBuffer() *pipeBuffer {
+	b := &pipeBuffer{}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+func (b *pipeBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return 0, io.ErrClosedPipe
+	}
+
+	n, err := b.buf.Write(p)
+	b.cond.Broadcast()
+	return n, err
+}
+
+func (b *pipeBuffer) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for b.buf.Len() == 0 && !b.closed {
+		b.cond.Wait()
+	}
+
+	if b.buf.Len() == 0 {
+		if b.err != nil {
+			return 0, b.err
+		}
+		return 0, io.EOF
+	}
+	return b.buf.Read(p)
+}
+
+// Close fails further writes, reads draining what was written before returning io.EOF.
+func (b *pipeBuffer) Close() error {
+	return b.CloseWithError(nil)
+}
+
+// CloseWithError closes the buffer as Close does, reads returning `err` once drained, if not nil.
+func (b *pipeBuffer) CloseWithError(err error) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.closed {
+		b.closed, b.err = true, err
+	}
+	b.cond.Broadcast()
+	return nil
+}
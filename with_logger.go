@@ -1,7 +1,148 @@
 package withttp
 
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
 type (
 	logger interface {
 		Printf(string, ...any)
 	}
+
+	// SlogConfig configures how calls are logged through log/slog.
+	SlogConfig struct {
+		// Level of calls succeeding. Defaults to slog.LevelInfo.
+		Level slog.Leveler
+		// ErrorLevel of calls failing, be it on their way or because of unmet assertions. Defaults to
+		// slog.LevelError.
+		ErrorLevel slog.Leveler
+		// AttemptLevel of every attempt at sending requests, which may be sent again, such as after a
+		// 401 Unauthorized. Defaults to slog.LevelDebug.
+		AttemptLevel slog.Leveler
+	}
+
+	// callStats gathers what calls are logged and measured with while executing them, also seen by
+	// middlewares through the context.
+	callStats struct {
+		start         time.Time
+		endpoint      string
+		attempts      atomic.Int32
+		bytesIn       atomic.Int64
+		bytesOut      atomic.Int64
+		itemsSent     atomic.Int64
+		itemsReceived atomic.Int64
+		timings       timingsRecorder
+
+		mu      sync.Mutex
+		reports map[any]func(last bool)
+	}
 )
+
+func levelOr(level slog.Leveler, fallback slog.Level) slog.Level {
+	if level == nil {
+		return fallback
+	}
+	return level.Level()
+}
+
+// attemptsMiddleware counts, and logs, every attempt at sending requests.
+func (c *Call[T]) attemptsMiddleware(e *Endpoint) Middleware {
+	return MiddlewareFunc(func(ctx context.Context, req Request, next DoFunc) (Response, error) {
+		attempt := c.stats.attempts.Add(1)
+		start := time.Now()
+
+		res, err := next(ctx, req)
+
+		attrs := append(c.requestAttrs(e, req),
+			slog.Int("attempt", int(attempt)),
+			slog.Duration("duration", time.Since(start)),
+		)
+		if err != nil {
+			attrs = append(attrs, slog.Any("error", err))
+		} else if res != nil {
+			attrs = append(attrs, slog.Int("status", res.Status()))
+		}
+
+		c.slogger.LogAttrs(ctx, levelOr(c.slogConfig.AttemptLevel, slog.LevelDebug), "withttp attempt", attrs...)
+
+		return res, err
+	})
+}
+
+// logCall logs the outcome of the call, once done with it.
+func (c *Call[T]) logCall(ctx context.Context, e *Endpoint, err error) {
+	if c.slogger == nil || c.Req == nil {
+		return
+	}
+
+	attrs := append(c.requestAttrs(e, c.Req),
+		slog.Int("attempt", int(c.stats.attempts.Load())),
+		slog.Duration("duration", time.Since(c.stats.start)),
+	)
+
+	if _, ok := RequestPayload(c.Req); !ok {
+		// Streamed payloads are only known in size once sent.
+		attrs = append(attrs, slog.Int64("bytes_out", c.stats.bytesOut.Load()))
+	}
+
+	if c.Res != nil {
+		attrs = append(attrs,
+			slog.Int("status", c.Res.Status()),
+			slog.Int64("bytes_in", c.stats.bytesIn.Load()),
+			timingsAttr(c.Timings),
+		)
+	}
+
+	level := levelOr(c.slogConfig.Level, slog.LevelInfo)
+	if err != nil {
+		level = levelOr(c.slogConfig.ErrorLevel, slog.LevelError)
+		attrs = append(attrs, slog.Any("error", err))
+	}
+
+	c.slogger.LogAttrs(ctx, level, "withttp call", attrs...)
+}
+
+// timingsAttr groups the timings of the last attempt, those of dialing phases only if dialed.
+func timingsAttr(t Timings) slog.Attr {
+	attrs := make([]any, 0, 7)
+
+	if !t.ConnReused {
+		attrs = append(attrs,
+			slog.Duration("dns", t.DNS),
+			slog.Duration("connect", t.Connect),
+			slog.Duration("tls", t.TLSHandshake),
+		)
+	}
+
+	attrs = append(attrs,
+		slog.Duration("ttfb", t.TTFB),
+		slog.Duration("body_read", t.BodyRead),
+		slog.Duration("total", t.Total),
+		slog.Bool("conn_reused", t.ConnReused),
+	)
+
+	return slog.Group("timings", attrs...)
+}
+
+func (c *Call[T]) requestAttrs(e *Endpoint, req Request) []slog.Attr {
+	attrs := make([]slog.Attr, 0, 8)
+
+	if e != nil {
+		attrs = append(attrs, slog.String("endpoint", e.name))
+	}
+
+	attrs = append(attrs,
+		slog.String("method", req.Method()),
+		slog.String("url", c.redactionPolicy().URL(req.URL())),
+	)
+
+	if payload, ok := RequestPayload(req); ok {
+		attrs = append(attrs, slog.Int("bytes_out", len(payload)))
+	}
+
+	return attrs
+}
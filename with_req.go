@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/url"
+	"reflect"
 	"sync"
 
 	"github.com/pkg/errors"
@@ -47,7 +48,8 @@ func ContentType[T any](ct string) CallReqOptionFunc[T] {
 type authHeaderKind string
 
 var (
-	authHeaderKindBasic authHeaderKind = "Basic"
+	authHeaderKindBasic  authHeaderKind = "Basic"
+	authHeaderKindBearer authHeaderKind = "Bearer"
 )
 
 func (a authHeaderKind) Codec() func(...string) (string, error) {
@@ -62,14 +64,26 @@ func (a authHeaderKind) Codec() func(...string) (string, error) {
 
 			return base64.StdEncoding.EncodeToString(S2B(user + ":" + pass)), nil
 		}
+	case authHeaderKindBearer:
+		return func(s ...string) (string, error) {
+			if len(s) < 1 || !StrIsset(s[0]) {
+				return "", errors.Wrapf(ErrAssertion, "header kind: %s", a)
+			}
+
+			return s[0], nil
+		}
 	default:
-		panic("unknown auth header kind")
+		return func(...string) (string, error) {
+			return "", errors.Wrapf(ErrUnknownAuthKind, "got: '%s'", a)
+		}
 	}
 }
 
-func CreateAuthorizationHeader(kind authHeaderKind, user, pass string) (string, error) {
+// CreateAuthorizationHeader creates the value of the authorization header of the given kind out of
+// its credentials, such as user and password for Basic, or the token for Bearer.
+func CreateAuthorizationHeader(kind authHeaderKind, credentials ...string) (string, error) {
 	fn := kind.Codec()
-	header, err := fn(user, pass)
+	header, err := fn(credentials...)
 	if err != nil {
 		return header, err
 	}
@@ -124,7 +138,8 @@ func URI[T any](raw string) CallReqOptionFunc[T] {
 }
 
 func RawBody[T any](payload []byte) CallReqOptionFunc[T] {
-	return func(_ *Call[T], req Request) (err error) {
+	return func(c *Call[T], req Request) (err error) {
+		c.ReqBodyRaw = payload
 		req.SetBody(payload)
 		return nil
 	}
@@ -136,11 +151,26 @@ func Body[T any](payload any) CallReqOptionFunc[T] {
 		if err != nil {
 			return err
 		}
+		c.ReqBodyRaw = data
 		req.SetBody(data)
 		return nil
 	}
 }
 
+// CompressRequest compresses the request body with the given content encoding algorithm and level,
+// see CompressionLevelDefault, and sets the Content-Encoding header accordingly. Fixed bodies are
+// compressed at once, whereas streamed ones are compressed on the fly.
+func CompressRequest[T any](encoding string, level int) CallReqOptionFunc[T] {
+	return func(c *Call[T], req Request) (err error) {
+		if err = checkContentEncoding(encoding); err != nil {
+			return err
+		}
+		c.ReqContentEncoding = encoding
+		c.ReqCompressionLevel = level
+		return nil
+	}
+}
+
 func RequestSniffer[T any](fn func([]byte, error)) CallReqOptionFunc[T] {
 	return func(c *Call[T], req Request) error {
 		c.ReqShouldSniff = true
@@ -149,12 +179,22 @@ func RequestSniffer[T any](fn func([]byte, error)) CallReqOptionFunc[T] {
 	}
 }
 
+// pipedRequest is implemented by requests whose streamed bodies are read while being written,
+// such as those dispatched to in-process handlers, which are handed buffers to write them into
+// whose reads wait for the writer to be done.
+type pipedRequest interface {
+	bodyPipe() io.ReadWriteCloser
+}
+
 func RequestStreamBody[T, U any](r rangeable[U]) StreamCallReqOptionFunc[T] {
 	return func(c *Call[T], req Request) error {
 		c.ReqIsStream = true
 
-		buf := closableReaderWriter{ReadWriter: bytes.NewBuffer(nil)} // TODO: pool buffer
-		req.SetBodyStream(buf, -1)                                    // TODO: bodySize
+		var buf io.ReadWriteCloser = closableReaderWriter{ReadWriter: bytes.NewBuffer(nil)} // TODO: pool buffer
+		if pr, ok := req.(pipedRequest); ok {
+			buf = pr.bodyPipe()
+		}
+		req.SetBodyStream(buf, -1) // TODO: bodySize
 
 		c.ReqStreamWriter = func(ctx context.Context, c *Call[T], req Request, wg *sync.WaitGroup) (err error) {
 			defer func() { wg.Done() }()
@@ -164,6 +204,7 @@ func RequestStreamBody[T, U any](r rangeable[U]) StreamCallReqOptionFunc[T] {
 				encoder, err = ContentTypeCodec(c.ReqContentType)
 
 				if err != nil {
+					_ = req.BodyStream().Close()
 					return
 				}
 			} else {
@@ -178,7 +219,8 @@ func RequestStreamBody[T, U any](r rangeable[U]) StreamCallReqOptionFunc[T] {
 				sniffer = func(_ []byte, _ error) {}
 			}
 
-			err = EncodeStream(ctx, r, req, encoder, sniffer)
+			items := countedRange[U]{rangeable: r, n: &c.stats.itemsSent}
+			err = EncodeStream[U](ctx, items, req, encoder, c.sentSniffer(sniffer))
 
 			return
 		}
@@ -216,6 +258,20 @@ func EncodeStream[T any](
 
 	var bts []byte
 
+	if he, ok := encoder.(headerEncoder); ok {
+		if bts, err = he.EncodeHeader(reflect.TypeOf((*T)(nil)).Elem()); err != nil {
+			return
+		}
+
+		if len(bts) > 0 {
+			_, err = stream.Write(bts)
+			sniffer(bts, err)
+			if err != nil {
+				return
+			}
+		}
+	}
+
 	r.Range(func(i int, x T) bool {
 		defer func() {
 			sniffer(bts, err)
@@ -5,6 +5,8 @@ import (
 	"context"
 	"io"
 	"net/http"
+	"net/http/httptest"
+	"reflect"
 	"testing"
 )
 
@@ -65,24 +67,21 @@ func TestCall_StreamingRequestFromSlice(t *testing.T) {
 		},
 	}
 
-	endpoint := NewEndpoint("mock").
-		Response(MockedRes(func(res Response) {
-			res.SetStatus(http.StatusOK)
-			res.SetBody(io.NopCloser(bytes.NewReader(nil)))
-		}))
+	fake := NewFakeClient()
+	fake.On("", "").Respond(http.StatusOK, nil)
 
 	for _, test := range tests {
 
 		t.Run(test.name, func(t *testing.T) {
 
-			call := NewCall[any](NewMockHttpClientAdapter()).
+			call := NewCall[any](fake).
 				ContentType(ContentTypeJSONEachRow).
 				RequestStreamBody(
 					RequestStreamBody[any, payload](Slice[payload](test.args.Stream)),
 				).
 				ExpectedStatusCodes(http.StatusOK)
 
-			err := call.CallEndpoint(context.TODO(), endpoint)
+			err := call.Call(context.TODO())
 
 			if !assertError(t, test.want.expectedErr, err) {
 				t.FailNow()
@@ -96,3 +95,319 @@ func TestCall_StreamingRequestFromSlice(t *testing.T) {
 		})
 	}
 }
+
+func TestCall_CompressRequest(t *testing.T) {
+	type (
+		payload struct {
+			Name string `json:"name"`
+		}
+
+		args struct {
+			encoding string
+			stream   bool
+		}
+
+		want struct {
+			ReceivedPayload []byte
+		}
+
+		testCase struct {
+			name string
+			args args
+			want want
+		}
+	)
+
+	items := []payload{{Name: "I am the first payload"}, {Name: "I am the second payload"}}
+
+	tests := []testCase{
+		{
+			name: "gzip fixed body",
+			args: args{encoding: ContentEncodingGzip},
+			want: want{
+				ReceivedPayload: []byte(`{"name":"I am the first payload"}`),
+			},
+		},
+		{
+			name: "zstd fixed body",
+			args: args{encoding: ContentEncodingZstd},
+			want: want{
+				ReceivedPayload: []byte(`{"name":"I am the first payload"}`),
+			},
+		},
+		{
+			name: "gzip streamed body",
+			args: args{encoding: ContentEncodingGzip, stream: true},
+			want: want{
+				ReceivedPayload: streamTextJoin("\n", []string{
+					`{"name":"I am the first payload"}`,
+					`{"name":"I am the second payload"}`,
+				}),
+			},
+		},
+		{
+			name: "brotli streamed body",
+			args: args{encoding: ContentEncodingBrotli, stream: true},
+			want: want{
+				ReceivedPayload: streamTextJoin("\n", []string{
+					`{"name":"I am the first payload"}`,
+					`{"name":"I am the second payload"}`,
+				}),
+			},
+		},
+	}
+
+	fake := NewFakeClient()
+	fake.On("", "").Respond(http.StatusOK, nil)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			call := NewCall[any](fake).
+				CompressRequest(test.args.encoding, CompressionLevelDefault)
+
+			if test.args.stream {
+				call = call.
+					ContentType(ContentTypeJSONEachRow).
+					RequestStreamBody(RequestStreamBody[any, payload](Slice[payload](items)))
+			} else {
+				call = call.
+					ContentType(ContentTypeJSON).
+					Body(items[0])
+			}
+
+			err := call.
+				ExpectedStatusCodes(http.StatusOK).
+				Call(context.TODO())
+
+			if !assertError(t, nil, err) {
+				t.FailNow()
+			}
+
+			if encoding, _ := call.Req.Header("content-encoding"); encoding != test.args.encoding {
+				t.Errorf("unexpected content encoding, want %s, have %s", test.args.encoding, encoding)
+			}
+
+			rc := DecodeContentEncoding(
+				io.NopCloser(bytes.NewReader(call.Req.Body())),
+				test.args.encoding,
+			)
+			actualReceivedBody, err := io.ReadAll(rc)
+			if err != nil {
+				t.Fatalf("unexpected error decoding body: %v", err)
+			}
+
+			if !BytesEquals(test.want.ReceivedPayload, actualReceivedBody) {
+				t.Errorf("unexpected received payload\nwant '%s'\nhave '%s'",
+					string(test.want.ReceivedPayload), string(actualReceivedBody))
+			}
+		})
+	}
+}
+
+func TestCall_CompressRequest_Adapters(t *testing.T) {
+	plain := bytes.Repeat([]byte(`{"name":"I am a payload"}`), 32)
+
+	type (
+		args struct {
+			call func(cli Client, url string) *Call[any]
+		}
+
+		testCase struct {
+			name string
+			args args
+		}
+	)
+
+	tests := []testCase{
+		{
+			name: "call body",
+			args: args{
+				call: func(cli Client, url string) *Call[any] {
+					return NewCall[any](cli).URL(url).RawBody(plain)
+				},
+			},
+		},
+		{
+			name: "endpoint body",
+			args: args{
+				call: func(cli Client, url string) *Call[any] {
+					return NewCall[any](cli).
+						URL(url).
+						Request(ReqOptionFunc(func(req Request) error {
+							req.SetBody(plain)
+							return nil
+						}))
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		for name, cli := range map[string]Client{
+			"net/http": NetHttpClient(&http.Client{}),
+			"fasthttp": Fasthttp(),
+		} {
+			t.Run(test.name+" "+name, func(t *testing.T) {
+				var (
+					received      []byte
+					contentLength int64
+					encoding      string
+				)
+
+				srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					contentLength = r.ContentLength
+					encoding = r.Header.Get("content-encoding")
+					received, _ = io.ReadAll(DecodeContentEncoding(r.Body, encoding))
+					w.WriteHeader(http.StatusNoContent)
+				}))
+				defer srv.Close()
+
+				call := test.args.call(cli, srv.URL).
+					Method(http.MethodPost).
+					CompressRequest(ContentEncodingGzip, 9).
+					ExpectedStatusCodes(http.StatusNoContent)
+
+				if err := call.Call(context.TODO()); err != nil {
+					t.Fatalf("unexpected error, want none, have %v", err)
+				}
+
+				if !bytes.Equal(plain, received) {
+					t.Errorf("unexpected received payload\nwant '%s'\nhave '%s'", plain, received)
+				}
+
+				if encoding != ContentEncodingGzip {
+					t.Errorf("unexpected content encoding, want %s, have '%s'", ContentEncodingGzip, encoding)
+				}
+
+				if contentLength <= 0 || contentLength >= int64(len(plain)) {
+					t.Errorf("unexpected content length %d, want the compressed size", contentLength)
+				}
+
+				if _, ok := RequestPayload(call.Req); !ok {
+					t.Errorf("unexpected streamed body, want it buffered")
+				}
+			})
+		}
+	}
+}
+
+func TestCall_StreamingRequestCSV(t *testing.T) {
+	type (
+		payload struct {
+			Name   string  `csv:"name"`
+			Amount float64 `csv:"amount"`
+		}
+
+		args struct {
+			contentType string
+			stream      func([]payload) rangeable[payload]
+		}
+
+		want struct {
+			ReceivedPayload []byte
+		}
+
+		testCase struct {
+			name string
+			args args
+			want want
+		}
+	)
+
+	items := []payload{
+		{Name: "BTC/USDT", Amount: 234.5},
+		{Name: `ETH, "USDT"`, Amount: 2},
+	}
+
+	fromSlice := func(items []payload) rangeable[payload] { return Slice[payload](items) }
+	fromChannel := func(items []payload) rangeable[payload] {
+		ch := make(chan payload, len(items))
+		for _, item := range items {
+			ch <- item
+		}
+		close(ch)
+		return Channel[payload](ch)
+	}
+
+	tests := []testCase{
+		{
+			name: "slice without header",
+			args: args{contentType: ContentTypeCSV, stream: fromSlice},
+			want: want{
+				ReceivedPayload: []byte("BTC/USDT,234.5\n\"ETH, \"\"USDT\"\"\",2\n"),
+			},
+		},
+		{
+			name: "slice with header",
+			args: args{contentType: ContentTypeCSVHeader, stream: fromSlice},
+			want: want{
+				ReceivedPayload: []byte("name,amount\nBTC/USDT,234.5\n\"ETH, \"\"USDT\"\"\",2\n"),
+			},
+		},
+		{
+			name: "channel with header",
+			args: args{contentType: ContentTypeCSVHeader, stream: fromChannel},
+			want: want{
+				ReceivedPayload: []byte("name,amount\nBTC/USDT,234.5\n\"ETH, \"\"USDT\"\"\",2\n"),
+			},
+		},
+	}
+
+	fake := NewFakeClient()
+	fake.On("", "").Respond(http.StatusOK, nil)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			call := NewCall[any](fake).
+				ContentType(test.args.contentType).
+				RequestStreamBody(
+					RequestStreamBody[any, payload](test.args.stream(items)),
+				).
+				ExpectedStatusCodes(http.StatusOK)
+
+			if err := call.Call(context.TODO()); err != nil {
+				t.Fatalf("unexpected error, want none, have %v", err)
+			}
+
+			actualReceivedBody := call.Req.Body()
+
+			if !BytesEquals(test.want.ReceivedPayload, actualReceivedBody) {
+				t.Errorf("unexpected received payload\nwant '%s'\nhave '%s'",
+					string(test.want.ReceivedPayload), string(actualReceivedBody))
+			}
+		})
+	}
+}
+
+func TestEncodeBody_CSV(t *testing.T) {
+	type payload struct {
+		Name   string   `csv:"name"`
+		Amount *float64 `csv:"amount,optional"`
+	}
+
+	amount := 234.5
+	items := []payload{{Name: "BTC/USDT", Amount: &amount}, {Name: "ETH/USDT"}}
+
+	bts, err := EncodeBody(items, ContentTypeCSVHeader)
+	if err != nil {
+		t.Fatalf("unexpected error, want none, have %v", err)
+	}
+
+	if want := "name,amount\nBTC/USDT,234.5\nETH/USDT,\n"; want != string(bts) {
+		t.Errorf("unexpected payload\nwant '%s'\nhave '%s'", want, bts)
+	}
+
+	csvCodec, err := ContentTypeCodec(ContentTypeCSVHeader)
+	if err != nil {
+		t.Fatalf("unexpected error, want none, have %v", err)
+	}
+
+	var decoded []payload
+	if err = csvCodec.Decode([]byte("amount,name\r\n234.5,BTC/USDT\r\n,ETH/USDT\r\n"), &decoded); err != nil {
+		t.Fatalf("unexpected error, want none, have %v", err)
+	}
+
+	if !reflect.DeepEqual(items, decoded) {
+		t.Errorf("unexpected decoded payload\nwant %+v\nhave %+v", items, decoded)
+	}
+}
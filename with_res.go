@@ -45,13 +45,13 @@ func ParseJSON[T any]() CallResOptionFunc[T] {
 
 func ParseStream[T any](factory StreamFactory[T], fn func(T) bool) CallResOptionFunc[T] {
 	return func(c *Call[T], res Response) (err error) {
-		return ReadStream[T](c.bodyReader(res), factory, fn)
+		return ReadStream[T](c.bodyReader(res), c.countedStreams(factory), fn)
 	}
 }
 
 func ParseStreamChan[T any](factory StreamFactory[T], out chan<- T) CallResOptionFunc[T] {
 	return func(c *Call[T], res Response) (err error) {
-		return ReadStreamChan(c.bodyReader(res), factory, out)
+		return ReadStreamChan(c.bodyReader(res), c.countedStreams(factory), out)
 	}
 }
 
@@ -74,6 +74,25 @@ func Assertion[T any](fn func(res Response) error) CallResOptionFunc[T] {
 	}
 }
 
+// ResponseSniffer hands the response body over to `fn`, chunk by chunk, as it is consumed by any
+// parser. Being a request option, it applies to every parser no matter where it is chained.
+func ResponseSniffer[T any](fn func([]byte, error)) CallReqOptionFunc[T] {
+	return func(c *Call[T], _ Request) error {
+		c.ResShouldSniff = true
+		c.ResStreamSniffer = fn
+		return nil
+	}
+}
+
+// MockedRes amends responses once received, after the fact.
+//
+// Deprecated: stub responses as sent by servers with FakeClient instead, which runs parsers and
+// assertions against them as it would against real ones:
+//
+//	fake := NewFakeClient()
+//	fake.On(http.MethodGet, "/orders").Respond(http.StatusOK, []byte(`{"amount": 234}`))
+//
+//	call := NewCall[Order](fake).URL("http://example.com/orders").ParseJSON()
 func MockedRes(fn func(response Response)) ResOption {
 	return ResOptionFunc(func(res Response) (err error) {
 		fn(res)
@@ -107,6 +126,10 @@ func ReadStream[T any](rc io.ReadCloser, factory StreamFactory[T], fn func(T) bo
 		keep = fn(stream.Data())
 	}
 
+	if keep {
+		err = stream.Err()
+	}
+
 	return
 }
 
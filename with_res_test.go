@@ -0,0 +1,205 @@
+package withttp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/sonirico/withttp/csvparser"
+)
+
+func TestCall_ResponseSniffed(t *testing.T) {
+	type (
+		args struct {
+			body    string
+			encoded bool
+			parse   func(c *Call[Order]) *Call[Order]
+		}
+
+		want struct {
+			sniffed string
+			failed  bool
+		}
+
+		testCase struct {
+			name string
+			args args
+			want want
+		}
+	)
+
+	ndjson := `{"amount": 234, "pair": "BTC/USDT"}
+{"amount": 123, "pair": ETH/USDT}
+{"amount": 345, "pair": "SOL/USDT"}`
+
+	tests := []testCase{
+		{
+			name: "parse json",
+			args: args{
+				body:  `{"amount": 234, "pair": "BTC/USDT"}`,
+				parse: func(c *Call[Order]) *Call[Order] { return c.ParseJSON() },
+			},
+			want: want{
+				sniffed: `{"amount": 234, "pair": "BTC/USDT"}`,
+			},
+		},
+		{
+			name: "parse json each row up to the malformed line",
+			args: args{
+				body: ndjson,
+				parse: func(c *Call[Order]) *Call[Order] {
+					return c.ParseJSONEachRow(func(Order) bool { return true })
+				},
+			},
+			want: want{
+				sniffed: ndjson,
+				failed:  true,
+			},
+		},
+		{
+			name: "parse csv",
+			args: args{
+				body: "amount,pair\n234,BTC/USDT",
+				parse: func(c *Call[Order]) *Call[Order] {
+					parser := csvparser.New[Order](
+						csvparser.SeparatorComma,
+						csvparser.IntCol[Order](csvparser.QuoteNone, nil,
+							func(x *Order, v int) { x.Amount = float64(v) }),
+						csvparser.StringCol[Order](csvparser.QuoteNone, nil,
+							func(x *Order, v string) { x.Pair = v }),
+					)
+					return c.ParseCSV(1, parser, func(Order) bool { return true })
+				},
+			},
+			want: want{
+				sniffed: "amount,pair\n234,BTC/USDT",
+			},
+		},
+		{
+			name: "sniffed payload is decompressed",
+			args: args{
+				body:    `{"amount": 234, "pair": "BTC/USDT"}`,
+				encoded: true,
+				parse:   func(c *Call[Order]) *Call[Order] { return c.ParseJSON() },
+			},
+			want: want{
+				sniffed: `{"amount": 234, "pair": "BTC/USDT"}`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			body := []byte(test.args.body)
+			if test.args.encoded {
+				body = compressTestPayload(t, ContentEncodingGzip, body)
+			}
+
+			fake := NewFakeClient()
+			stub := fake.On("", "")
+			if test.args.encoded {
+				stub.RespondHeader("content-encoding", ContentEncodingGzip)
+			}
+			stub.Respond(http.StatusOK, body)
+
+			sniffed := bytes.NewBuffer(nil)
+
+			call := test.args.parse(NewCall[Order](fake)).
+				ResponseSniffed(func(bts []byte, err error) {
+					sniffed.Write(bts)
+				})
+
+			err := call.Call(context.TODO())
+
+			if test.want.failed != (err != nil) {
+				t.Fatalf("unexpected error, want failure: %t, have %v", test.want.failed, err)
+			}
+
+			if test.want.sniffed != sniffed.String() {
+				t.Errorf("unexpected sniffed payload\nwant '%s'\nhave '%s'",
+					test.want.sniffed, sniffed.String())
+			}
+
+			if call.BodyRaw != nil {
+				t.Errorf("unexpected raw body, want none, have '%s'", call.BodyRaw)
+			}
+		})
+	}
+}
+
+func TestReadStream(t *testing.T) {
+	type (
+		args struct {
+			body    string
+			factory StreamFactory[Order]
+			limit   int
+		}
+
+		want struct {
+			orders int
+			err    error
+		}
+
+		testCase struct {
+			name string
+			args args
+			want want
+		}
+	)
+
+	long := `{"amount": 1, "pair": "` + strings.Repeat("X", 200) + `"}`
+
+	tests := []testCase{
+		{
+			name: "every record",
+			args: args{
+				body:    "{\"amount\": 1}\n{\"amount\": 2}",
+				factory: NewJSONEachRowStreamFactory[Order](),
+			},
+			want: want{orders: 2},
+		},
+		{
+			name: "record longer than the max buffer",
+			args: args{
+				body:    "{\"amount\": 1}\n" + long + "\n{\"amount\": 3}",
+				factory: NewJSONEachRowStreamFactory[Order](LineStreamOptions{MaxBufferSize: 64}),
+			},
+			want: want{orders: 1, err: bufio.ErrTooLong},
+		},
+		{
+			name: "stopped before the record longer than the max buffer",
+			args: args{
+				body:    "{\"amount\": 1}\n" + long,
+				factory: NewJSONEachRowStreamFactory[Order](LineStreamOptions{MaxBufferSize: 64}),
+				limit:   1,
+			},
+			want: want{orders: 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var orders int
+
+			err := ReadStream(io.NopCloser(strings.NewReader(test.args.body)), test.args.factory,
+				func(Order) bool {
+					orders++
+					return orders != test.args.limit
+				})
+
+			if !errors.Is(err, test.want.err) {
+				t.Fatalf("unexpected error, want %v, have %v", test.want.err, err)
+			}
+
+			if orders != test.want.orders {
+				t.Errorf("unexpected orders, want %d, have %d", test.want.orders, orders)
+			}
+		})
+	}
+}